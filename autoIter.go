@@ -0,0 +1,175 @@
+package mandel
+
+import (
+	"math"
+	"sort"
+)
+
+// Anstatt die maximale Anzahl Iterationen bei jeder Stuetzstelle von Hand
+// festzulegen, kann sie mit AutoIter fuer jede Ansicht geschaetzt werden.
+// Die Schaetzung erfolgt in drei Schritten:
+//
+//  1. Aus der Zoom-Tiefe d = log10(RefWidth/w) ergibt sich ein erster Wert
+//     Base + Scale * d^Power, welcher als Limit fuer eine Vorschau mit
+//     tiefer Aufloesung verwendet wird.
+//  2. Bleiben in der Vorschau zu viele Randpixel (Pixel ohne Escape neben
+//     Pixeln mit Escape) unaufgeloest, so wird das Limit verdoppelt, solange
+//     sich deren Anzahl dadurch noch deutlich verringert.
+//  3. Aus der Verteilung der Escape-Zeiten in der Vorschau wird das Quantil
+//     Quantile bestimmt und mit Margin multipliziert.
+//
+// Das Resultat liegt immer zwischen Min und Max.
+type AutoIter struct {
+	RefWidth, Base, Scale, Power float64
+	PreviewCols                  int
+	MaxUnresolved                float64
+	Quantile, Margin             float64
+	Min, Max                     int
+}
+
+// NewAutoIter erstellt eine Schaetzung mit Standardwerten.
+func NewAutoIter() *AutoIter {
+	return &AutoIter{
+		RefWidth:      3.5,
+		Base:          100.0,
+		Scale:         50.0,
+		Power:         1.5,
+		PreviewCols:   64,
+		MaxUnresolved: 0.01,
+		Quantile:      0.995,
+		Margin:        1.5,
+		Min:           64,
+		Max:           1 << 20,
+	}
+}
+
+// FieldFunc erstellt ein neues Feld mit cols Spalten und rows Zeilen (bspw.
+// mit f64.NewField). Damit kann AutoIter mit jeder Implementation von
+// [Field] verwendet werden.
+type FieldFunc func(cols, rows int) Field
+
+// clamp beschraenkt it auf den Bereich [Min, Max].
+func (ai *AutoIter) clamp(it float64) int {
+	return int(math.Min(math.Max(math.Ceil(it), float64(ai.Min)), float64(ai.Max)))
+}
+
+// DepthIter liefert die Anzahl Iterationen, welche sich allein aus der
+// Breite w der Ansicht ergibt.
+func (ai *AutoIter) DepthIter(w float64) int {
+	d := math.Max(math.Log10(ai.RefWidth/w), 0.0)
+	return ai.clamp(ai.Base + ai.Scale*math.Pow(d, ai.Power))
+}
+
+// autoView ist eine einfache Implementation von [View] fuer die Vorschau.
+type autoView struct {
+	x, y, w float64
+	it      int
+	tr      Transform
+}
+
+func (v *autoView) Values() (x, y, w float64, maxIt int) {
+	return v.x, v.y, v.w, v.it
+}
+
+func (v *autoView) SetValues(x, y, w float64, maxIt int) {
+	v.x, v.y, v.w, v.it = x, y, w, maxIt
+}
+
+func (v *autoView) Transform() Transform {
+	return v.tr
+}
+
+func (v *autoView) SetTransform(tr Transform) {
+	v.tr = tr
+}
+
+// Estimate schaetzt die Anzahl Iterationen fuer die Ansicht v, welche mit
+// cols x rows Pixeln dargestellt werden soll. Die Vorschau wird mit einem
+// Feld aus newField berechnet; die Anzahl Iterationen in v wird ignoriert.
+func (ai *AutoIter) Estimate(v View, cols, rows int, newField FieldFunc) int {
+	x, y, w, _ := v.Values()
+	pv := &autoView{x: x, y: y, w: w, tr: v.Transform()}
+
+	pCols := min(ai.PreviewCols, cols)
+	pRows := max(int(math.Round(float64(pCols)*float64(rows)/float64(cols))), 1)
+	field := newField(pCols, pRows)
+
+	limit := ai.DepthIter(w)
+	lastBoundary := -1
+	var escaped []float64
+	for {
+		pv.it = limit
+		field.CalcMandelbrot(pv)
+		var boundary int
+		escaped, boundary = previewStats(field, pCols, pRows)
+		if float64(boundary) <= ai.MaxUnresolved*float64(pCols*pRows) || limit >= ai.Max {
+			break
+		}
+		if lastBoundary >= 0 && float64(boundary) > 0.9*float64(lastBoundary) {
+			break
+		}
+		lastBoundary = boundary
+		limit = min(2*limit, ai.Max)
+	}
+	if len(escaped) == 0 {
+		return ai.clamp(float64(limit))
+	}
+	sort.Float64s(escaped)
+	q := escaped[int(ai.Quantile*float64(len(escaped)-1))]
+	return ai.clamp(q * ai.Margin)
+}
+
+// previewStats liefert die Escape-Zeiten aller Pixel der Vorschau und die
+// Anzahl der unaufgeloesten Randpixel.
+func previewStats(field Field, cols, rows int) (escaped []float64, boundary int) {
+	inside := func(col, row int) bool {
+		return field.Value(col, row) < 0.0
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if !inside(col, row) {
+				escaped = append(escaped, field.Value(col, row))
+				continue
+			}
+			if (col > 0 && !inside(col-1, row)) || (col < cols-1 && !inside(col+1, row)) ||
+				(row > 0 && !inside(col, row-1)) || (row < rows-1 && !inside(col, row+1)) {
+				boundary++
+			}
+		}
+	}
+	return escaped, boundary
+}
+
+// IterCurve enthaelt die geschaetzte Anzahl Iterationen an mehreren Stellen
+// eines Pfades. Dazwischen wird logarithmisch interpoliert, womit die
+// Anzahl Iterationen (und damit bspw. die Laenge der Palette) von Bild zu
+// Bild nicht springt.
+type IterCurve struct {
+	its []float64
+}
+
+// Curve schaetzt die Anzahl Iterationen an n+1 gleichmaessig verteilten
+// Stellen des Pfades p (siehe [AutoIter.Estimate]).
+func (ai *AutoIter) Curve(p Path, n, cols, rows int, newField FieldFunc) IterCurve {
+	c := IterCurve{its: make([]float64, n+1)}
+	for i := range c.its {
+		t := 0.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+		c.its[i] = math.Log(float64(ai.Estimate(p.GetView(t), cols, rows, newField)))
+	}
+	return c
+}
+
+// At liefert die Anzahl Iterationen an der Stelle t (0.0 <= t <= 1.0).
+func (c IterCurve) At(t float64) int {
+	n := len(c.its) - 1
+	if n <= 0 {
+		return int(math.Round(math.Exp(c.its[0])))
+	}
+	u := math.Min(math.Max(t, 0.0), 1.0) * float64(n)
+	i := min(int(u), n-1)
+	s := u - float64(i)
+	return int(math.Round(math.Exp((1.0-s)*c.its[i] + s*c.its[i+1])))
+}
@@ -0,0 +1,33 @@
+package mandel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAutoIterDepth(t *testing.T) {
+	ai := NewAutoIter()
+	if it := ai.DepthIter(ai.RefWidth); it != int(ai.Base) {
+		t.Errorf("DepthIter(RefWidth) = %d, want %v", it, ai.Base)
+	}
+	last := 0
+	for _, w := range []float64{10.0, 1.0, 1e-3, 1e-6, 1e-12} {
+		it := ai.DepthIter(w)
+		if it < last || it < ai.Min || it > ai.Max {
+			t.Errorf("DepthIter(%g) = %d", w, it)
+		}
+		last = it
+	}
+}
+
+func TestIterCurve(t *testing.T) {
+	c := IterCurve{its: []float64{math.Log(100), math.Log(400), math.Log(400)}}
+	for _, tc := range []struct {
+		t  float64
+		it int
+	}{{0.0, 100}, {0.25, 200}, {0.5, 400}, {0.75, 400}, {1.0, 400}, {1.5, 400}} {
+		if it := c.At(tc.t); it != tc.it {
+			t.Errorf("At(%v) = %d, want %d", tc.t, it, tc.it)
+		}
+	}
+}
@@ -26,7 +26,7 @@ import (
     "os"
     "strings"
 
-    . "github.com/stefan-muehlebach/mandel"
+    "github.com/stefan-muehlebach/mandel"
 )
 
 const (
@@ -42,7 +42,7 @@ const (
 type Field struct {
     cols, rows int
     maxIter    float64
-    pal        *Palette
+    pal        mandel.Palette
     f          [][]float64
     iterHist   []float64
 }
@@ -63,9 +63,9 @@ func NewField(cols, rows int) *Field {
 
 // Berechnet die Mandelbrot-Menge ueber dem Feld f mit der Ansicht v.
 func (f *Field) CalcMandelbrot(v *View) {
-    var dx, dy, xmin, ymax, cx, cy, zx, zy, zx2, zy2, rad, zn *big.Float
-    var sx, sy *big.Float
-    var escRad, two, half *big.Float
+    var pix, dxc, dyc, dxr, dyr, rx, ry, cx, cy, zx, zy, zx2, zy2, rad, zn *big.Float
+    var sx, sy, tmp *big.Float
+    var escRad, two *big.Float
     var iter, znf, nu float64
     var row, col, it int
     var iterate func(x, y *big.Float) float64
@@ -95,18 +95,26 @@ func (f *Field) CalcMandelbrot(v *View) {
     superSampled = func(cx, cy *big.Float, samp int) (iter float64) {
         iter = iterate(cx, cy)
         for s := 0; s < samp; s++ {
-            sx.Add(cx, sx.Mul(dx, big.NewFloat(rand.Float64()-0.5)))
-            sy.Add(cy, sy.Mul(dy, big.NewFloat(rand.Float64()-0.5)))
+            u := big.NewFloat(rand.Float64() - 0.5)
+            w := big.NewFloat(rand.Float64() - 0.5)
+            sx.Add(cx, sx.Mul(dxc, u))
+            sx.Add(sx, tmp.Mul(dxr, w))
+            sy.Add(cy, sy.Mul(dyc, u))
+            sy.Add(sy, tmp.Mul(dyr, w))
             iter += iterate(sx, sy)
         }
         iter /= float64(samp + 1)
         return iter
     }
 
-    dx = big.NewFloat(0.0).SetPrec(100)
-    dy = big.NewFloat(0.0).SetPrec(100)
-    xmin = big.NewFloat(0.0).SetPrec(100)
-    ymax = big.NewFloat(0.0).SetPrec(100)
+    pix = big.NewFloat(0.0).SetPrec(100)
+    dxc = big.NewFloat(0.0).SetPrec(100)
+    dyc = big.NewFloat(0.0).SetPrec(100)
+    dxr = big.NewFloat(0.0).SetPrec(100)
+    dyr = big.NewFloat(0.0).SetPrec(100)
+    rx = big.NewFloat(0.0).SetPrec(100)
+    ry = big.NewFloat(0.0).SetPrec(100)
+    tmp = big.NewFloat(0.0).SetPrec(100)
     cx = big.NewFloat(0.0).SetPrec(100)
     cy = big.NewFloat(0.0).SetPrec(100)
     sx = big.NewFloat(0.0).SetPrec(100)
@@ -120,19 +128,31 @@ func (f *Field) CalcMandelbrot(v *View) {
     rad = big.NewFloat(0.0).SetPrec(100)
     escRad = big.NewFloat(escRadius2).SetPrec(100)
     two = big.NewFloat(2.0).SetPrec(100)
-    half = big.NewFloat(0.5).SetPrec(100)
 
     f.maxIter = float64(v.it)
 
-    dx.Quo(v.w, big.NewFloat(float64(f.cols)))
-    dy.Set(dx)
+    // Die Pixel werden gemaess der Transformation der Ansicht gedreht und
+    // verzerrt: (dxc, dyc) ist der Schritt zum naechsten Pixel einer Zeile,
+    // (dxr, dyr) derjenige zur naechsten Zeile.
+    a, b, c, d := v.tr.Matrix()
+    pix.Quo(v.w, big.NewFloat(float64(f.cols)))
+    dxc.Mul(pix, big.NewFloat(a))
+    dyc.Mul(pix, big.NewFloat(c))
+    dxr.Mul(pix, big.NewFloat(-b))
+    dyr.Mul(pix, big.NewFloat(-d))
+
+    // Ecke links oben: vom Mittelpunkt aus je eine halbe Breite und eine
+    // halbe Hoehe zurueck.
+    halfCols := big.NewFloat(float64(f.cols) / 2.0)
+    halfRows := big.NewFloat(float64(f.rows) / 2.0)
+    rx.Sub(v.x, tmp.Mul(dxc, halfCols))
+    rx.Sub(rx, tmp.Mul(dxr, halfRows))
+    ry.Sub(v.y, tmp.Mul(dyc, halfCols))
+    ry.Sub(ry, tmp.Mul(dyr, halfRows))
 
-    xmin.Sub(v.x, xmin.Mul(v.w, half))
-    ymax.Add(v.y, ymax.Mul(dy, big.NewFloat(float64(f.rows)/2.0)))
-
-    cy.Set(ymax)
     for row = 0; row < f.rows; row++ {
-        cx.Set(xmin)
+        cx.Set(rx)
+        cy.Set(ry)
         for col = 0; col < f.cols; col++ {
             if v.samp > 0 {
                 iter = superSampled(cx, cy, v.samp)
@@ -144,15 +164,17 @@ func (f *Field) CalcMandelbrot(v *View) {
             } else {
                 f.f[row][col] = iter
             }
-            cx.Add(cx, dx)
+            cx.Add(cx, dxc)
+            cy.Add(cy, dyc)
         }
-        cy.Sub(cy, dy)
+        rx.Add(rx, dxr)
+        ry.Add(ry, dyr)
     }
 }
 
 // Fuegt dem Feld eine Palette hinzu. Eine bereits hinterlegte Palette wird
 // damit ueberschrieben.
-func (f *Field) AddPalette(p *Palette) {
+func (f *Field) AddPalette(p mandel.Palette) {
     f.pal = p
 }
 
@@ -178,6 +200,17 @@ func (f *Field) At(x, y int) color.Color {
     return f.pal.GetColor(f.f[y][x])
 }
 
+// Value liefert den berechneten (geglaetteten) Iterationswert an der Stelle
+// (x,y). Fuer Punkte der Mandelbrot-Menge ist dieser Wert -1.0.
+func (f *Field) Value(x, y int) float64 {
+    return f.f[y][x]
+}
+
+// Retourniert die maximale Anzahl Iterationen der letzten Berechnung.
+func (f *Field) MaxIterations() int {
+    return int(f.maxIter)
+}
+
 // ----------------------------------------------------------------------------
 //
 // Diese Methode(n) (Draw und WritePNG) werden eigentlich nicht mehr
@@ -0,0 +1,53 @@
+package big
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/stefan-muehlebach/mandel"
+    "github.com/stefan-muehlebach/mandel/f64"
+)
+
+const (
+    testCols, testRows, testIter = 40, 30, 200
+)
+
+// insideMismatch liefert den Anteil der Pixel, bei denen das Feld f und
+// das Feld ref (aus dem Package f64) nicht uebereinstimmen, ob der Punkt zur
+// Mandelbrot-Menge gehoert.
+func insideMismatch(f *Field, ref mandel.Field) float64 {
+    n := 0
+    for row := range testRows {
+        for col := range testCols {
+            if (f.f[row][col] < 0.0) != (ref.Value(col, row) < 0.0) {
+                n++
+            }
+        }
+    }
+    return float64(n) / float64(testCols*testRows)
+}
+
+// Die Ansicht muss gleich gedreht und verzerrt werden wie im Package f64.
+func TestTransform(t *testing.T) {
+    tr := mandel.Transform{Rot: 30.0, Skew: 0.2, Stretch: 1.3}
+
+    refView := f64.NewView()
+    refView.SetValues(-0.5, 0.1, 3.0, testIter)
+    refView.SetTransform(tr)
+    ref := f64.NewField(testCols, testRows, mandel.Samp1x1)
+    ref.CalcMandelbrot(refView)
+
+    v := NewView()
+    v.SetValues(big.NewFloat(-0.5), big.NewFloat(0.1), big.NewFloat(3.0), testIter, 0)
+    f := NewField(testCols, testRows)
+    f.CalcMandelbrot(v)
+    if d := insideMismatch(f, ref); d < 0.1 {
+        t.Fatalf("field without transform matches the transformed view (%v)", d)
+    }
+
+    v.SetTransform(tr)
+    f.CalcMandelbrot(v)
+    if d := insideMismatch(f, ref); d > 0.02 {
+        t.Errorf("%.1f%% of the pixels differ from package f64", 100.0*d)
+    }
+}
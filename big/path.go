@@ -1,20 +1,14 @@
 package big
 
 import (
-    "bufio"
-    "errors"
-    "fmt"
     "math"
     "math/big"
-    "os"
-    "regexp"
-    "strconv"
-    "strings"
+
+    "github.com/stefan-muehlebach/mandel"
 )
 
 const (
     BigFloatPrec = 100
-    pathFileName = "path.ini"
 )
 
 // Path
@@ -40,72 +34,32 @@ func (p *Path) SetSamples(samp int) {
     p.samp = samp
 }
 
-// Damit lassen sich die Stuetzstellen der Kamerafahrt aus einem File
-// einlesen. Format des Files:
-//
-// Neues Format:
-//
-//    xm0 ym0 w0 it0    (x/y des Mittelpunktes, Breite des Bildes, max Iter)
-//    xm1 ym1 w1 it1
-//    ...
+// Damit lassen sich die Stuetzstellen der Kamerafahrt aus der Szene
+// pathName in path.ini einlesen (siehe [mandel.LoadScene] fuer das Format).
+// Die Koordinaten werden mit der vollen Genauigkeit aus der Datei
+// uebernommen.
 func ReadPath(pathName string) (*Path, error) {
     var p *Path
-    var fd *os.File
-    var scanner *bufio.Scanner
-    var line string
-    var matches []string
+    var s *mandel.Scene
     var err error
     var x, y, w *big.Float
-    var it int64
-    var regComm, regBlock, regData *regexp.Regexp
-    var inBlock bool
 
-    x = big.NewFloat(0.0).SetPrec(100)
-    y = big.NewFloat(0.0).SetPrec(100)
-    w = big.NewFloat(0.0).SetPrec(100)
+    x = big.NewFloat(0.0).SetPrec(BigFloatPrec)
+    y = big.NewFloat(0.0).SetPrec(BigFloatPrec)
+    w = big.NewFloat(0.0).SetPrec(BigFloatPrec)
 
-    regComm = regexp.MustCompile(`^ *(#.*)?$`)
-    regBlock = regexp.MustCompile(`^ *\[([[:alnum:]]+)\] *$`)
-    regData = regexp.MustCompile(`^ *([+-]?[0-9]+\.[0-9]+) +([+-]?[0-9]+\.[0-9]+) +([+-]?[0-9]+\.[0-9]+(?:[Ee]-?[0-9]+)?) +([0-9]+) *$`)
-
-    fd, err = os.Open(pathFileName)
+    s, err = mandel.LoadScene(pathName)
     if err != nil {
         return nil, err
     }
     p = NewPath()
-    inBlock = false
-    scanner = bufio.NewScanner(fd)
-    for scanner.Scan() {
-        line = scanner.Text()
-        if regComm.MatchString(line) {
-            continue
-        }
-        if inBlock {
-            if regData.MatchString(line) {
-                matches = regData.FindStringSubmatch(line)
-                x.SetString(matches[1])
-                y.SetString(matches[2])
-                w.SetString(matches[3])
-                it, _ = strconv.ParseInt(matches[4], 10, 32)
-                p.AddView(x, y, w, int(it))
-            } else if regBlock.MatchString(line) {
-                break
-            } else {
-                return nil, errors.New(fmt.Sprintf("error on line: %s", line))
-            }
-        } else {
-            if regBlock.MatchString(line) {
-                matches = regBlock.FindStringSubmatch(line)
-                if strings.Compare(matches[1], pathName) == 0 {
-                    inBlock = true
-                }
-            }
-        }
-    }
-    if !inBlock {
-        return nil, errors.New(fmt.Sprintf("no path with name '%s' found!", pathName))
+    for _, k := range s.Keys {
+        x.SetString(k.X)
+        y.SetString(k.Y)
+        w.SetString(k.W)
+        p.AddView(x, y, w, k.MaxIter)
+        p.viewList[len(p.viewList)-1].tr = k.Transform
     }
-    fd.Close()
     return p, nil
 }
 
@@ -140,6 +94,7 @@ func (p *Path) GetView(t float64, v *View) {
         v.y.Set(p.viewList[i].y)
         v.w.Set(p.viewList[i].w)
         v.it = p.viewList[i].it
+        v.tr = p.viewList[i].tr
     } else {
         i := int(t * float64(len(p.viewList)-1))
         v0 = p.viewList[i]
@@ -176,6 +131,7 @@ func (p *Path) GetView(t float64, v *View) {
         v.y.Set(y)
         v.w.Set(w)
         v.it = it
+        v.tr = v0.tr.Interp(v1.tr, tt)
     }
     v.samp = p.samp
 }
@@ -2,13 +2,18 @@ package big
 
 import (
     "math/big"
+
+    "github.com/stefan-muehlebach/mandel"
 )
 
 // View ist eine Ansicht der komplexen Zahlenebene mit einem bestimmten
 // Anzeigebereich, einem Mittelpunkt und einer bestimmten Anzahl Iterationen.
+// Mit tr kann die Ansicht gedreht und verzerrt werden (siehe
+// [mandel.Transform]).
 type View struct {
     x, y, w  *big.Float
     it, samp int
+    tr       mandel.Transform
 }
 
 // Erstellt eine neue, leere View ohne definierten Bereich oder Mittelpunkt.
@@ -32,3 +37,13 @@ func (v *View) SetValues(x, y, w *big.Float, it, samp int) {
 func (v *View) GetValues() (x, y, w *big.Float, it, samp int) {
     return v.x, v.y, v.w, v.it, v.samp
 }
+
+// Liefert die Drehung und Verzerrung der Ansicht.
+func (v *View) Transform() mandel.Transform {
+    return v.tr
+}
+
+// Setzt die Drehung und Verzerrung der Ansicht.
+func (v *View) SetTransform(tr mandel.Transform) {
+    v.tr = tr
+}
@@ -4,7 +4,7 @@ import (
 	"flag"
 	"fmt"
 	_ "image"
-	_ "image/color"
+	"image/color"
 	"image/png"
 	"io/fs"
 	"log"
@@ -27,6 +27,9 @@ const (
     defImgDir      = "images"
     binFilePattern = "*.bin"
 	imgFilePattern = "img%05d.png"
+	defHistogram   = false
+	defLighting    = false
+	defBits        = 8
 )
 
 var (
@@ -34,17 +37,43 @@ var (
 	palLength      int
 	palOffset      float64
 	binDir, imgDir string
+	histogram      bool
+	layerName      string
+	lighting       bool
+	bits           int
+	inside         string
+	transfer       string
 )
 
 func check(err error) {
     if err != nil {
-        log.Fatalf(err.Error())
+        log.Fatal(err)
     }
 }
 
+// Erstellt aufgrund der Kommandozeilen-Optionen den Colorizer, mit welchem
+// die eingelesenen Felder eingefaerbt werden.
+func newColorizer(palette mandel.Palette) (colorizer mandel.Colorizer) {
+	if layerName != "" {
+		colorizer, err := mandel.ReadLayerColorizer(layerName)
+		check(err)
+		return colorizer
+	}
+	if histogram {
+		colorizer = mandel.NewHistogramColorizer(palette)
+	} else {
+		colorizer = mandel.NewPaletteColorizer(palette)
+	}
+	if lighting {
+		colorizer = mandel.NewLightingColorizer(colorizer, 45.0, 45.0, 8.0, 0.3)
+	}
+	return colorizer
+}
+
 func main() {
 	var nWorkers int
 	var palette mandel.Palette
+	var colorizer mandel.Colorizer
 	var field mandel.Field
 	var outFile string
 	var fh *os.File
@@ -53,6 +82,7 @@ func main() {
 
 	nWorkers = runtime.NumCPU()
 
+	mandel.ConfigFlag()
 	flag.StringVar(&palName, "palette", defPalName, "palette name")
 	flag.IntVar(&palLength, "palLength", defPalLength,
 		"palette length (default: equal to max iterations)")
@@ -61,7 +91,21 @@ func main() {
 	flag.StringVar(&binDir, "bindir", defBinDir, "input directory with binary files")
 	flag.StringVar(&imgDir, "imgdir", defImgDir, "output directory for images")
 	flag.IntVar(&nWorkers, "workers", 4, "number of go routines")
+	flag.BoolVar(&histogram, "histogram", defHistogram,
+		"distribute the palette colors by histogram equalisation")
+	flag.BoolVar(&lighting, "lighting", defLighting,
+		"add lighting (slope shading) to the images")
+	flag.StringVar(&layerName, "layers", "",
+		"name of a layer stack (overrides palette, histogram and lighting)")
+	flag.IntVar(&bits, "bits", defBits, "bits per color channel in the images (8 or 16)")
+	flag.StringVar(&inside, "inside", "",
+		"color inside the set (#rrggbb, #rrggbbaa or transparent; default: from palette)")
+	flag.StringVar(&transfer, "transfer", "",
+		"transfer function (linear, log, sqrt, pow:e or cyclic:d; default: from palette)")
 	flag.Parse()
+	if bits != 8 && bits != 16 {
+		log.Fatalf("invalid number of bits per color channel: %d", bits)
+	}
 
 	fmt.Printf("palette name    : %s\n", palName)
 	fmt.Printf("palette length  : %d\n", palLength)
@@ -69,10 +113,19 @@ func main() {
 	fmt.Printf("input dir       : %s\n", binDir)
 	fmt.Printf("output dir      : %s\n", imgDir)
 	fmt.Printf("#workers        : %d\n", nWorkers)
+	fmt.Printf("histogram       : %v\n", histogram)
+	fmt.Printf("lighting        : %v\n", lighting)
+	fmt.Printf("layer stack     : %s\n", layerName)
+	fmt.Printf("bits/channel    : %d\n", bits)
+	fmt.Printf("inside color    : %s\n", inside)
+	fmt.Printf("transfer        : %s\n", transfer)
 
 	os.Mkdir(imgDir, 0755)
 	fileSystem := os.DirFS(".")
 	field = f64.NewField(1, 1, mandel.Samp1x1)
+	if bits == 16 {
+		field.SetColorModel(color.RGBA64Model)
+	}
 	palette, err = mandel.NewPalette(palName)
 	check(err)
 	if palLength < 0 {
@@ -82,7 +135,14 @@ func main() {
 		palette.SetLength(palLength)
 	}
 	palette.SetOffset(palOffset / 100.0)
+	if inside != "" {
+		check(palette.SetOption("inside", inside))
+	}
+	if transfer != "" {
+		check(palette.SetOption("transfer", transfer))
+	}
 	field.AddPalette(palette)
+	colorizer = newColorizer(palette)
 	i = 0
 	fs.WalkDir(fileSystem, binDir,
         func(inFile string, d fs.DirEntry, err error) error {
@@ -93,11 +153,10 @@ func main() {
         		fmt.Printf("processing '%s'\n", inFile)
         		err = field.Read(inFile)
         		check(err)
-        		field.AdjPalette()
         		outFile = fmt.Sprintf(imgFilePattern, i)
         		fh, err = os.Create(path.Join(imgDir, outFile))
         		check(err)
-        		png.Encode(fh, field)
+        		png.Encode(fh, colorizer.Colorize(field))
         		fh.Close()
         		i++
         		return nil
@@ -0,0 +1,66 @@
+// Dieses Programm zeigt, aus welchen Verzeichnissen und Dateien die
+// Konfiguration (Paletten, Pfade, Ebenen-Stapel) gelesen wird. Die
+// Verzeichnisse werden in aufsteigender Prioritaet ausgegeben, d.h. Angaben
+// aus spaeter aufgefuehrten Dateien haben Vorrang. Mit '-palettes' wird
+// ausserdem fuer jede Palette angegeben, aus welcher Datei sie stammt.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/stefan-muehlebach/mandel"
+)
+
+var (
+	confFiles = []string{"palette.ini", "path.ini", "layer.ini"}
+	palettes  bool
+)
+
+func main() {
+	mandel.ConfigFlag()
+	flag.BoolVar(&palettes, "palettes", false, "show the source of every palette")
+	flag.Parse()
+
+	fmt.Println("search path (lowest precedence first):")
+	for i, d := range mandel.ConfDirs() {
+		state := ""
+		if fi, err := os.Stat(d.Path); err != nil || !fi.IsDir() {
+			state = " (missing)"
+		}
+		fmt.Printf("  %d. %-14s %s%s\n", i+1, d.Origin, d.Path, state)
+	}
+
+	for _, fileName := range confFiles {
+		fmt.Printf("\n%s:\n", fileName)
+		if fileName == "palette.ini" {
+			fmt.Println("  (embedded defaults)")
+		}
+		files := mandel.ConfFiles(fileName)
+		for _, f := range files {
+			fmt.Printf("  %s\n", f)
+		}
+		if len(files) == 0 && fileName != "palette.ini" {
+			fmt.Println("  (not found)")
+		}
+	}
+
+	if !palettes {
+		return
+	}
+	reg, err := mandel.DefaultPaletteRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("\npalettes:")
+	for _, name := range reg.Names() {
+		source, line, _ := reg.Source(name)
+		status := ""
+		if _, err := reg.Palette(name); err != nil {
+			status = "  ERROR: " + err.Error()
+		}
+		fmt.Printf("  %-20s %s:%d%s\n", name, source, line, status)
+	}
+}
@@ -1,18 +1,16 @@
 package main
 
 import (
-	"log"
     "flag"
     "fmt"
-    _ "image"
-    _ "image/color"
+    "image"
+    "image/color"
     "image/png"
-    _ "log"
-    _ "math/big"
+    "log"
+    "math"
     "os"
     "path/filepath"
     "runtime"
-    _ "runtime/trace"
     "time"
 
     "github.com/stefan-muehlebach/mandel"
@@ -30,7 +28,11 @@ const (
     defBinDir     = "data"
     defImgDir     = "images"
     defNumImages  = 128
+    defFPS        = 25.0
     defSampleMode = mandel.Samp1x1
+    defHistogram  = false
+    defLighting   = false
+    defBits       = 8
 
     imgFilePattern = "img%05d.png"
     binFilePattern = "img%05d.bin"
@@ -46,16 +48,221 @@ var (
     outDir         string
     pathName       string
     numImages      int
+    fps            float64
+    duration       float64
+    timing         mandel.Timing
+    autoIter       bool
+    iterCurve      *mandel.IterCurve
+    render         mandel.RenderMode
+    expStrips      *f64.ExpMapStrips
+    quality        float64
+    keyframes      *f64.Keyframes
+    blur           mandel.BlurMode
+    blurSamples    int
+    shutter        float64
     totalImages    int
     sampleMode     mandel.SampleMode = defSampleMode
+    histogram      bool
+    layerName      string
+    lighting       bool
+    bits           int
+    inside         string
+    transferName   string
+    transfer       mandel.Transfer
+    scene          *mandel.Scene
  ) 
 
 func check(err error) {
     if err != nil {
-        log.Fatalf(err.Error())
+        log.Fatal(err)
     }
 }
 
+// Erstellt aufgrund der Kommandozeilen-Optionen den Colorizer, mit welchem
+// die berechneten Felder eingefaerbt werden. Ein Ebenen-Stapel (-layers)
+// wird nicht hier, sondern einmal pro Worker erstellt.
+func newColorizer(palette mandel.Palette) (colorizer mandel.Colorizer) {
+    if histogram {
+        colorizer = mandel.NewHistogramColorizer(palette)
+    } else {
+        colorizer = mandel.NewPaletteColorizer(palette)
+    }
+    if lighting {
+        colorizer = mandel.NewLightingColorizer(colorizer, 45.0, 45.0, 8.0, 0.3)
+    }
+    return colorizer
+}
+
+// Liefert die Palette mit dem Namen name. Jede Palette wird pro Worker nur
+// einmal eingelesen und anschliessend in pals zwischengespeichert.
+func loadPalette(pals map[string]mandel.Palette, name string) mandel.Palette {
+    if pal, ok := pals[name]; ok {
+        return pal
+    }
+    pal, err := scene.NewPalette(name)
+    check(err)
+    pals[name] = pal
+    return pal
+}
+
+// Erstellt die Palette fuer ein einzelnes Bild. Angaben, welche im Pfad
+// nicht gemacht werden, werden den Kommandozeilen-Optionen entnommen.
+func framePalette(pals map[string]mandel.Palette, frame mandel.PaletteFrame) (palette mandel.Palette) {
+    name0, name1 := palName, palName
+    if frame.Fields&mandel.KeyName != 0 {
+        name0, name1 = frame.Name0, frame.Name1
+    }
+    palette = loadPalette(pals, name0)
+    if name0 != name1 && frame.Mix > 0.0 {
+        blend := mandel.NewBlendPalette(palette, loadPalette(pals, name1))
+        blend.SetMix(frame.Mix)
+        palette = blend
+    }
+    length := palLength
+    if frame.Fields&mandel.KeyLength != 0 {
+        length = frame.Length
+    }
+    if length < 0 {
+        palette.LenIsMaxIter()
+    } else {
+        palette.LenIsNotMaxIter()
+        palette.SetLength(length)
+    }
+    if frame.Fields&mandel.KeyOffset == 0 {
+        frame.Offset = palOffset/100.0
+    }
+    palette.SetOffset(frame.CycleOffset(1.0))
+    if inside != "" {
+        check(palette.SetOption("inside", inside))
+    }
+    if frame.Fields&mandel.KeyTransfer != 0 {
+        palette.SetTransfer(frame.Transfer)
+        palette.BlendTransfer(frame.Transfer1, frame.TransferMix)
+    } else if transferName != "" {
+        palette.SetTransfer(transfer)
+    }
+    return palette
+}
+
+// Prueft, ob in der Szene s Angaben zur Palette gemacht werden (fuer die
+// ganze Szene oder bei einzelnen Stuetzstellen).
+func hasPaletteKeys(s *mandel.Scene) bool {
+    if s.Palette.Fields != 0 {
+        return true
+    }
+    for _, k := range s.Keys {
+        if k.Palette.Fields != 0 {
+            return true
+        }
+    }
+    return false
+}
+
+// Uebernimmt die Einstellungen der Szene fuer alle Optionen, welche nicht
+// auf der Kommandozeile angegeben wurden. Angaben zur Palette auf der
+// Kommandozeile haben Vorrang vor denjenigen der Szene, nicht aber vor
+// denjenigen der einzelnen Stuetzstellen.
+func applyScene(scene *mandel.Scene) {
+    set := make(map[string]bool)
+    flag.Visit(func(f *flag.Flag) {
+        set[f.Name] = true
+    })
+    use := func(key, flagName string) bool {
+        return scene.IsSet(key) && !set[flagName]
+    }
+
+    if use("size", "cols") {
+        cols = scene.Cols
+    }
+    if use("size", "rows") {
+        rows = scene.Rows
+    }
+    if use("sampling", "sampleMode") {
+        sampleMode = scene.Sampling
+    }
+    if use("images", "images") {
+        numImages = scene.Images
+    }
+    if use("fps", "fps") {
+        fps = scene.FPS
+    }
+    if use("duration", "duration") {
+        duration = scene.Duration
+    }
+    if use("timing", "timing") {
+        timing = scene.Timing
+    }
+    if use("autoiter", "autoIter") {
+        autoIter = scene.AutoIter
+    }
+    if use("render", "render") {
+        render = scene.Render
+    }
+    if use("quality", "quality") {
+        quality = scene.Quality
+    }
+    if use("blur", "blur") {
+        blur = scene.Blur
+    }
+    if use("blursamples", "blurSamples") {
+        blurSamples = scene.BlurSamples
+    }
+    if use("shutter", "shutter") {
+        shutter = scene.Shutter
+    }
+    if use("bits", "bits") {
+        bits = scene.Bits
+    }
+    if use("imgdir", "imgdir") {
+        imgDir = scene.ImgDir
+    }
+    if use("layers", "layers") {
+        layerName = scene.Layers
+    }
+    if use("histogram", "histogram") {
+        histogram = scene.Histogram
+    }
+    if use("lighting", "lighting") {
+        lighting = scene.Lighting
+    }
+    if use("inside", "inside") {
+        inside = scene.Inside
+    }
+    if set["palette"] {
+        scene.Palette.Fields &^= mandel.KeyName
+    }
+    if set["palLength"] {
+        scene.Palette.Fields &^= mandel.KeyLength
+    }
+    if set["palOffset"] {
+        scene.Palette.Fields &^= mandel.KeyOffset
+    }
+    if set["transfer"] {
+        scene.Palette.Fields &^= mandel.KeyTransfer
+    }
+}
+
+// Liefert die Anzahl Stellen, an welchen die Anzahl Iterationen geschaetzt
+// wird: etwa alle 8 Bilder, aber hoechstens 256 Mal.
+func autoIterSamples() int {
+    return min(max((totalImages-1)/8, 1), 256)
+}
+
+// Liefert die Ansicht an der Stelle t des Pfades. Wird die Anzahl
+// Iterationen geschaetzt, so ersetzt der Wert der Schaetzung denjenigen des
+// Pfades.
+func frameView(path mandel.Path, t float64) mandel.View {
+    view := path.GetView(t)
+    if iterCurve != nil {
+        x, y, w, _ := view.Values()
+        tr := view.Transform()
+        view = f64.NewView()
+        view.SetValues(x, y, w, iterCurve.At(t))
+        view.SetTransform(tr)
+    }
+    return view
+}
+
 // Diese Funktion wird von mehreren Go-Routinen ausgefuert. Auf diesem Level
 // findet die Parallelisierung statt. Gesteuert werden die Routinen ueber die
 // Channels ch (Input-Channel fuer die Auftraege) und done (Output-Channel
@@ -65,25 +272,47 @@ func Worker(id int, ch <-chan int, done chan<- bool, path mandel.Path) {
     var t float64
     var i int
     var field mandel.Field
-    var palette mandel.Palette
+    var f64Field = f64.NewField(cols, rows, sampleMode)
+    var pals map[string]mandel.Palette
     var view mandel.View
+    var img image.Image
     var outFile string
     var fh *os.File
     var err error
 
-    field = f64.NewField(cols, rows, sampleMode)
-    palette, err = mandel.NewPalette(palName)
-    check(err)
-    if palLength < 0 {
-        palette.LenIsMaxIter()
-    } else {
-        palette.LenIsNotMaxIter()
-        palette.SetLength(palLength)
+    field = f64Field
+    if bits == 16 {
+        field.SetColorModel(color.RGBA64Model)
+    }
+    pals = make(map[string]mandel.Palette)
+
+    // Die Ebenen eines Stapels verwenden ihre eigenen Paletten aus
+    // layer.ini; der Stapel wird daher nur einmal eingelesen.
+    var layers *mandel.LayerColorizer
+    if layerName != "" {
+        layers, err = mandel.ReadLayerColorizer(layerName)
+        check(err)
     }
-    palette.SetOffset(palOffset/100.0)
-    field.AddPalette(palette)
 
-    // view = &f64.View{}
+    // Berechnet das Feld fuer die Ansicht v des Bildes i.
+    calc := func(v mandel.View) {
+        if expStrips != nil {
+            f64Field.CalcExpMap(expStrips, v)
+        } else if keyframes != nil {
+            f64Field.CalcKeyframes(keyframes, i, v)
+        } else {
+            field.CalcMandelbrot(v)
+        }
+    }
+    // Faerbt das Feld mit der Palette an der Stelle t des Pfades ein.
+    colorize := func(t float64) image.Image {
+        if layers != nil {
+            return layers.Colorize(field)
+        }
+        palette := framePalette(pals, path.GetPalette(t))
+        field.AddPalette(palette)
+        return newColorizer(palette).Colorize(field)
+    }
 
     for {
         i = <-ch
@@ -91,16 +320,41 @@ func Worker(id int, ch <-chan int, done chan<- bool, path mandel.Path) {
             break
         }
         t1 = time.Now()
-        t = float64(i) / float64(totalImages)
-        view = path.GetView(t)
-        field.CalcMandelbrot(view)
+        t = 0.0
+        if totalImages > 1 {
+            t = float64(i) / float64(totalImages-1)
+        }
+        if writeBin || blur == mandel.BlurOff {
+            view = frameView(path, t)
+            calc(view)
+            if !writeBin {
+                img = colorize(t)
+            }
+        } else {
+            ts := mandel.ShutterTimes(t, 1.0/float64(max(totalImages-1, 1)), shutter, blurSamples)
+            if blur == mandel.BlurSamples {
+                accum := mandel.NewLinearAccum(field.Bounds())
+                for _, ts := range ts {
+                    calc(frameView(path, ts))
+                    accum.Add(colorize(ts), 1.0)
+                }
+                img = accum.Image(field.ColorModel())
+            } else {
+                view = frameView(path, t)
+                calc(view)
+                views := make([]mandel.View, len(ts))
+                for j, ts := range ts {
+                    views[j] = frameView(path, ts)
+                }
+                img = mandel.FlowBlur(colorize(t), view, views)
+            }
+        }
         t2 = time.Now()
         if !writeBin {
             outFile = fmt.Sprintf(imgFilePattern, i)
             fh, err = os.Create(filepath.Join(imgDir, outFile))
             check(err)
-            field.AdjPalette()
-            png.Encode(fh, field)
+            png.Encode(fh, img)
             fh.Close()
         } else {
             outFile = fmt.Sprintf(binFilePattern, i)
@@ -120,24 +374,68 @@ func main() {
     var path mandel.Path
     var ch chan int
     var done chan bool
+    var expMap *mandel.ExpMap
     var err error
 
     nWorkers = runtime.NumCPU()
 
+    mandel.ConfigFlag()
     flag.BoolVar(&writeBin, "bin", defWriteBin, "write data as binary files instead of images")
     flag.IntVar(&cols, "cols", defNumCols, "number of columns")
     flag.IntVar(&rows, "rows", defNumRows, "number of rows")
     flag.StringVar(&palName, "palette", defPalName, "palette name")
     flag.IntVar(&palLength, "palLength", defPalLength, "palette length (default: equal to max iterations)")
     flag.Float64Var(&palOffset, "palOffset", 0.0, "offset (in %) of the first color of the palette")
-    flag.StringVar(&pathName, "path", defPathName, "path name")
+    flag.StringVar(&pathName, "path", defPathName, "path (scene) name; the settings of the scene are used for all options not given")
     flag.StringVar(&imgDir, "imgdir", defImgDir, "output directory for images")
     flag.StringVar(&binDir, "bindir", defBinDir, "output directory for binary files")
     flag.Var(&sampleMode, "sampleMode", "mode of subpixel sampling")
     flag.IntVar(&nWorkers, "workers", 4, "number of go routines")
-    flag.IntVar(&numImages, "images", defNumImages, "number of images between two views")
+    flag.IntVar(&numImages, "images", defNumImages, "number of images between two views (if no duration is given)")
+    flag.Float64Var(&fps, "fps", defFPS, "frames per second")
+    flag.Float64Var(&duration, "duration", 0.0, "total duration of the animation in seconds (default: from -images)")
+    flag.BoolVar(&autoIter, "autoIter", false, "estimate the max. number of iterations instead of using the values of the path")
+    flag.Var(&render, "render", "how the images are calculated (frames, expmap or keyframes)")
+    flag.Float64Var(&quality, "quality", 1.0, "max. magnification of the keyframe pixels (1: as sharp as a full render)")
+    flag.Var(&blur, "blur", "motion blur (off, samples or flow)")
+    flag.IntVar(&blurSamples, "blurSamples", 4, "number of views per image for motion blur")
+    flag.Float64Var(&shutter, "shutter", 180.0, "shutter angle in degrees for motion blur")
+    flag.Var(&timing, "timing", "distribution of the duration over the segments (uniform or zoom)")
+    flag.BoolVar(&histogram, "histogram", defHistogram, "distribute the palette colors by histogram equalisation")
+    flag.BoolVar(&lighting, "lighting", defLighting, "add lighting (slope shading) to the images")
+    flag.StringVar(&layerName, "layers", "", "name of a layer stack (overrides palette, histogram and lighting; not allowed with palette keys in the path)")
+    flag.IntVar(&bits, "bits", defBits, "bits per color channel in the images (8 or 16)")
+    flag.StringVar(&inside, "inside", "", "color inside the set (#rrggbb, #rrggbbaa or transparent; default: from palette)")
+    flag.StringVar(&transferName, "transfer", "", "transfer function (linear, log, sqrt, pow:e or cyclic:d; default: from palette)")
     flag.Parse()
 
+    scene, err = mandel.LoadScene(pathName)
+    check(err)
+    applyScene(scene)
+    // Die Paletten eines Ebenen-Stapels sind fest vorgegeben und koennen
+    // nicht entlang des Pfades animiert werden.
+    if layerName != "" && hasPaletteKeys(scene) {
+        log.Fatalf("layer stack '%s' can't be combined with the palette keys of scene '%s'",
+                layerName, pathName)
+    }
+    if quality < 1.0 {
+        log.Fatalf("invalid quality: %g", quality)
+    }
+    if blurSamples < 1 || shutter <= 0.0 || shutter > 360.0 {
+        log.Fatalf("invalid motion blur settings: %d samples, %g deg", blurSamples, shutter)
+    }
+    if bits != 8 && bits != 16 {
+        log.Fatalf("invalid number of bits per color channel: %d", bits)
+    }
+    if inside != "" {
+        _, err = mandel.ParseColor(inside)
+        check(err)
+    }
+    if transferName != "" {
+        transfer, err = mandel.ParseTransfer(transferName)
+        check(err)
+    }
+
     if writeBin {
         outDir = binDir
     } else {
@@ -153,13 +451,30 @@ func main() {
     fmt.Printf("output dir      : %s\n", outDir)
     fmt.Printf("#workers        : %d\n", nWorkers)
     fmt.Printf("#images/view    : %d\n", numImages)
+    fmt.Printf("frames/second   : %.1f\n", fps)
+    fmt.Printf("duration        : %.1f s\n", duration)
+    fmt.Printf("timing          : %v\n", timing)
+    fmt.Printf("auto iterations : %v\n", autoIter)
+    fmt.Printf("render mode     : %v\n", render)
+    fmt.Printf("quality         : %.2f\n", quality)
+    fmt.Printf("motion blur     : %v (%d samples, %.0f deg)\n", blur, blurSamples, shutter)
     fmt.Printf("sample mode     : %v\n", sampleMode)
+    fmt.Printf("histogram       : %v\n", histogram)
+    fmt.Printf("lighting        : %v\n", lighting)
+    fmt.Printf("layer stack     : %s\n", layerName)
+    fmt.Printf("bits/channel    : %d\n", bits)
+    fmt.Printf("inside color    : %s\n", inside)
+    fmt.Printf("transfer        : %s\n", transferName)
+    fmt.Printf("scene palette   : %s\n", scene.Palette)
 
     os.Mkdir(outDir, 0755)
 
     path = f64.NewPath()
-    err = path.Read(pathName)
+    err = path.SetScene(scene)
     check(err)
+    durs, err := scene.Durations(timing, duration, float64(numImages)/fps)
+    check(err)
+    path.SetDurations(durs)
 
     // if pth.NumViews() == 1 {
     //     field := f64.NewField(cols, rows, sampleMode)
@@ -194,7 +509,43 @@ func main() {
 
     ch   = make(chan int)
     done = make(chan bool)
-    totalImages = numImages*(path.NumViews()-1) + 1
+    total := 0.0
+    for _, d := range durs {
+        total += d
+    }
+    totalImages = int(math.Round(total*fps)) + 1
+
+    if autoIter {
+        curve := mandel.NewAutoIter().Curve(path, autoIterSamples(), cols, rows,
+                func(cols, rows int) mandel.Field {
+                    return f64.NewField(cols, rows, mandel.Samp1x1)
+                })
+        iterCurve = &curve
+    }
+
+    // Bei einer exponentiellen Karte wird die Anzahl Iterationen etwa alle
+    // 8 Bilder bestimmt. Die Streifen selber werden erst von den Workern
+    // bei Bedarf berechnet.
+    if render == mandel.RenderExpMap {
+        var iter func(t float64) int
+        if iterCurve != nil {
+            iter = iterCurve.At
+        }
+        expMap = mandel.PlanExpMap(path, max((totalImages-1)/8, 1), cols, rows, sampleMode, iter)
+        expStrips = f64.NewExpMapStrips(expMap)
+        fmt.Printf("exp. map        : %d columns, %d rows/octave, radius %g\n",
+                expMap.Cols, expMap.RowsPerOctave(), expMap.RMax)
+    }
+    if render == mandel.RenderKeyframes {
+        var iter func(t float64) int
+        if iterCurve != nil {
+            iter = iterCurve.At
+        }
+        plan := mandel.NewKeyframePlan(quality)
+        plan.Plan(path, totalImages-1, cols, rows, iter)
+        keyframes = f64.NewKeyframes(plan, sampleMode)
+        fmt.Printf("keyframes       : %d\n", len(plan.Keys))
+    }
 
     t1 := time.Now()
     for i := 0; i < nWorkers; i++ {
@@ -202,6 +553,15 @@ func main() {
     }
     for i := 0; i < totalImages; i++ {
         ch <- i
+        // Streifen, welche fuer die Bilder der Worker nicht mehr benoetigt
+        // werden, werden freigegeben. Zoomt der Pfad wieder hinaus, so
+        // werden sie bei Bedarf neu berechnet.
+        if j := i - 2*nWorkers; expStrips != nil && j >= 0 {
+            expStrips.Release(2.0 * expMap.ViewRadius(path.GetView(float64(j)/float64(totalImages-1)), cols, rows))
+        }
+        if j := i - 2*nWorkers; keyframes != nil && j >= 0 {
+            keyframes.Release(j)
+        }
     }
     for i := 0; i < nWorkers; i++ {
         ch <- -1
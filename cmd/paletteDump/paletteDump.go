@@ -1,7 +1,7 @@
 package main
 
 import (
-	_ "flag"
+	"flag"
 	"fmt"
 	"image"
 	"image/png"
@@ -27,6 +27,8 @@ const (
 func main() {
 	var palType string
 
+	mandel.ConfigFlag()
+	flag.Parse()
 	palNameList, err := mandel.PaletteNames()
 	if err != nil {
 		log.Fatalf("couldn't read palette names: %v", err)
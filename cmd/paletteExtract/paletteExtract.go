@@ -0,0 +1,156 @@
+// Mit diesem Programm wird aus einem Bild (PNG oder JPEG) eine Palette
+// erstellt, welche die Stimmung des Bildes wiedergibt. Die Palette wird als
+// neuer Abschnitt an palette.ini angehaengt. Zusaetzlich wird ein Vorschau-
+// bild erstellt, welches oben die gefundenen Farben (Breite entsprechend
+// ihrem Anteil im Bild) und unten den Verlauf der Palette zeigt.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stefan-muehlebach/mandel"
+)
+
+const (
+	defNumColors   = 8
+	defPreviewFile = "paletteExtract.png"
+
+	PreviewWidth   = 1024
+	SwatchHeight   = 60
+	ColorBarHeight = 100
+	Padding        = 10
+)
+
+var (
+	numColors   int
+	palName     string
+	outFile     string
+	previewFile string
+	dryRun      bool
+)
+
+func readImage(fileName string) (image.Image, error) {
+	fh, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	img, _, err := image.Decode(fh)
+	return img, err
+}
+
+// Erstellt das Vorschaubild mit den Farben colors und der Palette pal.
+func writePreview(fileName string, colors []mandel.ImageColor, pal mandel.Palette) error {
+	width := PreviewWidth + 2*Padding
+	height := SwatchHeight + ColorBarHeight + 3*Padding
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0xf5, 0xf5, 0xf5, 0xff}),
+		image.Point{}, draw.Src)
+
+	x0, sum := Padding, 0.0
+	for _, c := range colors {
+		sum += c.Weight
+		x1 := Padding + int(sum*PreviewWidth+0.5)
+		col := color.RGBA{uint8(255.0*c.R + 0.5), uint8(255.0*c.G + 0.5),
+			uint8(255.0*c.B + 0.5), 0xff}
+		draw.Draw(img, image.Rect(x0, Padding, x1, Padding+SwatchHeight),
+			image.NewUniform(col), image.Point{}, draw.Src)
+		x0 = x1
+	}
+
+	pal.SetLength(PreviewWidth)
+	pal.LenIsNotMaxIter()
+	pal.SetOffset(0.0)
+	y0 := SwatchHeight + 2*Padding
+	for x := 0; x < PreviewWidth; x++ {
+		col := pal.GetColor(float64(x))
+		for y := 0; y < ColorBarHeight; y++ {
+			img.Set(Padding+x, y0+y, col)
+		}
+	}
+
+	fh, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	return png.Encode(fh, img)
+}
+
+func main() {
+	var out io.Writer
+
+	mandel.ConfigFlag()
+	flag.IntVar(&numColors, "colors", defNumColors, "maximum number of colors in the palette")
+	flag.StringVar(&palName, "name", "", "name of the new palette (default: derived from the file name)")
+	flag.StringVar(&outFile, "out", "", "palette file to append the new palette to (default: palette.ini in the config path)")
+	flag.StringVar(&previewFile, "preview", defPreviewFile, "file name of the preview image (empty: no preview)")
+	flag.BoolVar(&dryRun, "n", false, "print the new section instead of appending it")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"usage: %s [options] image.{png,jpg}\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if outFile == "" {
+		defFile, err := mandel.ConfFilePath("palette.ini")
+		if err != nil {
+			log.Fatal(err)
+		}
+		outFile = defFile
+	}
+	fileName := flag.Arg(0)
+	if palName == "" {
+		palName = mandel.SectionName(strings.TrimSuffix(filepath.Base(fileName),
+			filepath.Ext(fileName)))
+	}
+
+	img, err := readImage(fileName)
+	if err != nil {
+		log.Fatalf("%s: %v", fileName, err)
+	}
+	colors, err := mandel.ExtractColors(img, numColors)
+	if err != nil {
+		log.Fatalf("%s: %v", fileName, err)
+	}
+	pal, err := mandel.PaletteFromColors(colors)
+	if err != nil {
+		log.Fatalf("%s: %v", fileName, err)
+	}
+
+	if dryRun {
+		out = os.Stdout
+	} else {
+		fh, err := os.OpenFile(outFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer fh.Close()
+		fmt.Fprintln(fh)
+		out = fh
+		fmt.Fprintf(os.Stderr, "%s: appending '%s' to %s\n", fileName, palName, outFile)
+	}
+	if err = pal.WriteSection(out, palName); err != nil {
+		log.Fatal(err)
+	}
+	if previewFile != "" {
+		if err = writePreview(previewFile, colors, pal); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
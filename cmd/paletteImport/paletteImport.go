@@ -0,0 +1,144 @@
+// Mit diesem Programm lassen sich Paletten aus anderen Programmen (Fractint,
+// Ultra Fractal, GIMP) importieren. Die Paletten werden als neue Abschnitte
+// an palette.ini angehaengt.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stefan-muehlebach/mandel"
+)
+
+var (
+	outFile string
+	dryRun  bool
+)
+
+// Liest die Datei fileName ein. Das Format wird anhand der Endung erkannt.
+func readPalettes(fileName string) ([]mandel.NamedPalette, error) {
+	fh, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	ext := strings.ToLower(filepath.Ext(fileName))
+	switch ext {
+	case ".map":
+		name := strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+		return mandel.ReadFractintMap(fh, name)
+	case ".ugr":
+		return mandel.ReadUltraFractalUgr(fh)
+	case ".ggr":
+		return mandel.ReadGimpGgr(fh)
+	default:
+		return nil, fmt.Errorf("unknown palette format '%s'", ext)
+	}
+}
+
+// Sorgt dafuer, dass name noch nicht vergeben ist, indem bei Bedarf eine
+// Nummer angehaengt wird.
+func uniqueName(name string, used map[string]bool) string {
+	newName := name
+	for i := 2; used[newName]; i++ {
+		newName = fmt.Sprintf("%s%d", name, i)
+	}
+	used[newName] = true
+	return newName
+}
+
+// Liefert die Namen aller Paletten, welche bereits in der Datei fileName
+// definiert sind. Existiert die Datei noch nicht, so ist kein Name vergeben.
+func usedNames(fileName string) (map[string]bool, error) {
+	used := make(map[string]bool)
+	fh, err := os.Open(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return used, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	names, err := mandel.ReadPaletteNames(fh)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		used[name] = true
+	}
+	return used, nil
+}
+
+// Haengt die Abschnitte in buf an die Datei fileName an.
+func appendFile(fileName string, buf []byte) error {
+	fh, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = fh.Write(buf); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+func main() {
+	var buf bytes.Buffer
+	var err error
+
+	mandel.ConfigFlag()
+	flag.StringVar(&outFile, "out", "", "palette file to append the imported palettes to (default: palette.ini in the config path)")
+	flag.BoolVar(&dryRun, "n", false, "print the new sections instead of appending them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"usage: %s [options] file.{map,ugr,ggr} ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if outFile == "" {
+		if outFile, err = mandel.ConfFilePath("palette.ini"); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Zuerst werden alle Dateien eingelesen, damit bei einem Fehler in
+	// einer der Dateien die Ausgabedatei unveraendert bleibt.
+	used, err := usedNames(outFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintln(&buf)
+	for _, fileName := range flag.Args() {
+		pals, err := readPalettes(fileName)
+		if err != nil {
+			log.Fatalf("%s: %v", fileName, err)
+		}
+		for _, np := range pals {
+			name := uniqueName(np.Name, used)
+			fmt.Fprintf(os.Stderr, "%s: importing '%s'\n", fileName, name)
+			if err = np.Palette.WriteSection(&buf, name); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if dryRun {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = appendFile(outFile, buf.Bytes())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
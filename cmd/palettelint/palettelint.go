@@ -0,0 +1,57 @@
+// Mit diesem Programm lassen sich Dateien mit Paletten (im Format von
+// palette.ini) pruefen. Alle gefundenen Probleme werden in der Form
+// 'datei:zeile:spalte: meldung' ausgegeben. Wurden Probleme gefunden,
+// endet das Programm mit dem Exit-Code 1. Werden keine Dateien angegeben, so
+// werden alle Dateien palette.ini im Suchpfad geprueft (siehe mandelConfig).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/stefan-muehlebach/mandel"
+)
+
+func lintFile(fileName string) (int, error) {
+	fh, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+	diags, err := mandel.LintPalettes(fh, fileName)
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	return len(diags), err
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"usage: %s [options] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	mandel.ConfigFlag()
+	flag.Parse()
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = mandel.ConfFiles("palette.ini")
+		if len(files) == 0 {
+			log.Fatal("no palette.ini found in the config path")
+		}
+	}
+	numDiags := 0
+	for _, fileName := range files {
+		n, err := lintFile(fileName)
+		if err != nil {
+			log.Fatalf("%s: %v", fileName, err)
+		}
+		numDiags += n
+	}
+	if numDiags > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,225 @@
+// Mit diesem Programm laesst sich vor einer (langen) Berechnung mit
+// mandelEngine ein Ueberblick ueber einen Pfad gewinnen: entlang des Pfades
+// werden gleichmaessig verteilt kleine Vorschaubilder berechnet und in
+// einem Raster angeordnet, jeweils mit der Nummer des Bildes (wie sie
+// mandelEngine verwenden wuerde) und den Koordinaten der Ansicht.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"math"
+	"os"
+
+	"github.com/stefan-muehlebach/gg"
+	"github.com/stefan-muehlebach/gg/color"
+	"github.com/stefan-muehlebach/gg/fonts"
+	"github.com/stefan-muehlebach/mandel"
+	"github.com/stefan-muehlebach/mandel/f64"
+)
+
+const (
+	defNumThumbs  = 16
+	defGridCols   = 4
+	defThumbWidth = 240
+	defNumImages  = 128
+	defFPS        = 25.0
+	defNumCols    = 320
+	defNumRows    = 240
+
+	Padding    = 8
+	TitleSize  = 18.0
+	LabelSize  = 11.0
+	LineHeight = 14
+)
+
+var (
+	pathName   string
+	palName    string
+	outFile    string
+	numThumbs  int
+	gridCols   int
+	thumbWidth int
+	sampleMode mandel.SampleMode = mandel.Samp2x2
+	scene      *mandel.Scene
+)
+
+// Liefert die Palette fuer die Angaben frame, analog zu mandelEngine, aber
+// ohne die dortigen Kommandozeilen-Optionen.
+func framePalette(frame mandel.PaletteFrame) mandel.Palette {
+	name0, name1 := palName, palName
+	if frame.Fields&mandel.KeyName != 0 {
+		name0, name1 = frame.Name0, frame.Name1
+	}
+	palette, err := scene.NewPalette(name0)
+	if err != nil {
+		log.Fatalf("couldn't create palette: %v", err)
+	}
+	if name0 != name1 && frame.Mix > 0.0 {
+		palette1, err := scene.NewPalette(name1)
+		if err != nil {
+			log.Fatalf("couldn't create palette: %v", err)
+		}
+		blend := mandel.NewBlendPalette(palette, palette1)
+		blend.SetMix(frame.Mix)
+		palette = blend
+	}
+	if frame.Fields&mandel.KeyLength != 0 && frame.Length >= 0 {
+		palette.LenIsNotMaxIter()
+		palette.SetLength(frame.Length)
+	} else {
+		palette.LenIsMaxIter()
+	}
+	palette.SetOffset(frame.CycleOffset(1.0))
+	if frame.Fields&mandel.KeyTransfer != 0 {
+		palette.SetTransfer(frame.Transfer)
+		palette.BlendTransfer(frame.Transfer1, frame.TransferMix)
+	}
+	if scene.Inside != "" {
+		if err := palette.SetOption("inside", scene.Inside); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return palette
+}
+
+// Liefert die Anzahl Bilder, welche mandelEngine mit den Einstellungen der
+// Szene fuer den Pfad path berechnen wuerde.
+func totalImages(path mandel.Path) int {
+	numImages, fps := defNumImages, defFPS
+	if scene.IsSet("images") {
+		numImages = scene.Images
+	}
+	if scene.IsSet("fps") {
+		fps = scene.FPS
+	}
+	durs, err := scene.Durations(scene.Timing, scene.Duration, float64(numImages)/fps)
+	if err != nil {
+		log.Fatal(err)
+	}
+	path.SetDurations(durs)
+	total := 0.0
+	for _, d := range durs {
+		total += d
+	}
+	return int(math.Round(total*fps)) + 1
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"usage: %s [options]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	mandel.ConfigFlag()
+	flag.StringVar(&pathName, "path", "Default", "path (scene) name")
+	flag.StringVar(&palName, "palette", "Default", "palette name (if not given in the path)")
+	flag.StringVar(&outFile, "out", "", "output file (default: storyboard<path>.png)")
+	flag.IntVar(&numThumbs, "n", defNumThumbs, "number of thumbnails")
+	flag.IntVar(&gridCols, "gridCols", defGridCols, "number of thumbnails per row")
+	flag.IntVar(&thumbWidth, "width", defThumbWidth, "width of a thumbnail in pixels")
+	flag.Var(&sampleMode, "sampleMode", "mode of subpixel sampling")
+	flag.Parse()
+
+	if numThumbs < 1 || gridCols < 1 || thumbWidth < 1 {
+		log.Fatal("the number and size of the thumbnails must be positive")
+	}
+	if outFile == "" {
+		outFile = fmt.Sprintf("storyboard%s.png", pathName)
+	}
+
+	var err error
+	scene, err = mandel.LoadScene(pathName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := f64.NewPath()
+	if err = path.SetScene(scene); err != nil {
+		log.Fatal(err)
+	}
+	numImages := totalImages(path)
+
+	// Die Vorschaubilder haben das Seitenverhaeltnis der Bilder, welche
+	// mandelEngine erstellen wuerde.
+	cols, rows := defNumCols, defNumRows
+	if scene.IsSet("size") {
+		cols, rows = scene.Cols, scene.Rows
+	}
+	thumbHeight := max(int(math.Round(float64(thumbWidth)*float64(rows)/float64(cols))), 1)
+	var autoIter *mandel.AutoIter
+	if scene.AutoIter {
+		autoIter = mandel.NewAutoIter()
+	}
+	newField := func(cols, rows int) mandel.Field {
+		return f64.NewField(cols, rows, mandel.Samp1x1)
+	}
+
+	gridRows := (numThumbs + gridCols - 1) / gridCols
+	tileWidth := thumbWidth + 2*Padding
+	tileHeight := thumbHeight + 2*LineHeight + 2*Padding
+	titleHeight := int(TitleSize) + 2*Padding
+	img := image.NewRGBA(image.Rect(0, 0, gridCols*tileWidth, titleHeight+gridRows*tileHeight))
+	gc := gg.NewContextForRGBA(img)
+	gc.SetFillColor(color.WhiteSmoke)
+	gc.Clear()
+
+	gc.SetFillColor(color.Black)
+	gc.SetStrokeColor(color.Black)
+	gc.SetFontFace(fonts.NewFace(fonts.GoBold, TitleSize))
+	gc.DrawStringAnchored(fmt.Sprintf("%s: %d views, %d images", pathName, path.NumViews(), numImages),
+		Padding, Padding, 0.0, 1.0)
+	gc.SetFontFace(fonts.NewFace(fonts.GoRegular, LabelSize))
+
+	field := f64.NewField(thumbWidth, thumbHeight, sampleMode)
+	for k := 0; k < numThumbs; k++ {
+		i := 0
+		if numThumbs > 1 {
+			i = int(math.Round(float64(k) * float64(numImages-1) / float64(numThumbs-1)))
+		}
+		t := 0.0
+		if numImages > 1 {
+			t = float64(i) / float64(numImages-1)
+		}
+		view := path.GetView(t)
+		x, y, w, it := view.Values()
+		if autoIter != nil {
+			it = autoIter.Estimate(view, cols, rows, newField)
+			view = f64.NewView()
+			view.SetValues(x, y, w, it)
+			view.SetTransform(path.GetView(t).Transform())
+		}
+		field.CalcMandelbrot(view)
+		palette := framePalette(path.GetPalette(t))
+		field.AddPalette(palette)
+		thumb := mandel.NewPaletteColorizer(palette).Colorize(field)
+
+		x0 := float64((k % gridCols) * tileWidth)
+		y0 := float64(titleHeight + (k/gridCols)*tileHeight)
+		gc.DrawImage(thumb, x0+Padding, y0+Padding)
+		gc.SetStrokeColor(color.DarkSlateGrey)
+		gc.SetStrokeWidth(1.0)
+		gc.DrawRectangle(x0+Padding, y0+Padding, float64(thumbWidth), float64(thumbHeight))
+		gc.Stroke()
+
+		yText := y0 + Padding + float64(thumbHeight) + LineHeight
+		gc.DrawStringAnchored(fmt.Sprintf("#%05d", i), x0+Padding, yText, 0.0, 0.0)
+		gc.DrawStringAnchored(fmt.Sprintf("t=%.3f  w=%.3g  it=%d", t, w, it),
+			x0+Padding+float64(thumbWidth), yText, 1.0, 0.0)
+		gc.DrawStringAnchored(fmt.Sprintf("%.10g %.10g", x, y),
+			x0+Padding, yText+LineHeight, 0.0, 0.0)
+		fmt.Printf("  [%2d]: image %05d, t=%.3f: %.10g %.10g %g %d\n", k, i, t, x, y, w, it)
+	}
+
+	fh, err := os.Create(outFile)
+	if err != nil {
+		log.Fatalf("couldn't create file: %v", err)
+	}
+	defer fh.Close()
+	if err = png.Encode(fh, img); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("storyboard written to %s\n", outFile)
+}
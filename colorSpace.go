@@ -0,0 +1,368 @@
+package mandel
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// Der Typ ColorSpace bezeichnet den Farbraum, in welchem zwischen den
+// Stuetzstellen einer [GradientPalette] interpoliert wird. Bei SpaceRGB
+// (Default) wird jeder Farbkanal einzeln und direkt auf den sRGB-Werten
+// interpoliert. Dies ist das bisherige Verhalten und erlaubt als einziger
+// Farbraum auch Stuetzstellen, bei denen nicht alle Kanaele definiert sind.
+// Alle anderen Farbraeume verwenden vollstaendige Farben als Stuetzstellen.
+type ColorSpace int
+
+const (
+	SpaceRGB ColorSpace = iota
+	SpaceLinearRGB
+	SpaceHSV
+	SpaceLab
+	SpaceOKLab
+	SpaceOKLCH
+)
+
+func (cs ColorSpace) String() string {
+	switch cs {
+	case SpaceRGB:
+		return "rgb"
+	case SpaceLinearRGB:
+		return "linear"
+	case SpaceHSV:
+		return "hsv"
+	case SpaceLab:
+		return "lab"
+	case SpaceOKLab:
+		return "oklab"
+	case SpaceOKLCH:
+		return "oklch"
+	default:
+		return "Unknown color space"
+	}
+}
+
+func (cs *ColorSpace) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "rgb":
+		*cs = SpaceRGB
+	case "linear", "linearrgb":
+		*cs = SpaceLinearRGB
+	case "hsv":
+		*cs = SpaceHSV
+	case "lab":
+		*cs = SpaceLab
+	case "oklab":
+		*cs = SpaceOKLab
+	case "oklch":
+		*cs = SpaceOKLCH
+	default:
+		return errors.New("Unknown color space: " + s)
+	}
+	return nil
+}
+
+// HueIndex liefert den Index der Farbton-Komponente im Farbraum, resp. -1,
+// falls der Farbraum keine Farbton-Komponente besitzt. Der Farbton wird
+// immer in Umdrehungen (d.h. in [0,1)) angegeben.
+func (cs ColorSpace) HueIndex() int {
+	switch cs {
+	case SpaceHSV:
+		return 0
+	case SpaceOKLCH:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// ChromaIndex liefert den Index der Komponente, welche die Buntheit angibt.
+// Ist diese Null, so ist der Farbton bedeutungslos. Fuer Farbraeume ohne
+// Farbton wird -1 retourniert.
+func (cs ColorSpace) ChromaIndex() int {
+	switch cs {
+	case SpaceHSV:
+		return 1
+	case SpaceOKLCH:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// FromRGB rechnet eine Farbe mit sRGB-Komponenten in [0,1] in den Farbraum
+// cs um.
+func (cs ColorSpace) FromRGB(c [3]float64) [3]float64 {
+	switch cs {
+	case SpaceLinearRGB:
+		return srgbToLinear(c)
+	case SpaceHSV:
+		return rgbToHSV(c)
+	case SpaceLab:
+		return xyzToLab(linearToXYZ(srgbToLinear(c)))
+	case SpaceOKLab:
+		return linearToOKLab(srgbToLinear(c))
+	case SpaceOKLCH:
+		return labToLCH(linearToOKLab(srgbToLinear(c)))
+	default:
+		return c
+	}
+}
+
+// ToRGB ist die Umkehrung von FromRGB. Das Resultat wird auf [0,1]
+// beschraenkt.
+func (cs ColorSpace) ToRGB(c [3]float64) [3]float64 {
+	switch cs {
+	case SpaceLinearRGB:
+		c = linearToSRGB(c)
+	case SpaceHSV:
+		c = hsvToRGB(c)
+	case SpaceLab:
+		c = linearToSRGB(xyzToLinear(labToXYZ(c)))
+	case SpaceOKLab:
+		c = linearToSRGB(okLabToLinear(c))
+	case SpaceOKLCH:
+		c = linearToSRGB(okLabToLinear(lchToLab(c)))
+	}
+	for i := range c {
+		c[i] = math.Max(0.0, math.Min(1.0, c[i]))
+	}
+	return c
+}
+
+// Der Typ HuePath legt fest, auf welchem Weg in Farbraeumen mit Farbton
+// (HSV, OKLCH) zwischen zwei Farbtoenen interpoliert wird.
+type HuePath int
+
+const (
+	HueShortest HuePath = iota
+	HueLongest
+)
+
+func (hp HuePath) String() string {
+	switch hp {
+	case HueShortest:
+		return "shortest"
+	case HueLongest:
+		return "longest"
+	default:
+		return "Unknown hue path"
+	}
+}
+
+func (hp *HuePath) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "shortest":
+		*hp = HueShortest
+	case "longest":
+		*hp = HueLongest
+	default:
+		return errors.New("Unknown hue path: " + s)
+	}
+	return nil
+}
+
+// Unwrap passt den Farbton h1 (in Umdrehungen) so an, dass bei einer
+// linearen Interpolation von h0 nach h1 der gewuenschte Weg um den
+// Farbkreis eingeschlagen wird.
+func (hp HuePath) Unwrap(h0, h1 float64) float64 {
+	d := h1 - h0
+	d -= math.Floor(d)
+	// d liegt jetzt in [0,1)
+	switch hp {
+	case HueLongest:
+		if d > 0.0 && d < 0.5 {
+			d -= 1.0
+		}
+	default:
+		if d > 0.5 {
+			d -= 1.0
+		}
+	}
+	return h0 + d
+}
+
+//-----------------------------------------------------------------------------
+//
+// Umrechnungen zwischen den einzelnen Farbraeumen. Als Weisspunkt wird
+// durchwegs D65 verwendet.
+
+func srgbToLinear(c [3]float64) (l [3]float64) {
+	for i, v := range c {
+		if v <= 0.04045 {
+			l[i] = v / 12.92
+		} else {
+			l[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	return
+}
+
+func linearToSRGB(l [3]float64) (c [3]float64) {
+	for i, v := range l {
+		if v <= 0.0031308 {
+			c[i] = 12.92 * v
+		} else {
+			c[i] = 1.055*math.Pow(v, 1.0/2.4) - 0.055
+		}
+	}
+	return
+}
+
+func linearToXYZ(c [3]float64) [3]float64 {
+	r, g, b := c[0], c[1], c[2]
+	return [3]float64{
+		0.4124564*r + 0.3575761*g + 0.1804375*b,
+		0.2126729*r + 0.7151522*g + 0.0721750*b,
+		0.0193339*r + 0.1191920*g + 0.9503041*b,
+	}
+}
+
+// xyzToLinear ist die Umkehrung von linearToXYZ. Die Koeffizienten sind die
+// der exakt invertierten Matrix, damit die Umrechnung verlustfrei hin und
+// zurueck moeglich ist.
+func xyzToLinear(c [3]float64) [3]float64 {
+	x, y, z := c[0], c[1], c[2]
+	return [3]float64{
+		3.2404548360*x - 1.5371388501*y - 0.4985315469*z,
+		-0.9692663899*x + 1.8760109288*y + 0.0415560823*z,
+		0.0556434196*x - 0.2040258543*y + 1.0572251625*z,
+	}
+}
+
+const (
+	labEps   = 216.0 / 24389.0
+	labKappa = 24389.0 / 27.0
+)
+
+var (
+	whiteD65 = [3]float64{0.95047, 1.0, 1.08883}
+)
+
+func xyzToLab(c [3]float64) [3]float64 {
+	var f [3]float64
+	for i := range c {
+		t := c[i] / whiteD65[i]
+		if t > labEps {
+			f[i] = math.Cbrt(t)
+		} else {
+			f[i] = (labKappa*t + 16.0) / 116.0
+		}
+	}
+	return [3]float64{116.0*f[1] - 16.0, 500.0 * (f[0] - f[1]), 200.0 * (f[1] - f[2])}
+}
+
+func labToXYZ(c [3]float64) [3]float64 {
+	var r [3]float64
+	fy := (c[0] + 16.0) / 116.0
+	fx := fy + c[1]/500.0
+	fz := fy - c[2]/200.0
+	if fx*fx*fx > labEps {
+		r[0] = fx * fx * fx
+	} else {
+		r[0] = (116.0*fx - 16.0) / labKappa
+	}
+	if c[0] > labKappa*labEps {
+		r[1] = fy * fy * fy
+	} else {
+		r[1] = c[0] / labKappa
+	}
+	if fz*fz*fz > labEps {
+		r[2] = fz * fz * fz
+	} else {
+		r[2] = (116.0*fz - 16.0) / labKappa
+	}
+	for i := range r {
+		r[i] *= whiteD65[i]
+	}
+	return r
+}
+
+func linearToOKLab(c [3]float64) [3]float64 {
+	r, g, b := c[0], c[1], c[2]
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+	return [3]float64{
+		0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func okLabToLinear(c [3]float64) [3]float64 {
+	L, a, b := c[0], c[1], c[2]
+	l := L + 0.3963377774*a + 0.2158037573*b
+	m := L - 0.1055613458*a - 0.0638541728*b
+	s := L - 0.0894841775*a - 1.2914855480*b
+	l, m, s = l*l*l, m*m*m, s*s*s
+	return [3]float64{
+		4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	}
+}
+
+func labToLCH(c [3]float64) [3]float64 {
+	h := math.Atan2(c[2], c[1]) / (2.0 * math.Pi)
+	if h < 0.0 {
+		h += 1.0
+	}
+	return [3]float64{c[0], math.Hypot(c[1], c[2]), h}
+}
+
+func lchToLab(c [3]float64) [3]float64 {
+	h := 2.0 * math.Pi * c[2]
+	return [3]float64{c[0], c[1] * math.Cos(h), c[1] * math.Sin(h)}
+}
+
+func rgbToHSV(c [3]float64) [3]float64 {
+	r, g, b := c[0], c[1], c[2]
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	d := max - min
+	h, s := 0.0, 0.0
+	if max > 0.0 {
+		s = d / max
+	}
+	if d > 0.0 {
+		switch max {
+		case r:
+			h = (g - b) / d
+			if h < 0.0 {
+				h += 6.0
+			}
+		case g:
+			h = (b-r)/d + 2.0
+		default:
+			h = (r-g)/d + 4.0
+		}
+		h /= 6.0
+	}
+	return [3]float64{h, s, max}
+}
+
+func hsvToRGB(c [3]float64) [3]float64 {
+	h, s, v := c[0], c[1], c[2]
+	h -= math.Floor(h)
+	h *= 6.0
+	i := math.Floor(h)
+	f := h - i
+	p := v * (1.0 - s)
+	q := v * (1.0 - s*f)
+	t := v * (1.0 - s*(1.0-f))
+	switch int(i) {
+	case 0:
+		return [3]float64{v, t, p}
+	case 1:
+		return [3]float64{q, v, p}
+	case 2:
+		return [3]float64{p, v, t}
+	case 3:
+		return [3]float64{p, q, v}
+	case 4:
+		return [3]float64{t, p, v}
+	default:
+		return [3]float64{v, p, q}
+	}
+}
@@ -0,0 +1,132 @@
+package mandel
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestColorSpaceRoundTrip(t *testing.T) {
+	const steps = 8
+
+	spaces := []ColorSpace{SpaceRGB, SpaceLinearRGB, SpaceHSV, SpaceLab,
+		SpaceOKLab, SpaceOKLCH}
+	for _, cs := range spaces {
+		for r := 0; r <= steps; r++ {
+			for g := 0; g <= steps; g++ {
+				for b := 0; b <= steps; b++ {
+					c := [3]float64{float64(r) / steps, float64(g) / steps,
+						float64(b) / steps}
+					d := cs.ToRGB(cs.FromRGB(c))
+					for i := range c {
+						if math.Abs(c[i]-d[i]) > 1.0e-6 {
+							t.Errorf("%v: %v -> %v -> %v", cs, c, cs.FromRGB(c), d)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestColorSpaceValues(t *testing.T) {
+	white := [3]float64{1.0, 1.0, 1.0}
+	red := [3]float64{1.0, 0.0, 0.0}
+	for _, tc := range []struct {
+		cs       ColorSpace
+		rgb, val [3]float64
+	}{
+		{SpaceLab, white, [3]float64{100.0, 0.0, 0.0}},
+		{SpaceLab, red, [3]float64{53.2408, 80.0925, 67.2032}},
+		{SpaceOKLab, white, [3]float64{1.0, 0.0, 0.0}},
+		{SpaceOKLab, red, [3]float64{0.6279554, 0.2248631, 0.1258463}},
+		{SpaceOKLCH, red, [3]float64{0.6279554, 0.2576833, 29.2338851 / 360.0}},
+		{SpaceHSV, red, [3]float64{0.0, 1.0, 1.0}},
+		{SpaceHSV, [3]float64{0.0, 0.5, 0.5}, [3]float64{0.5, 1.0, 0.5}},
+	} {
+		val := tc.cs.FromRGB(tc.rgb)
+		for i := range val {
+			if math.Abs(val[i]-tc.val[i]) > 1.0e-3 {
+				t.Errorf("%v: %v -> %v, want %v", tc.cs, tc.rgb, val, tc.val)
+				break
+			}
+		}
+	}
+}
+
+func TestHuePathUnwrap(t *testing.T) {
+	for _, tc := range []struct {
+		hp             HuePath
+		h0, h1, result float64
+	}{
+		{HueShortest, 350.0, 10.0, 370.0},
+		{HueLongest, 350.0, 10.0, 10.0},
+		{HueShortest, 10.0, 350.0, -10.0},
+		{HueLongest, 10.0, 350.0, 350.0},
+		{HueShortest, 0.0, 90.0, 90.0},
+		{HueLongest, 0.0, 90.0, -270.0},
+	} {
+		h := 360.0 * tc.hp.Unwrap(tc.h0/360.0, tc.h1/360.0)
+		if math.Abs(h-tc.result) > 1.0e-9 {
+			t.Errorf("%v: %v -> %v gives %v, want %v", tc.hp, tc.h0, tc.h1,
+				h, tc.result)
+		}
+	}
+}
+
+// Der Verlauf von 350 Grad nach 10 Grad (beides Rot) fuehrt auf dem kurzen
+// Weg ueber Rot, auf dem langen Weg ueber Cyan.
+func TestHuePathPalette(t *testing.T) {
+	const stops = "interp = linear\n0.0: #ff002b\n1.0: #ff2b00\n"
+	for hue, want := range map[string][3]float64{
+		"shortest": {1.0, 0.0, 0.0},
+		"longest":  {0.0, 1.0, 1.0},
+	} {
+		for _, space := range []string{"hsv", "oklch"} {
+			ini := "[H]\nspace = " + space + "\nhue = " + hue + "\n" + stops
+			p, err := ReadPalette(strings.NewReader(ini), "H")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := tableColor(colorTable(p), 0.5)
+			got := [3]float64{c.R, c.G, c.B}
+			if space == "hsv" {
+				for i := range got {
+					if math.Abs(got[i]-want[i]) > 0.02 {
+						t.Errorf("%s, %s: color at 0.5 is %v, want %v", space, hue, got, want)
+						break
+					}
+				}
+				continue
+			}
+			// In OKLCH liegt Cyan nicht genau gegenueber von Rot, daher wird
+			// nur geprueft, ob Rot, resp. Blau-Gruen ueberwiegt.
+			if isRed := got[0] > got[1] && got[0] > got[2]; isRed != (hue == "shortest") {
+				t.Errorf("%s, %s: color at 0.5 is %v", space, hue, got)
+			}
+		}
+	}
+}
+
+// Die mitgelieferten Paletten mit Farbraum muessen an ihren Stuetzstellen
+// genau die angegebenen Farben haben.
+func TestColorSpacePalettes(t *testing.T) {
+	for _, name := range []string{"Sunset", "IceAndFire", "Glacier", "HueWheel"} {
+		p := readTestPalette(t, name)
+		gp, ok := p.(*GradientPalette)
+		if !ok {
+			t.Fatalf("%s isn't a gradient palette", name)
+		}
+		if gp.ColorSpace() == SpaceRGB {
+			t.Errorf("%s: expected a color space other than rgb", name)
+		}
+		for _, cs := range gp.ColorStops() {
+			c := tableColor(colorTable(p), cs.Pos)
+			if math.Abs(c.R-cs.R)+math.Abs(c.G-cs.G)+math.Abs(c.B-cs.B) > 0.02 {
+				t.Errorf("%s: color at %v is %v, want %v", name, cs.Pos, c,
+					[3]float64{cs.R, cs.G, cs.B})
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package mandel
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// Ein Colorizer erstellt aus den berechneten Werten eines Feldes ein Bild.
+// Damit ist die (teure) Berechnung der Mandelbrot-Menge von der Einfaerbung
+// getrennt: ein einmal berechnetes Feld kann mit beliebig vielen Colorizern
+// dargestellt werden, ohne dass das Fraktal neu berechnet werden muss.
+// Colorizer koennen ausserdem ineinander verschachtelt werden (siehe
+// [LightingColorizer] und [LayerColorizer]).
+type Colorizer interface {
+	Colorize(f Field) image.Image
+}
+
+// adjLength passt die Laenge der Palette pal der maximalen Anzahl Iterationen
+// des Feldes an, sofern dies bei der Palette so hinterlegt ist. Dies ersetzt
+// den Aufruf von [Field.AdjPalette] vor der Ausgabe eines Bildes.
+func adjLength(pal Palette, f Field) {
+	if pal.IsLenMaxIter() {
+		pal.SetLength(f.MaxIterations())
+	}
+}
+
+// newImage erstellt ein leeres Bild in der Groesse des Feldes f. Liefert
+// [Field.ColorModel] das Modell color.RGBA64Model, so wird ein Bild mit
+// 16 Bit pro Farbkanal erstellt, sonst eines mit 8 Bit.
+func newImage(f Field) draw.Image {
+	if f.ColorModel() == color.RGBA64Model {
+		return image.NewRGBA64(f.Bounds())
+	}
+	return image.NewRGBA(f.Bounds())
+}
+
+// setColor setzt das Pixel (x,y) des mit [newImage] erstellten Bildes img
+// auf die Farbe c.
+func setColor(img draw.Image, x, y int, c ColorF) {
+	switch img := img.(type) {
+	case *image.RGBA64:
+		img.SetRGBA64(x, y, c.RGBA64())
+	case *image.RGBA:
+		img.SetRGBA(x, y, c.RGBA8())
+	default:
+		img.Set(x, y, c)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// PaletteColorizer ist der einfachste Colorizer: jeder Wert des Feldes wird
+// direkt ueber die Palette in eine Farbe umgesetzt. Das Resultat entspricht
+// dem, was bisher ueber [Field.At] erreicht wurde.
+type PaletteColorizer struct {
+	pal Palette
+}
+
+// Erstellt einen neuen Colorizer, welcher die Palette pal verwendet.
+func NewPaletteColorizer(pal Palette) *PaletteColorizer {
+	return &PaletteColorizer{pal: pal}
+}
+
+func (c *PaletteColorizer) Colorize(f Field) image.Image {
+	adjLength(c.pal, f)
+	img := newImage(f)
+	b := f.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			setColor(img, x, y, c.pal.GetColorF(f.Value(x, y)))
+		}
+	}
+	return img
+}
+
+//-----------------------------------------------------------------------------
+
+// HistogramColorizer verteilt die Farben der Palette gemaess der Haeufigkeit
+// der Iterationswerte im Feld (Histogramm-Ausgleich). Damit werden die
+// Farben der Palette gleichmaessig ueber das Bild verteilt, unabhaengig
+// davon, wie gross die maximale Anzahl Iterationen ist. Punkte, welche zur
+// Mandelbrot-Menge gehoeren, werden dabei nicht beruecksichtigt.
+type HistogramColorizer struct {
+	pal Palette
+}
+
+// Erstellt einen neuen Colorizer mit Histogramm-Ausgleich ueber der Palette
+// pal.
+func NewHistogramColorizer(pal Palette) *HistogramColorizer {
+	return &HistogramColorizer{pal: pal}
+}
+
+func (c *HistogramColorizer) Colorize(f Field) image.Image {
+	var values []float64
+
+	adjLength(c.pal, f)
+	b := f.Bounds()
+	values = make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if v := f.Value(x, y); v >= 0.0 {
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Float64s(values)
+	n := float64(len(values))
+	img := newImage(f)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := f.Value(x, y)
+			if v >= 0.0 {
+				cdf := float64(sort.SearchFloat64s(values, v)) / n
+				v = cdf * float64(c.pal.Length())
+			}
+			setColor(img, x, y, c.pal.GetColorF(v))
+		}
+	}
+	return img
+}
+
+//-----------------------------------------------------------------------------
+
+// LightingColorizer interpretiert die (geglaetteten) Iterationswerte des
+// Feldes als Hoehenrelief und beleuchtet dieses mit einer entfernten
+// Lichtquelle. Die Farben des Basis-Colorizers werden mit der so berechneten
+// Helligkeit multipliziert. Ist kein Basis-Colorizer angegeben, so entsteht
+// ein Graustufenbild, welches sich als Ebene in einem [LayerColorizer]
+// verwenden laesst.
+type LightingColorizer struct {
+	base                      Colorizer
+	azimuth, elevation, depth float64
+	ambient                   float64
+}
+
+// Erstellt einen neuen Beleuchtungs-Colorizer ueber dem Colorizer base (darf
+// auch nil sein).
+// azimuth und elevation geben die Richtung der Lichtquelle in Grad an,
+// depth die Ueberhoehung des Reliefs und ambient den Anteil an
+// ungerichtetem Licht (in [0,1]).
+func NewLightingColorizer(base Colorizer, azimuth, elevation, depth,
+	ambient float64) *LightingColorizer {
+	return &LightingColorizer{base, azimuth, elevation, depth, ambient}
+}
+
+func (c *LightingColorizer) Colorize(f Field) image.Image {
+	var src image.Image
+
+	b := f.Bounds()
+	if c.base != nil {
+		src = c.base.Colorize(f)
+	} else {
+		src = image.NewUniform(color.White)
+	}
+	img := newImage(f)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			col := ColorFOf(src.At(x, y))
+			s := c.shade(f, x, y)
+			col.R *= s
+			col.G *= s
+			col.B *= s
+			setColor(img, x, y, col)
+		}
+	}
+	return img
+}
+
+// height liefert die Hoehe des Reliefs an der Stelle (x,y). Damit auch bei
+// hohen Iterationszahlen brauchbare Steigungen entstehen, wird logarithmisch
+// skaliert. Fuer Punkte ausserhalb des Feldes oder innerhalb der Menge wird
+// ok=false retourniert.
+func height(f Field, x, y int) (h float64, ok bool) {
+	if !(image.Point{x, y}).In(f.Bounds()) {
+		return 0.0, false
+	}
+	v := f.Value(x, y)
+	if v < 0.0 {
+		return 0.0, false
+	}
+	return math.Log1p(v), true
+}
+
+// shade berechnet die Helligkeit (in [0,1]) an der Stelle (x,y).
+func (c *LightingColorizer) shade(f Field, x, y int) float64 {
+	h, ok := height(f, x, y)
+	if !ok {
+		return 1.0
+	}
+	hx0, ok0 := height(f, x-1, y)
+	hx1, ok1 := height(f, x+1, y)
+	hy0, ok2 := height(f, x, y-1)
+	hy1, ok3 := height(f, x, y+1)
+	if !ok0 {
+		hx0 = h
+	}
+	if !ok1 {
+		hx1 = h
+	}
+	if !ok2 {
+		hy0 = h
+	}
+	if !ok3 {
+		hy1 = h
+	}
+	nx := -c.depth * (hx1 - hx0) / 2.0
+	ny := c.depth * (hy1 - hy0) / 2.0
+	nz := 1.0
+	nl := math.Sqrt(nx*nx + ny*ny + nz*nz)
+
+	az := c.azimuth * math.Pi / 180.0
+	el := c.elevation * math.Pi / 180.0
+	lx := math.Cos(el) * math.Cos(az)
+	ly := math.Cos(el) * math.Sin(az)
+	lz := math.Sin(el)
+
+	d := (nx*lx + ny*ly + nz*lz) / nl
+	if d < 0.0 {
+		d = 0.0
+	}
+	return c.ambient + (1.0-c.ambient)*d
+}
+
+//-----------------------------------------------------------------------------
+
+// DistanceColorizer faerbt das Feld aufgrund einer Schaetzung der Distanz
+// (in Pixeln) zur Mandelbrot-Menge ein. Die Schaetzung basiert auf dem
+// Gradienten der geglaetteten Iterationswerte: ist nu der Iterationswert,
+// dann ist das Potential G = 2^(-nu) und die Distanz ungefaehr G/|grad G|.
+// Auf diese Weise werden die Filamente der Menge betont, ohne dass bei der
+// Berechnung des Feldes die Ableitung mitgefuehrt werden muss.
+type DistanceColorizer struct {
+	pal   Palette
+	scale float64
+}
+
+// Erstellt einen neuen Distanz-Colorizer mit der Palette pal. scale gibt an,
+// bei welcher (logarithmischen) Distanz die Palette einmal durchlaufen ist:
+// bei scale=8.0 entspricht eine Distanz von 255 Pixeln der Palettenlaenge.
+func NewDistanceColorizer(pal Palette, scale float64) *DistanceColorizer {
+	return &DistanceColorizer{pal, scale}
+}
+
+func (c *DistanceColorizer) Colorize(f Field) image.Image {
+	adjLength(c.pal, f)
+	b := f.Bounds()
+	img := newImage(f)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			setColor(img, x, y, c.pal.GetColorF(c.distance(f, x, y)))
+		}
+	}
+	return img
+}
+
+// distance liefert den Index in die Palette fuer den Punkt (x,y), resp. -1.0
+// fuer Punkte der Menge.
+func (c *DistanceColorizer) distance(f Field, x, y int) float64 {
+	v := f.Value(x, y)
+	if v < 0.0 {
+		return -1.0
+	}
+	grad := 0.0
+	n := 0
+	for _, d := range []image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		p := image.Point{x, y}.Add(d)
+		if !p.In(f.Bounds()) {
+			continue
+		}
+		w := f.Value(p.X, p.Y)
+		if w < 0.0 {
+			// Der Nachbar gehoert zur Menge: Distanz ist hoechstens 1 Pixel.
+			return 0.0
+		}
+		grad += (w - v) * (w - v)
+		n++
+	}
+	if n == 0 || grad == 0.0 {
+		return float64(c.pal.Length()) - 1.0
+	}
+	grad = math.Sqrt(2.0*grad/float64(n)) * math.Ln2
+	dist := 1.0 / grad
+	return math.Min(math.Log2(1.0+dist)/c.scale, 1.0) * (float64(c.pal.Length()) - 1.0)
+}
@@ -0,0 +1,325 @@
+package mandel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// layerFileName ist der Name der Datei, welche die Definitionen der
+	// Ebenen-Stapel enthaelt. Sie wird in denselben Verzeichnissen wie die
+	// Datei mit den Paletten gesucht (siehe [ConfDirs]).
+	layerFileName = "layer.ini"
+)
+
+var (
+	// regxLayer ist der regulaere Ausdruck fuer eine Ebene innerhalb eines
+	// Abschnittes in der Datei [layerFileName]: Typ der Ebene, Argumente,
+	// Deckkraft und Mischmodus.
+	regxLayer = regexp.MustCompile(`^ *([[:alpha:]]+) +(.+?) +([0-9\.]+) +([[:alpha:]]+) *$`)
+)
+
+// Der Typ BlendMode bestimmt, wie die Farben einer Ebene mit den Farben der
+// darunter liegenden Ebenen kombiniert werden. Die Modi entsprechen den
+// gleichnamigen Modi aus Ultra Fractal (resp. aus den gaengigen Bildbe-
+// arbeitungsprogrammen).
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendSoftLight
+)
+
+func (bm BlendMode) String() string {
+	switch bm {
+	case BlendNormal:
+		return "normal"
+	case BlendMultiply:
+		return "multiply"
+	case BlendScreen:
+		return "screen"
+	case BlendOverlay:
+		return "overlay"
+	case BlendSoftLight:
+		return "softlight"
+	default:
+		return "Unknown blend mode"
+	}
+}
+
+func (bm *BlendMode) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "normal":
+		*bm = BlendNormal
+	case "multiply":
+		*bm = BlendMultiply
+	case "screen":
+		*bm = BlendScreen
+	case "overlay":
+		*bm = BlendOverlay
+	case "softlight":
+		*bm = BlendSoftLight
+	default:
+		return errors.New("Unknown blend mode: " + s)
+	}
+	return nil
+}
+
+// Blend kombiniert einen Farbkanal a der unteren Ebene mit dem entsprechenden
+// Kanal b der oberen Ebene. Beide Werte (und das Resultat) liegen in [0,1].
+func (bm BlendMode) Blend(a, b float64) float64 {
+	switch bm {
+	case BlendMultiply:
+		return a * b
+	case BlendScreen:
+		return 1.0 - (1.0-a)*(1.0-b)
+	case BlendOverlay:
+		if a < 0.5 {
+			return 2.0 * a * b
+		}
+		return 1.0 - 2.0*(1.0-a)*(1.0-b)
+	case BlendSoftLight:
+		if b <= 0.5 {
+			return a - (1.0-2.0*b)*a*(1.0-a)
+		}
+		var d float64
+		if a <= 0.25 {
+			d = ((16.0*a-12.0)*a + 4.0) * a
+		} else {
+			d = math.Sqrt(a)
+		}
+		return a + (2.0*b-1.0)*(d-a)
+	default:
+		return b
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Eine Layer (Ebene) besteht aus einem Colorizer, der Deckkraft (in [0,1])
+// und dem Modus, mit welchem sie mit den darunterliegenden Ebenen gemischt
+// wird.
+type Layer struct {
+	Colorizer Colorizer
+	Opacity   float64
+	Mode      BlendMode
+}
+
+// LayerColorizer stapelt mehrere Ebenen uebereinander. Die erste Ebene liegt
+// zuunterst und wird auf einen schwarzen Hintergrund gelegt.
+type LayerColorizer struct {
+	layers []Layer
+}
+
+// Erstellt einen neuen Ebenen-Stapel mit den Ebenen in layers.
+func NewLayerColorizer(layers ...Layer) *LayerColorizer {
+	c := &LayerColorizer{}
+	c.layers = append(c.layers, layers...)
+	return c
+}
+
+// Fuegt dem Stapel zuoberst eine neue Ebene hinzu.
+func (c *LayerColorizer) AddLayer(l Layer) {
+	c.layers = append(c.layers, l)
+}
+
+// Retourniert die Anzahl Ebenen im Stapel.
+func (c *LayerColorizer) NumLayers() int {
+	return len(c.layers)
+}
+
+// Colorize faerbt das Feld f mit allen Ebenen ein. Die Deckkraft (Alpha)
+// des Resultats wird von der untersten Ebene uebernommen; die Ebenen darueber
+// veraendern nur die Farbe.
+func (c *LayerColorizer) Colorize(f Field) image.Image {
+	b := f.Bounds()
+	acc := make([][3]float64, b.Dx()*b.Dy())
+	alpha := make([]float64, b.Dx()*b.Dy())
+	for k, l := range c.layers {
+		src := l.Colorizer.Colorize(f)
+		t0 := 1.0 - l.Opacity
+		i := 0
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				col := ColorFOf(src.At(x, y))
+				if k == 0 {
+					alpha[i] = col.A
+				}
+				top := [3]float64{col.R, col.G, col.B}
+				for j := range 3 {
+					acc[i][j] = t0*acc[i][j] + l.Opacity*l.Mode.Blend(acc[i][j], top[j])
+				}
+				i++
+			}
+		}
+	}
+	img := newImage(f)
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			setColor(img, x, y, clampColor(acc[i], alpha[i]))
+			i++
+		}
+	}
+	return img
+}
+
+//-----------------------------------------------------------------------------
+
+// Retourniert einen Slice mit den Namen aller Ebenen-Stapel, welche in den
+// Dateien [layerFileName] im Suchpfad (siehe [ConfFiles]) definiert sind.
+func LayerColorizerNames() ([]string, error) {
+	var names []string
+
+	names = make([]string, 0)
+	for _, fileName := range ConfFiles(layerFileName) {
+		fd, err := os.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(fd)
+		for scanner.Scan() {
+			matches := regxSection.FindStringSubmatch(scanner.Text())
+			if matches != nil && !slices.Contains(names, matches[1]) {
+				names = append(names, matches[1])
+			}
+		}
+		fd.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// Erstellt einen Ebenen-Stapel aufgrund des Abschnittes name in der Datei
+// [layerFileName] (siehe [OpenConfSection]). Jede Zeile im Abschnitt
+// definiert eine Ebene (von unten nach oben) in der Form
+//
+//	typ  argumente  deckkraft  modus
+//
+// Die Deckkraft muss in [0,1] liegen. Folgende Typen (mit ihren Argumenten) werden unterstuetzt:
+//
+//	palette    <palette>
+//	histogram  <palette>
+//	distance   <palette> <scale>
+//	lighting   <azimuth> <elevation> <depth> <ambient>
+func ReadLayerColorizer(name string) (*LayerColorizer, error) {
+	var fd *os.File
+	var scanner *bufio.Scanner
+	var line string
+	var matches []string
+	var err error
+	var inSection bool
+	var c *LayerColorizer
+
+	fd, err = OpenConfSection(layerFileName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	c = NewLayerColorizer()
+	inSection = false
+	scanner = bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line = scanner.Text()
+		if regxComm.MatchString(line) {
+			continue
+		}
+		if inSection {
+			if regxSection.MatchString(line) {
+				break
+			}
+			l, err := parseLayer(line)
+			if err != nil {
+				return nil, fmt.Errorf("error on line: '%s':\n%v", line, err)
+			}
+			c.AddLayer(l)
+		} else {
+			if matches = regxSection.FindStringSubmatch(line); matches != nil {
+				if matches[1] == name {
+					inSection = true
+				}
+			}
+		}
+	}
+	if !inSection {
+		return nil, fmt.Errorf("no layer stack '%s' found!", name)
+	}
+	if c.NumLayers() == 0 {
+		return nil, fmt.Errorf("layer stack '%s' has no layers", name)
+	}
+	return c, nil
+}
+
+// parseLayer wertet eine einzelne Zeile eines Ebenen-Stapels aus.
+func parseLayer(line string) (l Layer, err error) {
+	var args []string
+	var v [4]float64
+
+	matches := regxLayer.FindStringSubmatch(line)
+	if matches == nil {
+		return l, errors.New("invalid layer definition")
+	}
+	if l.Opacity, err = strconv.ParseFloat(matches[3], 64); err != nil {
+		return l, err
+	}
+	if l.Opacity < 0.0 || l.Opacity > 1.0 {
+		return l, fmt.Errorf("opacity %g is outside of [0,1]", l.Opacity)
+	}
+	if err = l.Mode.Set(matches[4]); err != nil {
+		return l, err
+	}
+	args = strings.Fields(matches[2])
+	switch matches[1] {
+	case "palette", "histogram":
+		if len(args) != 1 {
+			return l, errors.New("expected a palette name")
+		}
+		pal, err := NewPalette(args[0])
+		if err != nil {
+			return l, err
+		}
+		if matches[1] == "palette" {
+			l.Colorizer = NewPaletteColorizer(pal)
+		} else {
+			l.Colorizer = NewHistogramColorizer(pal)
+		}
+	case "distance":
+		if len(args) != 2 {
+			return l, errors.New("expected a palette name and a scale")
+		}
+		pal, err := NewPalette(args[0])
+		if err != nil {
+			return l, err
+		}
+		if v[0], err = strconv.ParseFloat(args[1], 64); err != nil {
+			return l, err
+		}
+		l.Colorizer = NewDistanceColorizer(pal, v[0])
+	case "lighting":
+		if len(args) != 4 {
+			return l, errors.New("expected azimuth, elevation, depth and ambient")
+		}
+		for i, arg := range args {
+			if v[i], err = strconv.ParseFloat(arg, 64); err != nil {
+				return l, err
+			}
+		}
+		l.Colorizer = NewLightingColorizer(nil, v[0], v[1], v[2], v[3])
+	default:
+		return l, fmt.Errorf("unknown layer type '%s'", matches[1])
+	}
+	return l, nil
+}
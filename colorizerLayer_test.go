@@ -0,0 +1,92 @@
+package mandel
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// uniformColorizer faerbt jedes Feld einheitlich mit der Farbe c ein.
+type uniformColorizer struct {
+	c ColorF
+}
+
+func (c uniformColorizer) Colorize(f Field) image.Image {
+	return image.NewUniform(c.c)
+}
+
+func TestBlendModes(t *testing.T) {
+	for _, tc := range []struct {
+		mode       BlendMode
+		a, b, want float64
+	}{
+		{BlendNormal, 0.3, 0.7, 0.7},
+		{BlendMultiply, 0.5, 0.5, 0.25},
+		{BlendMultiply, 1.0, 0.4, 0.4},
+		{BlendScreen, 0.5, 0.5, 0.75},
+		{BlendScreen, 0.0, 0.4, 0.4},
+		{BlendOverlay, 0.25, 0.5, 0.25},
+		{BlendOverlay, 0.75, 0.5, 0.75},
+		{BlendOverlay, 0.5, 1.0, 1.0},
+		{BlendOverlay, 0.25, 0.0, 0.0},
+		{BlendSoftLight, 0.3, 0.5, 0.3},
+		{BlendSoftLight, 0.5, 0.0, 0.25},
+		{BlendSoftLight, 0.25, 1.0, 0.5},
+		{BlendSoftLight, 0.64, 1.0, 0.8},
+	} {
+		if v := tc.mode.Blend(tc.a, tc.b); math.Abs(v-tc.want) > 1.0e-12 {
+			t.Errorf("%v(%v, %v) = %v, want %v", tc.mode, tc.a, tc.b, v, tc.want)
+		}
+	}
+}
+
+func TestLayerOpacity(t *testing.T) {
+	base := Layer{uniformColorizer{ColorF{0.5, 0.5, 0.5, 0.8}}, 1.0, BlendNormal}
+	top := uniformColorizer{ColorF{0.5, 0.2, 1.0, 1.0}}
+	for _, tc := range []struct {
+		opacity float64
+		mode    BlendMode
+		want    [3]float64
+	}{
+		{0.0, BlendMultiply, [3]float64{0.5, 0.5, 0.5}},
+		{1.0, BlendMultiply, [3]float64{0.25, 0.1, 0.5}},
+		{0.5, BlendMultiply, [3]float64{0.375, 0.3, 0.5}},
+		{0.0, BlendScreen, [3]float64{0.5, 0.5, 0.5}},
+		{1.0, BlendScreen, [3]float64{0.75, 0.6, 1.0}},
+		{0.5, BlendNormal, [3]float64{0.5, 0.35, 0.75}},
+	} {
+		lc := NewLayerColorizer(base, Layer{top, tc.opacity, tc.mode})
+		c := ColorFOf(lc.Colorize(newTestField()).At(1, 1))
+		got := [3]float64{c.R, c.G, c.B}
+		for j := range got {
+			if math.Abs(got[j]-tc.want[j]) > 1.0e-4 {
+				t.Errorf("%v, opacity %v: color is %v, want %v", tc.mode,
+					tc.opacity, got, tc.want)
+				break
+			}
+		}
+		// Die Deckkraft des Resultats stammt von der untersten Ebene.
+		if math.Abs(c.A-0.8) > 1.0e-4 {
+			t.Errorf("%v, opacity %v: alpha is %v, want 0.8", tc.mode, tc.opacity, c.A)
+		}
+	}
+}
+
+func TestParseLayer(t *testing.T) {
+	l, err := parseLayer("lighting 45 45 8 0.3  0.5 softlight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Opacity != 0.5 || l.Mode != BlendSoftLight {
+		t.Errorf("got opacity %v, mode %v", l.Opacity, l.Mode)
+	}
+	for _, line := range []string{
+		"lighting 45 45 8 0.3  1.5 normal",
+		"lighting 45 45 8 0.3  0.5 dodge",
+		"lighting 45 45 8  0.5 normal",
+	} {
+		if _, err := parseLayer(line); err == nil {
+			t.Errorf("'%s': no error", line)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package mandel
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testField ist ein Feld mit vorgegebenen Werten, mit welchem die Colorizer
+// ohne Berechnung der Mandelbrot-Menge getestet werden koennen.
+type testField struct {
+	f [][]float64
+}
+
+func (f *testField) CalcMandelbrot(v View)       {}
+func (f *testField) AddPalette(pal Palette)      {}
+func (f *testField) AdjPalette()                 {}
+func (f *testField) Write(fileName string) error { return nil }
+func (f *testField) Read(fileName string) error  { return nil }
+func (f *testField) SetColorModel(m color.Model) {}
+func (f *testField) ColorModel() color.Model     { return color.RGBA64Model }
+func (f *testField) At(x, y int) color.Color     { return color.Black }
+func (f *testField) Value(x, y int) float64      { return f.f[y][x] }
+func (f *testField) MaxIterations() int          { return 1000 }
+func (f *testField) Bounds() image.Rectangle {
+	return image.Rect(0, 0, len(f.f[0]), len(f.f))
+}
+
+// newTestField erstellt ein kleines Feld mit festen Werten. Der Wert -1.0
+// steht fuer Punkte der Mandelbrot-Menge.
+func newTestField() *testField {
+	return &testField{[][]float64{
+		{1.0, 2.0, 3.5, 5.0, 8.0},
+		{2.0, 4.0, 9.0, 20.0, 13.0},
+		{3.0, 11.0, -1.0, -1.0, 21.0},
+		{5.0, 17.0, -1.0, 250.0, 34.0},
+		{8.0, 30.0, 60.0, 900.0, 55.0},
+	}}
+}
+
+// newGrayPalette liefert eine Palette, welche linear von Schwarz nach Weiss
+// verlaeuft und fuer das Innere der Menge Rot verwendet.
+func newGrayPalette(t *testing.T) Palette {
+	const ini = "[G]\ninterp = linear\ninside = #ff0000\n0.0: #000000\n1.0: #ffffff\n"
+	p, err := ReadPalette(strings.NewReader(ini), "G")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.LenIsNotMaxIter()
+	p.SetLength(100)
+	return p
+}
+
+func TestHistogramColorizer(t *testing.T) {
+	f := newTestField()
+	img := NewHistogramColorizer(newGrayPalette(t)).Colorize(f)
+
+	// Bei einer linearen Grau-Palette entspricht die Helligkeit genau dem
+	// Wert der Verteilungsfunktion.
+	type sample struct{ v, cdf float64 }
+	var samples []sample
+	b := f.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if v := f.Value(x, y); v >= 0.0 {
+				samples = append(samples, sample{v, ColorFOf(img.At(x, y)).R})
+			}
+		}
+	}
+	for _, s := range samples {
+		if s.cdf < 0.0 || s.cdf > 1.0 {
+			t.Errorf("value %v: cdf %v outside of [0,1]", s.v, s.cdf)
+		}
+		for _, o := range samples {
+			if o.v > s.v && o.cdf <= s.cdf {
+				t.Errorf("cdf isn't increasing: %v -> %v, %v -> %v", s.v, s.cdf, o.v, o.cdf)
+			}
+			if o.v == s.v && o.cdf != s.cdf {
+				t.Errorf("value %v has different colors: %v, %v", s.v, s.cdf, o.cdf)
+			}
+		}
+	}
+}
+
+func TestLightingColorizer(t *testing.T) {
+	const ambient = 0.3
+
+	f := newTestField()
+	for _, az := range []float64{0.0, 45.0, 135.0, 270.0} {
+		img := NewLightingColorizer(nil, az, 30.0, 8.0, ambient).Colorize(f)
+		b := f.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := ColorFOf(img.At(x, y))
+				if c.R < ambient-1.0e-4 || c.R > 1.0+1.0e-4 {
+					t.Errorf("azimuth %v, (%d,%d): shade %v outside of [%v,1]",
+						az, x, y, c.R, ambient)
+				}
+				if c.R != c.G || c.R != c.B {
+					t.Errorf("azimuth %v, (%d,%d): %v isn't gray", az, x, y, c)
+				}
+			}
+		}
+	}
+}
+
+func TestInsideColor(t *testing.T) {
+	pal := newGrayPalette(t)
+	f := newTestField()
+	red := ColorF{1.0, 0.0, 0.0, 1.0}
+	for name, c := range map[string]Colorizer{
+		"palette":   NewPaletteColorizer(pal),
+		"histogram": NewHistogramColorizer(pal),
+		"distance":  NewDistanceColorizer(pal, 8.0),
+	} {
+		img := c.Colorize(f)
+		for _, p := range []image.Point{{2, 2}, {3, 2}, {2, 3}} {
+			got := ColorFOf(img.At(p.X, p.Y))
+			if math.Abs(got.R-red.R)+math.Abs(got.G-red.G)+math.Abs(got.B-red.B) > 1.0e-4 {
+				t.Errorf("%s: inside point %v has color %v, want %v", name, p, got, red)
+			}
+		}
+		if got := ColorFOf(img.At(0, 0)); got.G != got.R {
+			t.Errorf("%s: outside point has color %v, want gray", name, got)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package mandel
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Die Konfigurationsdateien (palette.ini, path.ini, layer.ini) werden in
+// mehreren Verzeichnissen gesucht. In aufsteigender Prioritaet sind dies:
+//
+//  1. das Benutzerverzeichnis $XDG_CONFIG_HOME/mandel (resp.
+//     ~/.config/mandel, falls XDG_CONFIG_HOME nicht gesetzt ist),
+//  2. das Projektverzeichnis, d.h. das aktuelle Verzeichnis,
+//  3. das Verzeichnis in der Umgebungsvariable MANDEL_CONFIG,
+//  4. das Verzeichnis, welches mit der Option '-config' angegeben wurde
+//     (siehe [ConfigFlag]).
+//
+// Paletten werden aus allen gefundenen Dateien palette.ini gelesen, wobei
+// Paletten aus Dateien mit hoeherer Prioritaet diejenigen mit gleichem Namen
+// ueberschreiben. Die mitgelieferten Paletten (siehe [DefaultPaletteRegistry])
+// haben die tiefste Prioritaet. Pfade und Ebenen-Stapel werden in der Datei
+// mit der hoechsten Prioritaet gesucht, welche einen Abschnitt mit dem
+// gesuchten Namen enthaelt (siehe [OpenConfSection]).
+
+const (
+	// confEnvVar ist der Name der Umgebungsvariable mit einem zusaetzlichen
+	// Konfigurationsverzeichnis.
+	confEnvVar = "MANDEL_CONFIG"
+)
+
+var (
+	// confDirFlag ist das Verzeichnis, welches mit der Option '-config'
+	// (resp. mit SetConfDir) angegeben wurde.
+	confDirFlag string
+)
+
+// Die Herkunft eines Verzeichnisses im Suchpfad.
+const (
+	OriginUser    = "user"
+	OriginProject = "project"
+	OriginEnv     = confEnvVar
+	OriginFlag    = "-config"
+)
+
+// ConfDir ist ein Verzeichnis im Suchpfad fuer Konfigurationsdateien. In
+// Origin ist vermerkt, woher das Verzeichnis stammt (OriginUser, etc.).
+type ConfDir struct {
+	Path   string
+	Origin string
+}
+
+// Setzt das Verzeichnis mit der hoechsten Prioritaet (entspricht der Option
+// '-config').
+func SetConfDir(dir string) {
+	confDirFlag = dir
+}
+
+// ConfigFlag registriert die Option '-config' beim Standard-FlagSet. Muss
+// vor flag.Parse aufgerufen werden und sollte von jedem Programm verwendet
+// werden, welches Konfigurationsdateien liest.
+func ConfigFlag() {
+	flag.StringVar(&confDirFlag, "config", "",
+		"directory with configuration files (palette.ini, path.ini, layer.ini)")
+}
+
+// userConfDir liefert das Benutzerverzeichnis fuer die Konfiguration.
+func userConfDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, confDir), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", confDir), nil
+}
+
+// ConfDirs liefert alle Verzeichnisse des Suchpfades in aufsteigender
+// Prioritaet. Verzeichnisse, welche mehrfach vorkommen, werden nur mit der
+// hoechsten Prioritaet aufgefuehrt. Ob die Verzeichnisse existieren, wird
+// nicht geprueft.
+func ConfDirs() []ConfDir {
+	var dirs []ConfDir
+
+	add := func(dir, origin string) {
+		if dir == "" {
+			return
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		for i, d := range dirs {
+			if d.Path == dir {
+				dirs = append(dirs[:i], dirs[i+1:]...)
+				break
+			}
+		}
+		dirs = append(dirs, ConfDir{dir, origin})
+	}
+	if dir, err := userConfDir(); err == nil {
+		add(dir, OriginUser)
+	}
+	if dir, err := os.Getwd(); err == nil {
+		add(dir, OriginProject)
+	}
+	add(os.Getenv(confEnvVar), OriginEnv)
+	add(confDirFlag, OriginFlag)
+	return dirs
+}
+
+// ConfFiles liefert die Pfade aller existierenden Dateien fileName im
+// Suchpfad, in aufsteigender Prioritaet.
+func ConfFiles(fileName string) []string {
+	var files []string
+
+	for _, d := range ConfDirs() {
+		path := filepath.Join(d.Path, fileName)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// OpenConfSection oeffnet von allen Dateien fileName im Suchpfad diejenige
+// mit der hoechsten Prioritaet, welche einen Abschnitt [section] enthaelt.
+// Enthaelt keine Datei diesen Abschnitt, so wird die Datei gemaess
+// [OpenConfFile] geoeffnet.
+func OpenConfSection(fileName, section string) (*os.File, error) {
+	files := ConfFiles(fileName)
+	for i := len(files) - 1; i >= 0; i-- {
+		ok, err := hasSection(files[i], section)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return os.Open(files[i])
+		}
+	}
+	return OpenConfFile(fileName)
+}
+
+// hasSection prueft, ob die Datei fileName einen Abschnitt [section]
+// enthaelt.
+func hasSection(fileName, section string) (bool, error) {
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return false, err
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		if m := regxSection.FindStringSubmatch(scanner.Text()); m != nil && m[1] == section {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// defConfDir liefert das Verzeichnis, in welchem neue Konfigurationsdateien
+// angelegt werden: das Verzeichnis mit der hoechsten Prioritaet, wobei das
+// Projektverzeichnis nur verwendet wird, wenn sonst keines bekannt ist.
+func defConfDir() (string, error) {
+	dirs := ConfDirs()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if dirs[i].Origin != OriginProject {
+			return dirs[i].Path, nil
+		}
+	}
+	if len(dirs) > 0 {
+		return dirs[0].Path, nil
+	}
+	return "", errors.New("no configuration directory found")
+}
+
+// Alle Konfigurationsdateien (palette.ini, path.ini) haben denselben Aufbau:
+// sie bestehen aus Abschnitten, welche mit '[name]' eingeleitet werden und
+// Optionen der Form 'schluessel = wert' sowie beliebige Datenzeilen
+// enthalten. Leere Zeilen und Kommentare (beginnend mit '#') werden
+// ignoriert. Mit confScanner werden solche Dateien zeilenweise gelesen.
+
+// confLineKind gibt die Art einer Zeile an.
+type confLineKind int
+
+const (
+	confSection confLineKind = iota
+	confOption
+	confData
+)
+
+// confLine ist eine einzelne (nicht leere) Zeile einer Konfigurationsdatei.
+// Bei Abschnitten enthaelt key den Namen des Abschnittes, bei Optionen den
+// Schluessel und value den Wert.
+type confLine struct {
+	kind       confLineKind
+	no         int
+	text       string
+	key, value string
+}
+
+// confScanner liest die Zeilen einer Konfigurationsdatei, wobei leere
+// Zeilen und Kommentare uebersprungen werden.
+type confScanner struct {
+	scanner *bufio.Scanner
+	line    confLine
+	lineNo  int
+}
+
+// Erstellt einen neuen Scanner, welcher aus r liest. lineNo ist die Anzahl
+// Zeilen, welche vor dem Anfang von r liegen (fuer Fehlermeldungen).
+func newConfScanner(r io.Reader, lineNo int) *confScanner {
+	return &confScanner{scanner: bufio.NewScanner(r), lineNo: lineNo}
+}
+
+// Scan liest die naechste Zeile, welche anschliessend mit Line abgefragt
+// werden kann. Liefert false am Ende der Daten oder bei einem Fehler.
+func (cs *confScanner) Scan() bool {
+	for cs.scanner.Scan() {
+		cs.lineNo++
+		text := cs.scanner.Text()
+		if regxComm.MatchString(text) {
+			continue
+		}
+		cs.line = confLine{kind: confData, no: cs.lineNo, text: text}
+		if m := regxSection.FindStringSubmatch(text); m != nil {
+			cs.line.kind = confSection
+			cs.line.key = m[1]
+		} else if m := regxOption.FindStringSubmatch(text); m != nil {
+			cs.line.kind = confOption
+			cs.line.key, cs.line.value = m[1], m[2]
+		}
+		return true
+	}
+	return false
+}
+
+// Line liefert die zuletzt mit Scan gelesene Zeile.
+func (cs *confScanner) Line() confLine {
+	return cs.line
+}
+
+// Err liefert den ersten Fehler, welcher beim Lesen aufgetreten ist.
+func (cs *confScanner) Err() error {
+	return cs.scanner.Err()
+}
+
+// Find ueberspringt alle Zeilen bis und mit dem Titel des Abschnittes name.
+// Liefert false, falls kein solcher Abschnitt gefunden wurde.
+func (cs *confScanner) Find(name string) bool {
+	for cs.Scan() {
+		if cs.line.kind == confSection && cs.line.key == name {
+			return true
+		}
+	}
+	return false
+}
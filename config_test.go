@@ -0,0 +1,67 @@
+package mandel
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfDirs(t *testing.T) {
+	base := t.TempDir()
+	xdg := filepath.Join(base, "xdg")
+	env := filepath.Join(base, "env")
+	flg := filepath.Join(base, "flag")
+	for _, dir := range []string{filepath.Join(xdg, confDir), env, flg} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv(confEnvVar, env)
+	SetConfDir(flg)
+	defer SetConfDir("")
+
+	dirs := ConfDirs()
+	origins := make([]string, len(dirs))
+	for i, d := range dirs {
+		origins[i] = d.Origin
+	}
+	want := []string{OriginUser, OriginProject, OriginEnv, OriginFlag}
+	if strings.Join(origins, ",") != strings.Join(want, ",") {
+		t.Fatalf("ConfDirs() = %v, want origins %v", dirs, want)
+	}
+	if dirs[0].Path != filepath.Join(xdg, confDir) {
+		t.Errorf("user dir = %s", dirs[0].Path)
+	}
+
+	write := func(dir, data string) {
+		if err := os.WriteFile(filepath.Join(dir, "test.ini"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(xdg, confDir), "[A]\nuser\n[B]\nuser\n")
+	write(flg, "[A]\nflag\n")
+	files := ConfFiles("test.ini")
+	if len(files) != 2 || !strings.HasPrefix(files[1], flg) {
+		t.Fatalf("ConfFiles() = %v", files)
+	}
+	if path, _ := ConfFilePath("test.ini"); path != files[1] {
+		t.Errorf("ConfFilePath() = %s, want %s", path, files[1])
+	}
+	if path, _ := ConfFilePath("new.ini"); path != filepath.Join(flg, "new.ini") {
+		t.Errorf("ConfFilePath() for a new file = %s", path)
+	}
+	for section, want := range map[string]string{"A": "flag", "B": "user"} {
+		fd, err := OpenConfSection("test.ini", section)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(fd)
+		fd.Close()
+		if !strings.Contains(string(data), want) {
+			t.Errorf("section %s: read %q, want the %s file", section, data, want)
+		}
+	}
+}
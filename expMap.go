@@ -0,0 +1,169 @@
+package mandel
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// RenderMode gibt an, wie die Bilder einer Animation berechnet werden.
+//
+//	frames     Jedes Bild wird vollstaendig neu berechnet.
+//	expmap     Zuerst wird eine exponentielle Karte (siehe [ExpMap]) um den
+//	           Mittelpunkt der letzten Ansicht berechnet, die Bilder werden
+//	           anschliessend daraus abgetastet. Eignet sich fuer lange Zooms
+//	           auf einen festen Punkt.
+//	keyframes  Nur einzelne Schluesselbilder (siehe [KeyframePlan]) werden
+//	           vollstaendig berechnet, die Bilder dazwischen werden daraus
+//	           abgetastet. Neu berechnet werden nur Pixel, welche in keinem
+//	           Schluesselbild genuegend fein enthalten sind.
+type RenderMode int
+
+const (
+	RenderFrames RenderMode = iota
+	RenderExpMap
+	RenderKeyframes
+)
+
+func (rm RenderMode) String() string {
+	switch rm {
+	case RenderFrames:
+		return "frames"
+	case RenderExpMap:
+		return "expmap"
+	case RenderKeyframes:
+		return "keyframes"
+	default:
+		return "Unknown render mode"
+	}
+}
+
+func (rm *RenderMode) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "frames":
+		*rm = RenderFrames
+	case "expmap":
+		*rm = RenderExpMap
+	case "keyframes":
+		*rm = RenderKeyframes
+	default:
+		return errors.New("Unknown render mode: " + s)
+	}
+	return nil
+}
+
+// ExpMap beschreibt die Geometrie einer exponentiellen (log-polaren) Karte
+// um den Punkt (X, Y). Die Spalten entsprechen dem Winkel (Cols Spalten
+// fuer eine volle Umdrehung), die Zeilen dem Logarithmus des Radius: Zeile
+// 0 hat den Radius RMax, jede weitere Zeile ist um den Faktor
+// exp(2*Pi/Cols) kleiner. Damit sind die Pixel der Karte ueberall
+// (annaehernd) quadratisch. Die Karte wird in Streifen zu je
+// [ExpMap.RowsPerOctave] Zeilen unterteilt, ein Streifen pro Halbierung des
+// Radius (Oktave).
+type ExpMap struct {
+	X, Y, RMax float64
+	Cols       int
+	iters      []expMapIter
+}
+
+// expMapIter enthaelt die Anzahl Iterationen einer Ansicht, deren Ecken
+// den Abstand r vom Mittelpunkt der Karte haben.
+type expMapIter struct {
+	r  float64
+	it int
+}
+
+// PlanExpMap bestimmt die Karte fuer die Bilder des Pfades p mit cols x
+// rows Pixeln. Dazu werden die Ansichten an n+1 gleichmaessig verteilten
+// Stellen untersucht: der Mittelpunkt ist derjenige der letzten Ansicht,
+// der Radius gerade so gross, dass alle Ansichten abgedeckt sind. Die
+// Anzahl Spalten wird so gewaehlt, dass die Karte auch in den Ecken der
+// Bilder mindestens so fein ist wie ein (Sub-)Pixel. iter liefert die
+// Anzahl Iterationen der Ansicht an der Stelle t (bspw. aus einer
+// [IterCurve]); ist iter nil, werden die Werte des Pfades verwendet.
+func PlanExpMap(p Path, n, cols, rows int, sm SampleMode, iter func(t float64) int) *ExpMap {
+	m := &ExpMap{}
+	m.X, m.Y, _, _ = p.GetView(1.0).Values()
+	m.Cols = int(math.Ceil(math.Pi * math.Hypot(float64(cols), float64(rows)) * float64(sm)))
+
+	m.iters = make([]expMapIter, n+1)
+	for i := range m.iters {
+		t := 1.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+		v := p.GetView(t)
+		_, _, _, it := v.Values()
+		if iter != nil {
+			it = iter(t)
+		}
+		r := m.ViewRadius(v, cols, rows)
+		m.iters[i] = expMapIter{r, it}
+		m.RMax = math.Max(m.RMax, r)
+	}
+	return m
+}
+
+// ViewRadius liefert den groessten Abstand der Ecken der Ansicht v (mit
+// cols x rows Pixeln) vom Mittelpunkt der Karte. Die Ansicht ist damit
+// vollstaendig in den Zeilen ab [ExpMap.Row] dieses Radius enthalten.
+func (m *ExpMap) ViewRadius(v View, cols, rows int) float64 {
+	r := 0.0
+	for _, p := range viewCorners(v, cols, rows) {
+		r = math.Max(r, math.Hypot(p[0]-m.X, p[1]-m.Y))
+	}
+	return r
+}
+
+// viewCorners liefert die Ecken der Ansicht v mit cols x rows Pixeln in der
+// komplexen Ebene.
+func viewCorners(v View, cols, rows int) [4][2]float64 {
+	var corners [4][2]float64
+
+	x, y, w, _ := v.Values()
+	a, b, c, d := v.Transform().Matrix()
+	u, h := w/2.0, w*float64(rows)/float64(cols)/2.0
+	for i, s := range [4][2]float64{{-u, -h}, {u, -h}, {-u, h}, {u, h}} {
+		corners[i] = [2]float64{x + a*s[0] + b*s[1], y + c*s[0] + d*s[1]}
+	}
+	return corners
+}
+
+// RowsPerOctave liefert die Anzahl Zeilen eines Streifens.
+func (m *ExpMap) RowsPerOctave() int {
+	return int(math.Ceil(float64(m.Cols) * math.Ln2 / (2.0 * math.Pi)))
+}
+
+// Radius liefert den Radius der (nicht notwendigerweise ganzzahligen)
+// Zeile row.
+func (m *ExpMap) Radius(row float64) float64 {
+	return m.RMax * math.Exp(-row*2.0*math.Pi/float64(m.Cols))
+}
+
+// Row ist die Umkehrung von [ExpMap.Radius].
+func (m *ExpMap) Row(r float64) float64 {
+	return math.Log(m.RMax/r) * float64(m.Cols) / (2.0 * math.Pi)
+}
+
+// Point liefert den Punkt der komplexen Ebene in Spalte col und Zeile row.
+func (m *ExpMap) Point(col, row int) (x, y float64) {
+	sin, cos := math.Sincos(2.0 * math.Pi * float64(col) / float64(m.Cols))
+	r := m.Radius(float64(row))
+	return m.X + r*cos, m.Y + r*sin
+}
+
+// MaxIter liefert die Anzahl Iterationen fuer die Punkte mit Abstand r vom
+// Mittelpunkt: das Maximum aller untersuchten Ansichten, in welchen diese
+// Punkte noch zu sehen sind.
+func (m *ExpMap) MaxIter(r float64) int {
+	it := 0
+	for _, mi := range m.iters {
+		if mi.r >= r {
+			it = max(it, mi.it)
+		}
+	}
+	if it == 0 && len(m.iters) > 0 {
+		it = m.iters[0].it
+	}
+	return it
+}
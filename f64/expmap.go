@@ -0,0 +1,217 @@
+package f64
+
+import (
+    "math"
+    "sync"
+
+    . "github.com/stefan-muehlebach/mandel"
+)
+
+// ExpMapStrips enthaelt die berechneten Streifen einer exponentiellen Karte
+// (siehe mandel.ExpMap). Die Streifen werden erst berechnet, wenn sie fuer
+// ein Bild benoetigt werden, und koennen mit Release wieder freigegeben
+// werden. Alle Methoden koennen von mehreren Go-Routinen gleichzeitig
+// verwendet werden.
+type ExpMapStrips struct {
+    m       *ExpMap
+    rows    int
+    mutex   sync.Mutex
+    octaves map[int]*expMapStrip
+}
+
+// expMapStrip ist ein einzelner Streifen der Karte. Die Werte werden wie im
+// Feld abgelegt (-1.0 fuer Punkte der Mandelbrot-Menge), aber mit einfacher
+// Genauigkeit, da die Karte bei tiefen Zooms sehr gross wird.
+type expMapStrip struct {
+    once sync.Once
+    F    []float32
+}
+
+// Erstellt die (noch leeren) Streifen zur Karte m.
+func NewExpMapStrips(m *ExpMap) *ExpMapStrips {
+    s := &ExpMapStrips{}
+    s.m = m
+    s.rows = m.RowsPerOctave()
+    s.octaves = make(map[int]*expMapStrip)
+    return s
+}
+
+// Liefert den Streifen k und berechnet ihn bei Bedarf.
+func (s *ExpMapStrips) octave(k int) []float32 {
+    s.mutex.Lock()
+    strip, ok := s.octaves[k]
+    if !ok {
+        strip = &expMapStrip{}
+        s.octaves[k] = strip
+    }
+    s.mutex.Unlock()
+    strip.once.Do(func() {
+        strip.F = s.calcOctave(k)
+    })
+    return strip.F
+}
+
+// Berechnet die Werte des Streifens k. Die Anzahl Iterationen wird anhand
+// des groessten Radius im Streifen bestimmt.
+func (s *ExpMapStrips) calcOctave(k int) []float32 {
+    var f f64Field
+
+    cols := s.m.Cols
+    maxIter := s.m.MaxIter(s.m.Radius(float64(k * s.rows)))
+    data := make([]float32, cols*s.rows)
+    for row := 0; row < s.rows; row++ {
+        for col := 0; col < cols; col++ {
+            cx, cy := s.m.Point(col, k*s.rows+row)
+            iter := float64(maxIter)
+            if !f.skipCalculation(cx, cy) {
+                iter = f.calcPixel(cx, cy, maxIter)
+            }
+            if iter == float64(maxIter) {
+                iter = -1.0
+            }
+            data[row*cols+col] = float32(iter)
+        }
+    }
+    return data
+}
+
+// Release gibt alle Streifen frei, deren Punkte alle weiter als r vom
+// Mittelpunkt entfernt sind. Werden diese spaeter doch noch benoetigt, so
+// werden sie neu berechnet.
+func (s *ExpMapStrips) Release(r float64) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    for k := range s.octaves {
+        if s.m.Radius(float64((k+1)*s.rows-1)) > r {
+            delete(s.octaves, k)
+        }
+    }
+}
+
+// expMapWindow enthaelt die Streifen k0, k0+1, ... einer Karte, welche fuer
+// ein einzelnes Bild benoetigt werden. Damit muss beim Abtasten nicht fuer
+// jeden Punkt auf die (gemeinsam genutzte) Karte zugegriffen werden.
+type expMapWindow struct {
+    m    *ExpMap
+    rows int
+    k0   int
+    octs [][]float32
+}
+
+// Liefert die Streifen, welche die Radien von r1 bis r0 (r0 < r1) abdecken.
+func (s *ExpMapStrips) window(r0, r1 float64) *expMapWindow {
+    win := &expMapWindow{m: s.m, rows: s.rows}
+    win.k0 = int(math.Max(s.m.Row(r1), 0.0)) / s.rows
+    k1 := (int(math.Max(s.m.Row(r0), 0.0)) + 1) / s.rows
+    for k := win.k0; k <= k1; k++ {
+        win.octs = append(win.octs, s.octave(k))
+    }
+    return win
+}
+
+// Liefert den Wert der Karte in der (ganzzahligen) Zeile row und Spalte col.
+// Die Spalten werden periodisch fortgesetzt.
+func (win *expMapWindow) at(col, row int) float64 {
+    cols := win.m.Cols
+    col %= cols
+    if col < 0 {
+        col += cols
+    }
+    return float64(win.octs[row/win.rows-win.k0][(row%win.rows)*cols+col])
+}
+
+// Liefert den Wert der Karte im Punkt (dx, dy) relativ zum Mittelpunkt.
+// Radien kleiner als rMin werden auf rMin angehoben. Zwischen den Werten
+// der Karte wird bilinear interpoliert.
+func (win *expMapWindow) value(dx, dy, rMin float64) float64 {
+    r := math.Max(math.Hypot(dx, dy), rMin)
+    v := math.Max(win.m.Row(r), 0.0)
+    u := math.Atan2(dy, dx) / (2.0 * math.Pi) * float64(win.m.Cols)
+
+    row, col := int(v), int(math.Floor(u))
+    return bilinear(win.at(col, row), win.at(col+1, row),
+            win.at(col, row+1), win.at(col+1, row+1), u-float64(col), v-float64(row))
+}
+
+// Interpoliert bilinear zwischen den Werten v00 (links oben), v01 (rechts
+// oben), v10 (links unten) und v11 (rechts unten). Gehoert einer der Punkte
+// zur Mandelbrot-Menge (Wert < 0), so wird der naechste Wert verwendet.
+func bilinear(v00, v01, v10, v11, fu, fv float64) float64 {
+    if v00 < 0.0 || v01 < 0.0 || v10 < 0.0 || v11 < 0.0 {
+        switch {
+        case fv < 0.5 && fu < 0.5:
+            return v00
+        case fv < 0.5:
+            return v01
+        case fu < 0.5:
+            return v10
+        default:
+            return v11
+        }
+    }
+    return (1.0-fv)*((1.0-fu)*v00+fu*v01) + fv*((1.0-fu)*v10+fu*v11)
+}
+
+// Berechnet das Feld f mit der Ansicht v durch Abtasten der Karte s anstatt
+// durch Iteration. Die Geometrie (inkl. Drehung und Verzerrung) und das
+// Subsampling entsprechen CalcMandelbrot.
+func (f *f64Field) CalcExpMap(s *ExpMapStrips, v View) {
+    var d, h, cx, cy, rx, ry, iter float64
+    var a, b, c, e float64
+    var row, col int
+
+    x, y, w, it := v.Values()
+    f.MaxIter = float64(it)
+
+    a, b, c, e = v.Transform().Matrix()
+    d = w / float64(f.Cols)
+    h = d * float64(f.Rows)
+    dxc, dyc := a*d, c*d
+    dxr, dyr := -b*d, -e*d
+    cx = x - s.m.X - a*w/2.0 + b*h/2.0
+    cy = y - s.m.Y - c*w/2.0 + e*h/2.0
+    rMin := d / float64(4*f.sm)
+    win := s.window(rMin, s.m.ViewRadius(v, f.Cols, f.Rows))
+
+    for row = 0; row < f.Rows; row++ {
+        rx, ry = cx, cy
+        for col = 0; col < f.Cols; col++ {
+            iter = f.sampleCell(win, rx, ry, dxc, dyc, dxr, dyr, rMin)
+            if iter == f.MaxIter {
+                f.F[row][col] = -1.0
+            } else {
+                f.F[row][col] = iter
+            }
+            rx += dxc
+            ry += dyc
+        }
+        cx += dxr
+        cy += dyr
+    }
+}
+
+// Entspricht calcCell, die Werte stammen aber aus der Karte. Punkte der
+// Mandelbrot-Menge zaehlen wie bei der Berechnung mit MaxIter.
+func (f *f64Field) sampleCell(win *expMapWindow, cx, cy, dxc, dyc, dxr, dyr, rMin float64) (iter float64) {
+    var rx, ry, sx, sy, val float64
+    var cellRow, cellCol int
+
+    n := float64(f.sm)
+    dxc, dyc, dxr, dyr = dxc/n, dyc/n, dxr/n, dyr/n
+    sx, sy = cx, cy
+    for cellRow = 0; cellRow < int(f.sm); cellRow++ {
+        rx, ry = sx, sy
+        for cellCol = 0; cellCol < int(f.sm); cellCol++ {
+            val = win.value(rx, ry, rMin)
+            if val < 0.0 || val > f.MaxIter {
+                val = f.MaxIter
+            }
+            iter += val
+            rx += dxc
+            ry += dyc
+        }
+        sx += dxr
+        sy += dyr
+    }
+    return iter / (n * n)
+}
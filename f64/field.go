@@ -45,6 +45,7 @@ type f64Field struct {
     pal        Palette
     F          [][]float64
     sm         SampleMode
+    model      color.Model
     // iterHist []float64
 }
 
@@ -63,7 +64,8 @@ func NewField(cols, rows int, sm SampleMode) *f64Field {
 
 // Berechnet die Mandelbrot-Menge ueber dem Feld f mit der Ansicht v.
 func (f *f64Field) CalcMandelbrot(v View) {
-    var dx, dy, xmin, ymax, h, cx, cy, iter float64
+    var d, h, cx, cy, rx, ry, iter float64
+    var a, b, c, e float64
     // var total float64
     var row, col int
     
@@ -78,29 +80,36 @@ func (f *f64Field) CalcMandelbrot(v View) {
     //     }
     // }
 
-    dx = w / float64(f.Cols)
-    dy = dx
-    h = dy * float64(f.Rows)
-    xmin = x - w/2.0
-    ymax = y + h/2.0
+    // Die Pixel werden zuerst relativ zum Mittelpunkt der Ansicht
+    // adressiert und anschliessend mit der Matrix der View gedreht und
+    // verzerrt. (dxc, dyc) ist der Schritt von einer Spalte zur naechsten,
+    // (dxr, dyr) derjenige von einer Zeile zur naechsten.
+    a, b, c, e = v.Transform().Matrix()
+    d = w / float64(f.Cols)
+    h = d * float64(f.Rows)
+    dxc, dyc := a*d, c*d
+    dxr, dyr := -b*d, -e*d
+    cx = x - a*w/2.0 + b*h/2.0
+    cy = y - c*w/2.0 + e*h/2.0
 
-    cy = ymax
     for row = 0; row < f.Rows; row++ {
-        cx = xmin
+        rx, ry = cx, cy
         for col = 0; col < f.Cols; col++ {
-            if f.skipCalculation(cx, cy) {
+            if f.skipCalculation(rx, ry) {
                 iter = f.MaxIter
             } else {
-                iter = f.calcCell(cx, cy, dx, dy, it)
+                iter = f.calcCell(rx, ry, dxc, dyc, dxr, dyr, it)
             }
             if iter == f.MaxIter {
                 f.F[row][col] = -1.0
             } else {
                 f.F[row][col] = iter
             }
-            cx += dx
+            rx += dxc
+            ry += dyc
         }
-        cy -= dy
+        cx += dxr
+        cy += dyr
     }
 
     // total = 0.0
@@ -112,8 +121,11 @@ func (f *f64Field) CalcMandelbrot(v View) {
     // }
 }
 
-func (f *f64Field) calcCell(cx, cy, dx, dy float64, maxIter int) (iter float64) {
-    var rx, ry float64
+// Berechnet den Wert eines Pixels, wobei (cx, cy) die linke obere Ecke des
+// Pixels ist. Bei Subsampling werden die Schritte (dxc, dyc) zur naechsten
+// Spalte und (dxr, dyr) zur naechsten Zeile unterteilt.
+func (f *f64Field) calcCell(cx, cy, dxc, dyc, dxr, dyr float64, maxIter int) (iter float64) {
+    var rx, ry, sx, sy float64
     var cellRow, cellCol int
 
     if f.sm == Samp1x1 {
@@ -121,18 +133,20 @@ func (f *f64Field) calcCell(cx, cy, dx, dy float64, maxIter int) (iter float64)
     }
 
     iter = 0.0
-    dx /= float64(f.sm)
-    dy /= float64(f.sm)
-    ry = cy
+    n := float64(f.sm)
+    dxc, dyc, dxr, dyr = dxc/n, dyc/n, dxr/n, dyr/n
+    sx, sy = cx, cy
     for cellRow = 0; cellRow < int(f.sm); cellRow++ {
-        rx = cx
+        rx, ry = sx, sy
         for cellCol = 0; cellCol < int(f.sm); cellCol++ {
             iter += f.calcPixel(rx, ry, maxIter)
-            rx += dx
+            rx += dxc
+            ry += dyc
         }
-        ry -= dy
+        sx += dxr
+        sy += dyr
     }
-    return iter / (float64(f.sm) * float64(f.sm))
+    return iter / (n * n)
 }
 
 func (f *f64Field) calcPixel(cx, cy float64, maxIter int) (iter float64) {
@@ -217,9 +231,32 @@ func (f *f64Field) Read(fileName string) (error) {
     return err
 }
 
+// Legt fest, mit welchem Farbmodell das Feld als Bild dargestellt wird.
+// Unterstuetzt werden color.RGBAModel (Default, 8 Bit pro Farbkanal) und
+// color.RGBA64Model (16 Bit pro Farbkanal). Die Colorizer erstellen ihre
+// Bilder ebenfalls in diesem Farbmodell.
+func (f *f64Field) SetColorModel(m color.Model) {
+    f.model = m
+}
+
+// Value liefert den berechneten (geglaetteten) Iterationswert an der Stelle
+// (x,y). Fuer Punkte der Mandelbrot-Menge ist dieser Wert -1.0. Diese und
+// die folgende Methode werden von den Colorizern verwendet.
+func (f *f64Field) Value(x, y int) float64 {
+    return f.F[y][x]
+}
+
+// Retourniert die maximale Anzahl Iterationen der letzten Berechnung.
+func (f *f64Field) MaxIterations() int {
+    return int(f.MaxIter)
+}
+
 // Methoden des image.Image Interfaces. Auf diese Weise wird die Speicherung
 // der Felddaten als PNG oder JPG realisiert.
 func (f *f64Field) ColorModel() color.Model {
+    if f.model == color.RGBA64Model {
+        return color.RGBA64Model
+    }
     return color.RGBAModel
 }
 
@@ -228,6 +265,9 @@ func (f *f64Field) Bounds() image.Rectangle {
 }
 
 func (f *f64Field) At(x, y int) color.Color {
+    if f.model == color.RGBA64Model {
+        return f.pal.GetColorF(f.F[y][x]).RGBA64()
+    }
     return f.pal.GetColor(f.F[y][x])
 }
 
@@ -0,0 +1,169 @@
+package f64
+
+import (
+    "sync"
+
+    . "github.com/stefan-muehlebach/mandel"
+)
+
+// Keyframes enthaelt die berechneten Schluesselbilder eines Plans (siehe
+// mandel.KeyframePlan). Die Schluesselbilder werden erst berechnet, wenn sie
+// fuer ein Bild benoetigt werden, und koennen mit Release wieder freigegeben
+// werden. Alle Methoden koennen von mehreren Go-Routinen gleichzeitig
+// verwendet werden.
+type Keyframes struct {
+    plan   *KeyframePlan
+    sm     SampleMode
+    mutex  sync.Mutex
+    fields map[int]*keyframeField
+}
+
+type keyframeField struct {
+    once  sync.Once
+    field *f64Field
+}
+
+// Erstellt die (noch leeren) Schluesselbilder zum Plan plan. Diese werden
+// mit dem Subsampling sm berechnet.
+func NewKeyframes(plan *KeyframePlan, sm SampleMode) *Keyframes {
+    kf := &Keyframes{}
+    kf.plan = plan
+    kf.sm = sm
+    kf.fields = make(map[int]*keyframeField)
+    return kf
+}
+
+// Liefert das Schluesselbild k und berechnet es bei Bedarf.
+func (kf *Keyframes) key(k int) *f64Field {
+    kf.mutex.Lock()
+    kff, ok := kf.fields[k]
+    if !ok {
+        kff = &keyframeField{}
+        kf.fields[k] = kff
+    }
+    kf.mutex.Unlock()
+    kff.once.Do(func() {
+        key := kf.plan.Keys[k]
+        view := NewView()
+        view.SetValues(key.X, key.Y, key.W, key.MaxIter)
+        kff.field = NewField(key.Cols, key.Rows, kf.sm)
+        kff.field.CalcMandelbrot(view)
+    })
+    return kff.field
+}
+
+// Release gibt alle Schluesselbilder frei, welche fuer die Bilder ab dem
+// Bild i nicht mehr benoetigt werden. Werden diese spaeter doch noch
+// benoetigt, so werden sie neu berechnet.
+func (kf *Keyframes) Release(i int) {
+    kf.mutex.Lock()
+    defer kf.mutex.Unlock()
+    for k := range kf.fields {
+        if k < kf.plan.FrameKey(i)-1 {
+            delete(kf.fields, k)
+        }
+    }
+}
+
+// keyframeSource ist ein Schluesselbild, aus welchem ein bestimmtes Bild
+// abgetastet wird. (x0, y0) ist die Position des Wertes in der linken
+// oberen Ecke.
+type keyframeSource struct {
+    field  *f64Field
+    x0, y0 float64
+    pix    float64
+}
+
+// Liefert den Wert im Punkt (x, y) und ob dieser Punkt im Schluesselbild
+// enthalten ist.
+func (src *keyframeSource) value(x, y float64) (float64, bool) {
+    u := (x - src.x0) / src.pix
+    v := (src.y0 - y) / src.pix
+    if u < 0.0 || v < 0.0 || u >= float64(src.field.Cols-1) || v >= float64(src.field.Rows-1) {
+        return 0.0, false
+    }
+    col, row := int(u), int(v)
+    return bilinear(src.field.F[row][col], src.field.F[row][col+1],
+            src.field.F[row+1][col], src.field.F[row+1][col+1],
+            u-float64(col), v-float64(row)), true
+}
+
+// Liefert den Abstand des Mittelwertes der Subsamples zur linken oberen
+// Ecke eines Pixels (in Pixeln).
+func sampleOffset(sm SampleMode) float64 {
+    n := float64(sm)
+    return (n - 1.0) / (2.0 * n)
+}
+
+// Berechnet das Feld f fuer das Bild i mit der Ansicht v. Die Werte werden
+// wenn moeglich aus den Schluesselbildern abgetastet (zuerst aus dem
+// naechst tieferen, dann aus dem zustaendigen und zuletzt aus dem
+// vorangehenden). Ist ein Pixel in keinem davon enthalten, oder muessten
+// die Pixel der Schluesselbilder mehr als erlaubt vergroessert werden, so
+// wird der Wert wie bei CalcMandelbrot berechnet.
+func (f *f64Field) CalcKeyframes(kf *Keyframes, i int, v View) {
+    var d, h, cx, cy, rx, ry, iter float64
+    var a, b, c, e float64
+    var row, col int
+    var ok bool
+
+    x, y, w, it := v.Values()
+    f.MaxIter = float64(it)
+
+    a, b, c, e = v.Transform().Matrix()
+    d = w / float64(f.Cols)
+    h = d * float64(f.Rows)
+    dxc, dyc := a*d, c*d
+    dxr, dyr := -b*d, -e*d
+    cx = x - a*w/2.0 + b*h/2.0
+    cy = y - c*w/2.0 + e*h/2.0
+
+    var srcs []*keyframeSource
+    cur := kf.plan.FrameKey(i)
+    for k := min(cur+1, len(kf.plan.Keys)-1); k >= max(cur-1, 0); k-- {
+        key := kf.plan.Keys[k]
+        if key.PixelSize() > kf.plan.Quality*d*(1.0+1e-9) {
+            continue
+        }
+        off := sampleOffset(kf.sm) * key.PixelSize()
+        srcs = append(srcs, &keyframeSource{
+            field: kf.key(k),
+            x0:    key.X - key.W/2.0 + off,
+            y0:    key.Y + key.H/2.0 - off,
+            pix:   key.PixelSize(),
+        })
+    }
+    off := sampleOffset(f.sm)
+    ox, oy := off*(dxc+dxr), off*(dyc+dyr)
+
+    for row = 0; row < f.Rows; row++ {
+        rx, ry = cx, cy
+        for col = 0; col < f.Cols; col++ {
+            ok = false
+            for _, src := range srcs {
+                if iter, ok = src.value(rx+ox, ry+oy); ok {
+                    if iter < 0.0 || iter > f.MaxIter {
+                        iter = f.MaxIter
+                    }
+                    break
+                }
+            }
+            if !ok {
+                if f.skipCalculation(rx, ry) {
+                    iter = f.MaxIter
+                } else {
+                    iter = f.calcCell(rx, ry, dxc, dyc, dxr, dyr, it)
+                }
+            }
+            if iter == f.MaxIter {
+                f.F[row][col] = -1.0
+            } else {
+                f.F[row][col] = iter
+            }
+            rx += dxc
+            ry += dyc
+        }
+        cx += dxr
+        cy += dyr
+    }
+}
@@ -1,19 +1,11 @@
 package f64
 
 import (
-    "bufio"
-    "errors"
-    "fmt"
     "math"
-    "os"
-    "regexp"
+    "slices"
     "strconv"
-    "strings"
-    . "github.com/stefan-muehlebach/mandel"
-)
 
-const (
-    pathFileName = "path.ini"
+    . "github.com/stefan-muehlebach/mandel"
 )
 
 // Der Datentyp Path definiert eine Kamerafahrt ueber der komplexen
@@ -22,6 +14,17 @@ const (
 // Iterationen bei der Berechnung maximal verwendet werden sollen.
 type f64Path struct {
     viewList []View
+    keyList  []viewKey
+    interp   PathInterp
+    spline   SplineParams
+    PaletteTrack
+}
+
+// viewKey enthaelt die Koordinaten einer Stuetzstelle aus der Szene als
+// Text (mit allenfalls mehr Stellen, als ein float64 aufnehmen kann). Die
+// Dauer der Segmente wird in der [Timeline] verwaltet.
+type viewKey struct {
+    x, y, w string
 }
 
 // Erstellt einen neuen Pfad, der noch keine Ansichten hat.
@@ -31,75 +34,85 @@ func NewPath() *f64Path {
     return p
 }
 
-// Damit lassen sich die Stuetzstellen der Kamerafahrt aus einem File
-// einlesen. Format des Files:
-//
-// Neues Format:
-//
-//    xm0 ym0 w0 it0    (x/y des Mittelpunktes, Breite des Bildes, max Iter)
-//    xm1 ym1 w1 it1
-//    ...
+// Damit lassen sich die Stuetzstellen der Kamerafahrt aus der Szene
+// pathName in path.ini einlesen (siehe [LoadScene] fuer das Format).
 func (p *f64Path) Read(pathName string) (error) {
-    var fd *os.File
-    var scanner *bufio.Scanner
-    var line string
-    var matches []string
-    var err error
-    var x, y, w float64
-    var it int64
-    var regComm, regBlock, regData *regexp.Regexp
-    var inBlock bool
-
-    regComm = regexp.MustCompile(`^ *(#.*)?$`)
-    regBlock = regexp.MustCompile(`^ *\[([[:alnum:]]+)\] *$`)
-    regData = regexp.MustCompile(`^ *([+-]?[0-9]+\.[0-9]+) +([+-]?[0-9]+\.[0-9]+) +([+-]?[0-9]+\.[0-9]+(?:[Ee]-?[0-9]+)?) +([0-9]+) *$`)
-
-    fd, err = OpenConfFile(pathFileName)
+    s, err := LoadScene(pathName)
     if err != nil {
         return err
     }
-    inBlock = false
-    scanner = bufio.NewScanner(fd)
-    for scanner.Scan() {
-        line = scanner.Text()
-        if regComm.MatchString(line) {
-            continue
-        }
-        if inBlock {
-            if regData.MatchString(line) {
-                matches = regData.FindStringSubmatch(line)
-                x, _ = strconv.ParseFloat(matches[1], 64)
-                y, _ = strconv.ParseFloat(matches[2], 64)
-                w, _ = strconv.ParseFloat(matches[3], 64)
-                it, _ = strconv.ParseInt(matches[4], 10, 32)
-                p.AddView(x, y, w, int(it))
-            } else if regBlock.MatchString(line) {
-                break
-            } else {
-                return errors.New(fmt.Sprintf("error on line: %s", line))
-            }
-        } else {
-            if regBlock.MatchString(line) {
-                matches = regBlock.FindStringSubmatch(line)
-                if strings.Compare(matches[1], pathName) == 0 {
-                    inBlock = true
-                }
-            }
+    return p.SetScene(s)
+}
+
+// Ersetzt alle Ansichten durch die Stuetzstellen der Szene s. Die Angaben
+// zur Palette der Szene gelten fuer alle Stuetzstellen, welche keine
+// eigenen Angaben machen. Die Dauer der Segmente wird gemaess
+// [Scene.Durations] berechnet, wobei ein Segment ohne weitere Angaben eine
+// Sekunde dauert.
+func (p *f64Path) SetScene(s *Scene) (error) {
+    p.viewList = p.viewList[:0]
+    p.keyList = p.keyList[:0]
+    p.PaletteTrack = PaletteTrack{}
+    p.SetDefaultKey(s.Palette)
+    p.SetInterp(s.Interp, s.Spline)
+    fixed := make([]bool, max(len(s.Keys)-1, 0))
+    for i, k := range s.Keys {
+        p.AddView(k.Values())
+        p.viewList[i].SetTransform(k.Transform)
+        p.SetPaletteKey(i, k.Palette)
+        p.keyList[i] = viewKey{k.X, k.Y, k.W}
+        if i < len(fixed) {
+            fixed[i] = k.Dur > 0.0
         }
     }
-    if !inBlock {
-        return errors.New(fmt.Sprintf("no path with name '%s' found!", pathName))
+    durs, err := s.Durations(s.Timing, s.Duration, 1.0)
+    if err != nil {
+        return err
     }
-    fd.Close()
+    p.SetDurations(durs)
+    p.SetFixed(fixed)
     return nil
 }
 
+// Legt fest, wie zwischen den Ansichten interpoliert wird (siehe
+// [PathInterp]). sp wird nur bei InterpSpline verwendet.
+func (p *f64Path) SetInterp(interp PathInterp, sp SplineParams) {
+    p.interp, p.spline = interp, sp
+}
+
 // Fuegt der Kamerafahrt eine neue Ansicht oder Stuetzstelle hinzu. Die neue
 // Ansicht wird immer am Ende der bestehenden Kamerafahrt angehaengt.
 func (p *f64Path) AddView(x, y, w float64, it int) {
+    p.InsertView(p.NumViews(), x, y, w, it)
+}
+
+// Fuegt vor der Ansicht i eine neue Ansicht ein (mit i gleich der Anzahl
+// Ansichten am Ende). Die neue Ansicht hat keine eigenen Angaben zur
+// Palette. Wird ein Segment geteilt, so erhalten beide Teile die Haelfte
+// seiner Dauer (siehe [Timeline.InsertKey]).
+func (p *f64Path) InsertView(i int, x, y, w float64, it int) {
     v := NewView()
     v.SetValues(x, y, w, it)
-    p.viewList = append(p.viewList, v)
+    p.viewList = slices.Insert(p.viewList, i, View(v))
+    p.keyList = slices.Insert(p.keyList, i, viewKey{})
+    p.InsertPaletteKey(i, PaletteKey{})
+    p.InsertKey(i)
+}
+
+// Entfernt die Ansicht i. Die beiden Segmente um die Ansicht werden zu
+// einem zusammengefasst (siehe [Timeline.RemoveKey]); eine fest
+// vorgegebene Dauer bleibt nur erhalten, wenn beide Segmente eine haben.
+func (p *f64Path) RemoveView(i int) {
+    p.viewList = slices.Delete(p.viewList, i, i+1)
+    p.keyList = slices.Delete(p.keyList, i, i+1)
+    p.RemovePaletteKey(i)
+    p.RemoveKey(i)
+}
+
+// Ersetzt die Koordinaten und die Anzahl Iterationen der Ansicht i. Die
+// Transformation, die Angaben zur Palette und die Dauer bleiben erhalten.
+func (p *f64Path) SetView(i int, x, y, w float64, it int) {
+    p.viewList[i].SetValues(x, y, w, it)
 }
 
 // Mit NumViews wird die Anzahl der Ansichten in diesem Pfad ermittelt.
@@ -107,68 +120,60 @@ func (p *f64Path) NumViews() int {
     return len(p.viewList)
 }
 
-// Berechnet eine neue View auf dem Pfad zwischen der ersten und der letzten
-// View. v muss eine initialisierte View sein. Der Parameter t ist ein Wert
-// zwischen 0.0 und 1.0 und gibt die Position auf dem Pfad an.
-// func (p *f64Path) GetViewOld(t float64, v *View) {
-//     var x, y float64
-//     var v0, v1 *View
-
-//     if (t == 1.0) || (p.NumViews() == 1) {
-//         i :=  p.NumViews() - 1
-//         v.x = p.viewList[i].x
-//         v.y = p.viewList[i].y
-//         v.w = p.viewList[i].w
-//         v.it = p.viewList[i].it
-//     } else {
-//         // TO DO: das ist noch das extrem komplizierte Interpolationsverfahren,
-//         // welches mit grosser W'keit einfacher implementiert werden koennte
-//         // (siehe Interpolation bei der Palette).
-//         i := int(t * float64(len(p.viewList)-1))
-//         v0 = p.viewList[i]
-//         v1 = p.viewList[i+1]
-//         t = t*float64(len(p.viewList)-1) - float64(i)
-//         tt := 0.5 * (1.0 - math.Cos(t*math.Pi))
-//         fw := math.Pow(v1.w/v0.w, tt)
-//         w := v0.w * fw
-//         it := v0.it + int(tt*float64(v1.it-v0.it))
-//         if v0.w >= v1.w {
-//             k := 1.0 - math.Pow(1.0-math.Pow(1.0-tt, 1.3), 1.0/1.3)
-//             x = v1.x - fw*(v1.x-v0.x)*k
-//             y = v1.y - fw*(v1.y-v0.y)*k
-//         } else {
-//             k := 1.0 - math.Pow(1.0-math.Pow(tt, 1.3), 1.0/1.3)
-//             fw = fw / (v1.w / v0.w)
-//             x = v0.x - fw*(v0.x-v1.x)*k
-//             y = v0.y - fw*(v0.y-v1.y)*k
-//         }
-//         v.x = x
-//         v.y = y
-//         v.w = w
-//         v.it = it
-//     }
-// }
+// Liefert alle Ansichten des Pfades. Die Liste ist eine Kopie, die
+// Ansichten selber jedoch nicht: Aenderungen daran (bspw. mit SetTransform)
+// wirken sich auf den Pfad aus.
+func (p *f64Path) Views() []View {
+    return slices.Clone(p.viewList)
+}
 
-func (p *f64Path) GetView(t float64) (v View) {
-    // var x, y float64
-    // var v0, v1 View
+// Liefert die Stuetzstellen des Pfades, so wie sie in einer Szene abgelegt
+// werden (bspw. um den Pfad mit [Scene.WriteSection] zu speichern). Die
+// Koordinaten werden mit so vielen Stellen geschrieben, dass beim Einlesen
+// wieder genau derselbe Wert entsteht; stammt eine Koordinate unveraendert
+// aus einer Szene, so wird deren Text mit allen Stellen uebernommen.
+func (p *f64Path) SceneKeys() []SceneKey {
+    text := func(s string, v float64) string {
+        if s != "" {
+            if u, err := strconv.ParseFloat(s, 64); err == nil && u == v {
+                return s
+            }
+        }
+        return strconv.FormatFloat(v, 'g', -1, 64)
+    }
+    keys := make([]SceneKey, p.NumViews())
+    for i, view := range p.viewList {
+        x, y, w, it := view.Values()
+        vk := p.keyList[i]
+        keys[i] = SceneKey{
+            X: text(vk.x, x), Y: text(vk.y, y), W: text(vk.w, w),
+            MaxIter:   it,
+            Transform: view.Transform(),
+            Palette:   p.PaletteKey(i),
+        }
+        if i < p.NumViews()-1 {
+            keys[i].Dur = p.FixedDuration(i)
+        }
+    }
+    return keys
+}
 
-    if (t == 1.0) || (p.NumViews() == 1) {
-        i := p.NumViews() - 1
+func (p *f64Path) GetView(t float64) (v View) {
+    if p.interp == InterpSpline && p.NumViews() > 1 {
+        return p.splineView(t)
+    }
+    i, s := p.Locate(t, p.NumViews())
+    if i == p.NumViews()-1 {
         v = p.viewList[i]
     } else {
         v = NewView()
         // TO DO: das ist noch das extrem komplizierte Interpolationsverfahren,
         // welches mit grosser W'keit einfacher implementiert werden koennte
         // (siehe Interpolation bei der Palette).
-        i := int(t * float64(p.NumViews()-1))
-        // v0 = p.viewList[i]
         x0, y0, w0, it0 := p.viewList[i].Values()
-        // v1 = p.viewList[i+1]
         x1, y1, w1, it1 := p.viewList[i+1].Values()
-        
-        t = t*float64(p.NumViews()-1) - float64(i)
-        tt := 0.5 * (1.0 - math.Cos(t*math.Pi))
+
+        tt := 0.5 * (1.0 - math.Cos(s*math.Pi))
         fw := math.Pow(w1/w0, tt)
         w := w0 * fw
         it := it0 + int(tt*float64(it1-it0))
@@ -184,6 +189,51 @@ func (p *f64Path) GetView(t float64) (v View) {
             y = y0 - fw*(y0-y1)*k
         }
         v.SetValues(x, y, w, it)
+        v.SetTransform(p.viewList[i].Transform().Interp(p.viewList[i+1].Transform(), tt))
     }
     return
 }
+
+// splineView berechnet die Ansicht an der Stelle t mit einem Spline durch
+// x, y und log(w) aller Ansichten. Der Mittelpunkt wird dabei an die Breite
+// gekoppelt (siehe SplineParams.EvalPos), damit das Ziel eines tiefen Zooms
+// im Bild bleibt. Die Anzahl Iterationen, die Drehung, die Scherung und
+// (logarithmisch) die Streckung werden mit dem gleichen Spline interpoliert.
+func (p *f64Path) splineView(t float64) View {
+    n := p.NumViews()
+    xs := make([]float64, n)
+    ys := make([]float64, n)
+    lws := make([]float64, n)
+    its := make([]float64, n)
+    rots := make([]float64, n)
+    skews := make([]float64, n)
+    lsts := make([]float64, n)
+    durs := make([]float64, n-1)
+    for i, view := range p.viewList {
+        x, y, w, it := view.Values()
+        xs[i], ys[i], lws[i], its[i] = x, y, math.Log(w), float64(it)
+        tr := view.Transform()
+        rots[i], skews[i] = tr.Rot, tr.Skew
+        if tr.Stretch > 0.0 {
+            lsts[i] = math.Log(tr.Stretch)
+        }
+        if i < n-1 {
+            durs[i] = p.SegmentDuration(i)
+        }
+    }
+
+    i, s := p.Locate(t, n)
+    it := int(math.Round(p.spline.Eval(its, durs, i, s)))
+    if it < 1 {
+        it = 1
+    }
+    v := NewView()
+    v.SetValues(p.spline.EvalPos(xs, lws, durs, i, s), p.spline.EvalPos(ys, lws, durs, i, s),
+            math.Exp(p.spline.Eval(lws, durs, i, s)), it)
+    v.SetTransform(Transform{
+        Rot:     p.spline.Eval(rots, durs, i, s),
+        Skew:    p.spline.Eval(skews, durs, i, s),
+        Stretch: math.Exp(p.spline.Eval(lsts, durs, i, s)),
+    })
+    return v
+}
@@ -1,8 +1,12 @@
 package f64
 
 import (
-	"log"
+    "math"
+    "sort"
+    "strings"
     "testing"
+
+    . "github.com/stefan-muehlebach/mandel"
 )
 
 const (
@@ -10,33 +14,325 @@ const (
 )
 
 var (
-    path *Path
-    view *View
-    view2 View
-    err error
+    // Ein Ausschnitt aus dem Pfad 'GrosseTour' in path.ini.
+    tourViews = [][4]float64{
+        {-1.0, 0.0, 3.5, 100},
+        {-0.745428, 0.113009, 3.0e-5, 1024},
+        {-1.0, 0.0, 3.5, 100},
+        {-0.16, 1.0405, 0.026, 1024},
+        {-1.0, 0.0, 3.5, 100},
+    }
+    view View
 )
 
-func init() {
-    path, err = ReadPath("Default")
-    if err != nil {
-        log.Fatalf("could not read path: %v", err)
+func newTourPath(interp PathInterp) *f64Path {
+    p := NewPath()
+    for _, v := range tourViews {
+        p.AddView(v[0], v[1], v[2], int(v[3]))
+    }
+    p.SetInterp(interp, SplineParams{})
+    return p
+}
+
+func TestSplineKeys(t *testing.T) {
+    p := newTourPath(InterpSpline)
+    n := float64(len(tourViews) - 1)
+    for i, tv := range tourViews {
+        x, y, w, it := p.GetView(float64(i) / n).Values()
+        if math.Abs(x-tv[0]) > 1e-12 || math.Abs(y-tv[1]) > 1e-12 ||
+                math.Abs(w-tv[2]) > 1e-12*tv[2] || it != int(tv[3]) {
+            t.Errorf("view %d: got %v %v %v %d, want %v", i, x, y, w, it, tv)
+        }
+    }
+}
+
+// An den Stuetzstellen muss die Geschwindigkeit (in x, y und log(w)) von
+// links und von rechts gleich sein, auch wenn die Segmente unterschiedlich
+// lange dauern.
+func TestSplineContinuity(t *testing.T) {
+    testSplineContinuity(t, nil)
+    testSplineContinuity(t, []float64{4.0, 1.0, 2.5, 0.5})
+}
+
+func testSplineContinuity(t *testing.T, durs []float64) {
+    const h = 1e-6
+
+    p := newTourPath(InterpSpline)
+    p.SetDurations(durs)
+    n := p.Duration(p.NumViews())
+    tk := 0.0
+    for i := 1; i < len(tourViews)-1; i++ {
+        tk += p.SegmentDuration(i-1) / n
+        x0, y0, w0, _ := p.GetView(tk - h).Values()
+        x1, y1, w1, _ := p.GetView(tk).Values()
+        x2, y2, w2, _ := p.GetView(tk + h).Values()
+        left := []float64{x1 - x0, y1 - y0, math.Log(w1 / w0)}
+        right := []float64{x2 - x1, y2 - y1, math.Log(w2 / w1)}
+        for j := range left {
+            if math.Abs(left[j]-right[j]) > 1e-3*math.Max(math.Abs(left[j]), 10*h) {
+                t.Errorf("durations %v, view %d, coord %d: velocity jumps from %g to %g",
+                        durs, i, j, left[j]/h, right[j]/h)
+            }
+        }
+    }
+}
+
+// Bei einem tiefen Zoom auf einen festen Punkt muss dieser waehrend des
+// ganzen letzten Segmentes im Bild bleiben, auch wenn der Pfad davor
+// schwenkt.
+func TestSplineDeepZoom(t *testing.T) {
+    const x, y = -0.745428000525, 0.11300999994
+
+    p := NewPath()
+    p.AddView(-1.0, 0.0, 3.5, 80)
+    p.AddView(-0.5, 0.0, 3.5, 80)
+    p.AddView(x, y, 1.0e-4, 600)
+    p.AddView(x, y, 5.0e-11, 1200)
+    p.SetInterp(InterpSpline, SplineParams{})
+    for k := 0; k <= 1000; k++ {
+        tv := 2.0/3.0 + float64(k)/3000.0
+        vx, vy, w, _ := p.GetView(tv).Values()
+        if math.Abs(vx-x) > w/2.0 || math.Abs(vy-y) > w/2.0 {
+            t.Fatalf("t = %g: target is %g, %g widths off center", tv,
+                    (x-vx)/w, (y-vy)/w)
+        }
+    }
+}
+
+func TestPathScene(t *testing.T) {
+    s := NewScene("Test")
+    if err := s.SetOption("interp", "spline"); err != nil {
+        t.Fatal(err)
+    }
+    if err := s.SetOption("tension", "0.5"); err != nil {
+        t.Fatal(err)
+    }
+    s.Keys = []SceneKey{{X: "-1.0", Y: "0.0", W: "3.5", MaxIter: 80},
+            {X: "-0.75", Y: "0.1", W: "0.035", MaxIter: 800}}
+    p := NewPath()
+    if err := p.SetScene(s); err != nil {
+        t.Fatal(err)
+    }
+    if p.interp != InterpSpline || p.spline.Tension != 0.5 || p.NumViews() != 2 {
+        t.Errorf("scene not applied: %+v", p)
+    }
+    // Zwischen zwei Ansichten ist log(w) linear, d.h. in der Mitte liegt
+    // das geometrische Mittel.
+    if _, _, w, _ := p.GetView(0.5).Values(); math.Abs(w-0.35) > 1e-9 {
+        t.Errorf("w = %v, want 0.35", w)
     }
-    view = NewView()
 }
 
 func BenchmarkGetView(b *testing.B) {
+    path := newTourPath(InterpEase)
     for i:=0; i<b.N; i++ {
         for t:=0.0; t<=1.0; t+=dt {
-            path.GetView(t, view)
+            view = path.GetView(t)
+        }
+    }
+}
+
+func TestPathEdit(t *testing.T) {
+    data := `[Edit]
+-1.0                     0.0            3.5     80  dur=4 pal=Fire
+-0.74542800052500000001  0.11300999994  5e-11  1200
+-0.5                     0.0            3.5     80  cps=0.5
+`
+    s, err := ReadScene(strings.NewReader(data), "Edit")
+    if err != nil {
+        t.Fatal(err)
+    }
+    p := NewPath()
+    if err := p.SetScene(s); err != nil {
+        t.Fatal(err)
+    }
+    p.SetDurations([]float64{4.0, 2.0})
+
+    p.InsertView(1, -0.75, 0.1, 0.01, 500)
+    if n := p.NumViews(); n != 4 {
+        t.Fatalf("NumViews() = %d, want 4", n)
+    }
+    if x, _, _, it := p.Views()[1].Values(); x != -0.75 || it != 500 {
+        t.Errorf("inserted view: %v, %v", x, it)
+    }
+    for i, want := range []float64{2.0, 2.0, 2.0} {
+        if d := p.SegmentDuration(i); d != want {
+            t.Errorf("SegmentDuration(%d) = %v, want %v", i, d, want)
+        }
+    }
+    if k := p.PaletteKey(1); k.Fields != 0 {
+        t.Errorf("inserted view has palette key %v", k)
+    }
+    if k := p.PaletteKey(3); k.String() != "cps=0.5" {
+        t.Errorf("PaletteKey(3) = %v", k)
+    }
+    keys := p.SceneKeys()
+    if keys[0].Dur != 2.0 || keys[1].Dur != 2.0 || keys[2].Dur != 0.0 {
+        t.Errorf("fixed durations: %v, %v, %v", keys[0].Dur, keys[1].Dur, keys[2].Dur)
+    }
+
+    // Unveraenderte Koordinaten werden mit allen Stellen uebernommen,
+    // geaenderte mit voller float64-Genauigkeit geschrieben.
+    if keys[2].X != "-0.74542800052500000001" || keys[2].W != "5e-11" {
+        t.Errorf("coordinates of view 2: %s %s", keys[2].X, keys[2].W)
+    }
+    p.SetView(2, -0.7454280005250001, 0.11300999994, 5e-11, 1200)
+    if keys := p.SceneKeys(); keys[2].X != "-0.7454280005250001" || keys[2].Y != "0.11300999994" {
+        t.Errorf("coordinates of view 2: %s %s", keys[2].X, keys[2].Y)
+    }
+
+    p.RemoveView(1)
+    if p.NumViews() != 3 || p.SegmentDuration(0) != 4.0 || p.SegmentDuration(1) != 2.0 {
+        t.Errorf("after RemoveView: %d views, durations %v, %v", p.NumViews(),
+                p.SegmentDuration(0), p.SegmentDuration(1))
+    }
+    p.RemoveView(2)
+    p.RemoveView(0)
+    if keys := p.SceneKeys(); len(keys) != 1 || keys[0].Dur != 0.0 || keys[0].MaxIter != 1200 {
+        t.Errorf("after removing both ends: %+v", keys)
+    }
+
+    // Ein veraenderter Pfad kann als Szene gespeichert und wieder gelesen
+    // werden.
+    p = NewPath()
+    p.SetScene(s)
+    p.InsertView(3, -0.16, 1.0405, 0.026, 1024)
+    p.Views()[3].SetTransform(Transform{Rot: 30.0})
+    s.Keys = p.SceneKeys()
+    var buf strings.Builder
+    if err := s.WriteSection(&buf); err != nil {
+        t.Fatal(err)
+    }
+    s1, err := ReadScene(strings.NewReader(buf.String()), "Edit")
+    if err != nil {
+        t.Fatal(err)
+    }
+    q := NewPath()
+    if err := q.SetScene(s1); err != nil {
+        t.Fatal(err)
+    }
+    for _, tv := range []float64{0.0, 0.3, 0.7, 1.0} {
+        x0, y0, w0, it0 := p.GetView(tv).Values()
+        x1, y1, w1, it1 := q.GetView(tv).Values()
+        if x0 != x1 || y0 != y1 || w0 != w1 || it0 != it1 ||
+                p.GetView(tv).Transform() != q.GetView(tv).Transform() {
+            t.Errorf("t=%v: got %v %v %v %v, want %v %v %v %v", tv, x1, y1, w1, it1,
+                    x0, y0, w0, it0)
         }
     }
 }
 
-func BenchmarkGetView2(b *testing.B) {
+func BenchmarkGetViewSpline(b *testing.B) {
+    path := newTourPath(InterpSpline)
     for i:=0; i<b.N; i++ {
         for t:=0.0; t<=1.0; t+=dt {
-            view2 = path.GetView2(t)
+            view = path.GetView(t)
+        }
+    }
+}
+
+func TestPathTransform(t *testing.T) {
+    for _, interp := range []PathInterp{InterpEase, InterpSpline} {
+        p := NewPath()
+        p.AddView(-0.5, 0.0, 3.0, 100)
+        p.AddView(-0.5, 0.0, 3.0, 100)
+        p.viewList[1].SetTransform(Transform{Rot: 720.0})
+        p.SetInterp(interp, SplineParams{})
+        if tr := p.GetView(0.5).Transform(); math.Abs(tr.Rot-360.0) > 1e-9 {
+            t.Errorf("%v: Rot = %v, want 360", interp, tr.Rot)
+        }
+        if tr := p.GetView(1.0).Transform(); tr.Rot != 720.0 {
+            t.Errorf("%v: Rot = %v, want 720", interp, tr.Rot)
+        }
+    }
+}
+
+// Die geschaetzte Anzahl Iterationen muss beim Hineinzoomen zunehmen.
+func TestAutoIter(t *testing.T) {
+    ai := NewAutoIter()
+    newField := func(cols, rows int) Field {
+        return NewField(cols, rows, Samp1x1)
+    }
+    last := 0
+    for _, w := range []float64{3.5, 1e-3, 1e-7, 5e-11} {
+        v := NewView()
+        v.SetValues(-0.745428000525, 0.11300999994, w, 0)
+        it := ai.Estimate(v, 320, 240, newField)
+        if it <= last || it < ai.DepthIter(3.5) {
+            t.Errorf("w=%g: Estimate() = %d (previous: %d)", w, it, last)
         }
+        last = it
+    }
+}
+
+// Die aus der exponentiellen Karte abgetasteten Bilder muessen mit den
+// direkt berechneten uebereinstimmen.
+func TestExpMap(t *testing.T) {
+    p := newZoomPath()
+    m := PlanExpMap(p, 8, zoomCols, zoomRows, Samp1x1, nil)
+    if r := m.Radius(m.Row(1e-3)); math.Abs(r-1e-3) > 1e-15 {
+        t.Errorf("Radius(Row(1e-3)) = %v", r)
+    }
+    strips := NewExpMapStrips(m)
+    for _, tv := range []float64{0.0, 0.5, 0.9} {
+        sampled := NewField(zoomCols, zoomRows, Samp1x1)
+        sampled.CalcExpMap(strips, p.GetView(tv))
+        compareZoomField(t, p, tv, sampled)
+    }
+}
+
+// Dasselbe gilt fuer die Bilder aus Schluesselbildern. Mit Quality 1 darf
+// fuer jede Halbierung der Breite hoechstens ein Schluesselbild noetig sein.
+func TestKeyframes(t *testing.T) {
+    const n = 40
+
+    p := newZoomPath()
+    plan := NewKeyframePlan(1.0)
+    plan.Plan(p, n, zoomCols, zoomRows, nil)
+    if octaves := math.Log2(3.5 / 3.0e-5); len(plan.Keys) > int(octaves)+2 {
+        t.Errorf("%d keyframes for %.1f octaves", len(plan.Keys), octaves)
     }
+    kf := NewKeyframes(plan, Samp1x1)
+    for _, i := range []int{0, 13, 27, 36} {
+        tv := float64(i) / n
+        sampled := NewField(zoomCols, zoomRows, Samp1x1)
+        sampled.CalcKeyframes(kf, i, p.GetView(tv))
+        compareZoomField(t, p, tv, sampled)
+    }
+}
+
+const (
+    zoomCols, zoomRows = 96, 72
+)
+
+func newZoomPath() *f64Path {
+    p := NewPath()
+    p.AddView(-0.745428, 0.113009, 3.5, 200)
+    p.AddView(-0.745428, 0.113009, 3.0e-5, 1000)
+    return p
 }
 
+// Vergleicht das Feld sampled mit dem direkt berechneten Feld an der Stelle
+// tv des Pfades p. Bei feinen Strukturen unterscheiden sich die Werte wegen
+// Aliasing stark, daher wird nur der Median der Abweichungen geprueft.
+func compareZoomField(t *testing.T, p *f64Path, tv float64, sampled *f64Field) {
+    t.Helper()
+    direct := NewField(zoomCols, zoomRows, Samp1x1)
+    direct.CalcMandelbrot(p.GetView(tv))
+    var diffs []float64
+    inside := 0
+    for row := 0; row < zoomRows; row++ {
+        for col := 0; col < zoomCols; col++ {
+            v0, v1 := direct.Value(col, row), sampled.Value(col, row)
+            if (v0 < 0.0) != (v1 < 0.0) {
+                inside++
+            }
+            diffs = append(diffs, math.Abs(v0-v1))
+        }
+    }
+    sort.Float64s(diffs)
+    if med := diffs[len(diffs)/2]; med > 0.25 || inside > zoomCols*zoomRows/10 {
+        t.Errorf("t=%v: median difference %v, %d pixels inside only once",
+                tv, med, inside)
+    }
+}
@@ -1,11 +1,17 @@
 package f64
 
+import (
+    . "github.com/stefan-muehlebach/mandel"
+)
+
 // View ist eine Ansicht der komplexen Zahlenebene mit einem bestimmten
 // Anzeigebereich, einem Mittelpunkt und einer bestimmten Anzahl Iterationen
-// sowie Anzahl Samples.
+// sowie Anzahl Samples. Optional kann die Ansicht gedreht und verzerrt
+// werden (siehe [Transform]).
 type f64View struct {
     x, y, w float64
     it      int
+    tr      Transform
 }
 
 // Erstellt eine neue, leere View ohne definierten Bereich oder Mittelpunkt.
@@ -21,3 +27,13 @@ func (v *f64View) SetValues(x, y, w float64, it int) {
 func (v *f64View) Values() (x, y, w float64, it int) {
     return v.x, v.y, v.w, v.it
 }
+
+// Liefert die Drehung und Verzerrung der View.
+func (v *f64View) Transform() Transform {
+    return v.tr
+}
+
+// Setzt die Drehung und Verzerrung der View.
+func (v *f64View) SetTransform(tr Transform) {
+    v.tr = tr
+}
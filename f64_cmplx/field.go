@@ -25,7 +25,7 @@ import (
     "math/cmplx"
     "os"
     "strings"
-    . "github.com/stefan-muehlebach/mandel"
+    "github.com/stefan-muehlebach/mandel"
 )
 
 const (
@@ -42,7 +42,7 @@ const (
 type Field struct {
     cols, rows int
     maxIter float64
-    pal *Palette
+    pal mandel.Palette
     f [][]float64
 }
 
@@ -64,22 +64,26 @@ func NewField(cols, rows int) (*Field) {
 // Berechnet die Mandelbrot-Menge ueber dem Feld f mit der Ansicht v.
 //
 func (f *Field) CalcMandelbrot(v *View) {
-    var dRe, dIm, reDiff, imDiff, iter, zn, nu float64
+    var d, h, iter, zn, nu float64
     var row, col, it int
-	var z0, z, c complex128
+	var z0, z, c, dCol, dRow complex128
 
     f.maxIter = float64(v.it)
-	
-	dRe = v.w / float64(f.cols)
-	dIm = dRe
-	
-	z0 = v.z - complex(float64(f.cols/2)*dRe, -float64(f.rows/2)*dIm)
-	
-	imDiff = 0.0
+
+    // Die Pixel werden gemaess der Transformation der Ansicht gedreht und
+    // verzerrt; dCol und dRow sind die Schritte zum naechsten Pixel einer
+    // Zeile, resp. zur naechsten Zeile.
+    a, b, cc, e := v.tr.Matrix()
+	d = v.w / float64(f.cols)
+	h = d * float64(f.rows)
+	dCol = complex(a*d, cc*d)
+	dRow = complex(-b*d, -e*d)
+
+	z0 = v.z - complex(a*v.w/2.0-b*h/2.0, cc*v.w/2.0-e*h/2.0)
+
     for row = 0; row < f.rows; row++ {
-        reDiff = 0.0
+        c = z0
 		for col = 0; col < f.cols; col++ {
-			c  = z0 + complex(reDiff, imDiff)
             z  = 0.0 + 0.0i
             it = 0
             for (real(z)*real(z) + imag(z)*imag(z) <= escRad2) && (it < v.it) {
@@ -94,16 +98,16 @@ func (f *Field) CalcMandelbrot(v *View) {
                 iter = -1.0
             }
             f.f[row][col] = iter
-			reDiff += dRe
+			c += dCol
         }
-		imDiff -= dIm
+		z0 += dRow
     }
 }
 
 // Fuegt dem Feld eine Palette hinzu. Eine bereits hinterlegte Palette wird
 // damit ueberschrieben.
 //
-func (f *Field) AddPalette(p *Palette) {
+func (f *Field) AddPalette(p mandel.Palette) {
     f.pal = p
 }
 
@@ -131,6 +135,19 @@ func (f *Field) At(x, y int) (color.Color) {
 	return f.pal.GetColor(f.f[y][x])
 }
 
+// Value liefert den berechneten (geglaetteten) Iterationswert an der Stelle
+// (x,y). Fuer Punkte der Mandelbrot-Menge ist dieser Wert -1.0.
+//
+func (f *Field) Value(x, y int) float64 {
+    return f.f[y][x]
+}
+
+// Retourniert die maximale Anzahl Iterationen der letzten Berechnung.
+//
+func (f *Field) MaxIterations() int {
+    return int(f.maxIter)
+}
+
 //----------------------------------------------------------------------------
 //
 // Diese Methode(n) (Draw und WritePNG) werden eigentlich nicht mehr
@@ -1,14 +1,10 @@
 package f64_cmplx
 
 import (
-	"bufio"
-	"errors"
-    "fmt"
     "math"
     "os"
-	"regexp"
-	"strconv"
-    "strings"
+
+    "github.com/stefan-muehlebach/mandel"
 )
 
 // Path
@@ -30,68 +26,30 @@ func NewPath() (*Path) {
     return p
 }
 
-// Damit lassen sich die Stuetzstellen der Kamerafahrt aus einem File
-// einlesen. Format des Files:
-//
-// Neues Format:
-//
-//     xm0 ym0 w0 it0    (x/y des Mittelpunktes, Breite des Bildes, max Iter)
-//     xm1 ym1 w1 it1
-//     ...
+// Damit lassen sich die Stuetzstellen der Kamerafahrt aus der Szene
+// pathName in der Datei fileName einlesen (siehe [mandel.ReadScene] fuer
+// das Format).
 //
 func (p *Path) ReadFile(fileName, pathName string) (error) {
     var fd *os.File
-	var scanner *bufio.Scanner
-	var line string
-	var matches []string
+    var s *mandel.Scene
     var err error
-    var x, y, w float64
-    var it int64
-	var regComm, regBlock, regData *regexp.Regexp
-	var inBlock bool
-	
-	regComm  = regexp.MustCompile(`^ *(#.*)?$`)
-	regBlock = regexp.MustCompile(`^ *\[([[:alnum:]]+)\] *$`)
-	regData  = regexp.MustCompile(`^ *([+-]?[0-9]+\.[0-9]+) +([+-]?[0-9]+\.[0-9]+) +([+-]?[0-9]+\.[0-9]+(?:[Ee]-?[0-9]+)?) +([0-9]+) *$`)
 
     fd, err = os.Open(fileName)
     if err != nil {
         return err
     }
-	inBlock = false
-	scanner = bufio.NewScanner(fd)
-	for scanner.Scan() {
-		line = scanner.Text()
-		if regComm.MatchString(line) {
-			continue
-		}
-		if inBlock {
-			if regData.MatchString(line) {
-				matches = regData.FindStringSubmatch(line)
-				x, _  = strconv.ParseFloat(matches[1], 64)
-				y, _  = strconv.ParseFloat(matches[2], 64)
-				w, _  = strconv.ParseFloat(matches[3], 64)
-				it, _ = strconv.ParseInt(matches[4], 10, 32)
-				p.AddView(complex(x, y), w, int(it))
-			} else if regBlock.MatchString(line) {
-				break
-			} else {
-				return errors.New(fmt.Sprintf("error on line: %s", line))
-			}
-		} else {
-			if regBlock.MatchString(line) {
-				matches = regBlock.FindStringSubmatch(line)
-				if strings.Compare(matches[1], pathName) == 0 {
-					inBlock = true
-				}
-			}
-		}
-	}
-	if ! inBlock {
-		return errors.New(fmt.Sprintf("no path with name '%s' found!", pathName))
-	}
-    fd.Close()
-	return nil
+    defer fd.Close()
+    s, err = mandel.ReadScene(fd, pathName)
+    if err != nil {
+        return err
+    }
+    for _, k := range s.Keys {
+        x, y, w, it := k.Values()
+        p.AddView(complex(x, y), w, it)
+        p.viewList[len(p.viewList)-1].tr = k.Transform
+    }
+    return nil
 }
 
 // Fuegt der Kamerafahrt eine neue Ansicht oder Stuetzstelle hinzu. Die neue
@@ -100,7 +58,7 @@ func (p *Path) ReadFile(fileName, pathName string) (error) {
 func (p *Path) AddView(z complex128, w float64, it int) {
 	var v *View
 	
-	v = &View{z, w, it}
+	v = &View{z: z, w: w, it: it}
 	p.viewList = append(p.viewList, v)
 }
 
@@ -121,6 +79,7 @@ func (p *Path) GetView(t float64, v *View) {
 		v.z = p.viewList[i].z
 		v.w = p.viewList[i].w
 		v.it = p.viewList[i].it
+		v.tr = p.viewList[i].tr
 	} else {
 		i := int(t * float64(len(p.viewList) - 1))
 		v0  = p.viewList[i]
@@ -141,5 +100,6 @@ func (p *Path) GetView(t float64, v *View) {
 		v.z = z
 		v.w = w
 		v.it = it
+		v.tr = v0.tr.Interp(v1.tr, tt)
 	}
 }
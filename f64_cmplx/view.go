@@ -1,12 +1,19 @@
 package f64_cmplx
 
+import (
+    "github.com/stefan-muehlebach/mandel"
+)
+
 // View ist eine Ansicht der komplexen Zahlenebene mit einem bestimmten
 // Anzeigebereich, einem Mittelpunkt und einer bestimmten Anzahl Iterationen.
+// Mit tr kann die Ansicht gedreht und verzerrt werden (siehe
+// [mandel.Transform]).
 //
 type View struct {
 	z complex128
     w float64
     it int
+    tr mandel.Transform
 }
 
 // Erstellt eine neue, leere View ohne definierten Bereich oder Mittelpunkt.
@@ -24,3 +31,15 @@ func (v *View) SetValues(z complex128, w float64, it int) {
 func (v *View) GetValues() (z complex128, w float64, it int) {
     return v.z, v.w, v.it
 }
+
+// Liefert die Drehung und Verzerrung der Ansicht.
+//
+func (v *View) Transform() mandel.Transform {
+    return v.tr
+}
+
+// Setzt die Drehung und Verzerrung der Ansicht.
+//
+func (v *View) SetTransform(tr mandel.Transform) {
+    v.tr = tr
+}
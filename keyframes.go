@@ -0,0 +1,108 @@
+package mandel
+
+import (
+	"math"
+)
+
+// Keyframe ist ein Schluesselbild: ein achsenparalleler Ausschnitt mit
+// Mittelpunkt (X, Y), Breite W und Hoehe H, welcher mit Cols x Rows Pixeln
+// und MaxIter Iterationen vollstaendig berechnet wird.
+type Keyframe struct {
+	X, Y, W, H float64
+	Cols, Rows int
+	MaxIter    int
+}
+
+// PixelSize liefert die Groesse eines Pixels in der komplexen Ebene.
+func (k *Keyframe) PixelSize() float64 {
+	return k.W / float64(k.Cols)
+}
+
+// KeyframePlan legt fest, welche Schluesselbilder fuer die Bilder eines
+// Pfades berechnet werden und welches Schluesselbild fuer welches Bild
+// zustaendig ist.
+//
+//	Step     Zoom-Faktor zwischen zwei Schluesselbildern
+//	Border   Zusaetzlicher Rand der Schluesselbilder (relativ zur Breite
+//	         resp. Hoehe auf jeder Seite), damit auch bei Verschiebungen
+//	         und Drehungen das ganze Bild abgedeckt bleibt
+//	Quality  Wie stark die Pixel eines Schluesselbildes hoechstens
+//	         vergroessert werden duerfen. Mit 1 sind die Schluesselbilder
+//	         so fein, dass kein Bild unschaerfer wird als bei der direkten
+//	         Berechnung; mit Step werden sie mit der Aufloesung der Bilder
+//	         berechnet, dafuer werden die Bilder bis zum Faktor Step
+//	         unschaerfer.
+type KeyframePlan struct {
+	Step, Border, Quality float64
+	Keys                  []Keyframe
+	frameKey              []int
+}
+
+// NewKeyframePlan erstellt einen (leeren) Plan mit der Qualitaet quality.
+func NewKeyframePlan(quality float64) *KeyframePlan {
+	return &KeyframePlan{Step: 2.0, Border: 0.1, Quality: quality}
+}
+
+// Plan bestimmt die Schluesselbilder fuer die n+1 gleichmaessig verteilten
+// Bilder (t = i/n) des Pfades p mit cols x rows Pixeln. Ein neues
+// Schluesselbild wird angelegt, sobald das aktuelle ein Bild nicht mehr
+// vollstaendig abdeckt oder seine Pixel mehr als Quality vergroessert
+// wuerden. iter liefert die Anzahl Iterationen der Ansicht an der Stelle t
+// (bspw. aus einer [IterCurve]); ist iter nil, werden die Werte des Pfades
+// verwendet.
+func (kp *KeyframePlan) Plan(p Path, n, cols, rows int, iter func(t float64) int) {
+	kp.Keys = kp.Keys[:0]
+	kp.frameKey = make([]int, n+1)
+	its := make([]int, n+1)
+	for i := range kp.frameKey {
+		t := 0.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+		v := p.GetView(t)
+		_, _, w, it := v.Values()
+		if iter != nil {
+			it = iter(t)
+		}
+		its[i] = it
+		pix := w / float64(cols)
+
+		x0, y0 := math.Inf(1), math.Inf(1)
+		x1, y1 := math.Inf(-1), math.Inf(-1)
+		for _, c := range viewCorners(v, cols, rows) {
+			x0, y0 = math.Min(x0, c[0]), math.Min(y0, c[1])
+			x1, y1 = math.Max(x1, c[0]), math.Max(y1, c[1])
+		}
+		if len(kp.Keys) > 0 {
+			k := &kp.Keys[len(kp.Keys)-1]
+			if k.PixelSize() <= kp.Quality*pix*(1.0+1e-9) &&
+				x0 >= k.X-k.W/2.0 && x1 <= k.X+k.W/2.0 &&
+				y0 >= k.Y-k.H/2.0 && y1 <= k.Y+k.H/2.0 {
+				kp.frameKey[i] = len(kp.Keys) - 1
+				continue
+			}
+		}
+
+		kpix := pix * kp.Quality / kp.Step
+		k := Keyframe{X: (x0 + x1) / 2.0, Y: (y0 + y1) / 2.0}
+		k.Cols = int(math.Ceil((x1 - x0) * (1.0 + 2.0*kp.Border) / kpix))
+		k.Rows = int(math.Ceil((y1 - y0) * (1.0 + 2.0*kp.Border) / kpix))
+		k.W, k.H = float64(k.Cols)*kpix, float64(k.Rows)*kpix
+		kp.Keys = append(kp.Keys, k)
+		kp.frameKey[i] = len(kp.Keys) - 1
+	}
+
+	// Ein Schluesselbild wird auch von den Bildern des vorangehenden und des
+	// nachfolgenden Schluesselbildes verwendet.
+	for i, k := range kp.frameKey {
+		for j := max(k-1, 0); j <= min(k+1, len(kp.Keys)-1); j++ {
+			kp.Keys[j].MaxIter = max(kp.Keys[j].MaxIter, its[i])
+		}
+	}
+}
+
+// FrameKey liefert den Index des Schluesselbildes, welches fuer das Bild i
+// zustaendig ist.
+func (kp *KeyframePlan) FrameKey(i int) int {
+	return kp.frameKey[min(max(i, 0), len(kp.frameKey)-1)]
+}
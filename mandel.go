@@ -7,26 +7,42 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 )
 
 var (
-	// confDir ist der Name des Verzeichnisses, in welchem alle Konfigurations
-	// Dateien erwartet werden. Dies ist aktuell fest eingestellt und zeigt
-	// auf '~/.config/mandel'
-	confDir = filepath.Join(".config", "mandel")
+	// confDir ist der Name des Unterverzeichnisses von '~/.config' (resp.
+	// $XDG_CONFIG_HOME), in welchem die Konfigurationsdateien des Benutzers
+	// erwartet werden. Weitere Verzeichnisse sind in [ConfDirs] beschrieben.
+	confDir = "mandel"
 )
 
+// ConfFilePath liefert den absoluten Pfad der Konfigurationsdatei fileName.
+// Existiert die Datei in mehreren Verzeichnissen des Suchpfades (siehe
+// [ConfDirs]), so wird diejenige mit der hoechsten Prioritaet verwendet.
+// Existiert sie in keinem, so wird der Pfad geliefert, unter welchem sie
+// neu angelegt werden soll.
+func ConfFilePath(fileName string) (string, error) {
+	if files := ConfFiles(fileName); len(files) > 0 {
+		return files[len(files)-1], nil
+	}
+	dir, err := defConfDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
 // OpenConfFile ist eine interne Funktion, mit welcher Konfigurationsdateien
-// im Verzeichnis [confDir] standardisiert angesprochen werden koennen.
+// standardisiert angesprochen werden koennen (siehe [ConfFilePath]).
 func OpenConfFile(fileName string) (*os.File, error) {
-	homeDir, err := os.UserHomeDir()
+	absPath, err := ConfFilePath(fileName)
 	if err != nil {
 		return nil, err
 	}
-	absPath := filepath.Join(homeDir, confDir, fileName)
 	fh, err := os.Open(absPath)
 	return fh, err
 }
@@ -34,13 +50,23 @@ func OpenConfFile(fileName string) (*os.File, error) {
 type View interface {
 	Values() (x, y, w float64, maxIt int)
 	SetValues(x, y, w float64, maxIt int)
+	Transform() Transform
+	SetTransform(tr Transform)
 }
 
 type Path interface {
 	Read(pathName string) error
+	SetScene(s *Scene) error
+	SetDurations(durs []float64)
 	AddView(x, y, w float64, maxIt int)
+	InsertView(i int, x, y, w float64, maxIt int)
+	RemoveView(i int)
+	SetView(i int, x, y, w float64, maxIt int)
 	NumViews() int
+	Views() []View
+	SceneKeys() []SceneKey
 	GetView(t float64) View
+	GetPalette(t float64) PaletteFrame
 }
 
 type Field interface {
@@ -49,9 +75,13 @@ type Field interface {
 	AdjPalette()
 	Write(fileName string) error
 	Read(fileName string) error
+	SetColorModel(m color.Model)
 	ColorModel() color.Model
 	Bounds() image.Rectangle
 	At(x, y int) color.Color
+
+	Value(x, y int) float64
+	MaxIterations() int
 }
 
 type Palette interface {
@@ -63,9 +93,15 @@ type Palette interface {
 	SetOffset(offset float64)
 	Offset() float64
 	GetColor(f float64) color.RGBA
+	GetColorF(f float64) ColorF
+	SetTransfer(tf Transfer)
+	Transfer() Transfer
+	BlendTransfer(tf Transfer, mix float64)
 
 	GetRegexp() *regexp.Regexp
 	ProcessLine(line string) error
+	SetOption(key, value string) error
+	WriteSection(w io.Writer, name string) error
 	Update()
 	Ready() bool
 }
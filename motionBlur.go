@@ -0,0 +1,252 @@
+package mandel
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// BlurMode gibt an, ob und wie die Bilder einer Animation mit
+// Bewegungsunschaerfe versehen werden.
+//
+//	off      Keine Bewegungsunschaerfe.
+//	samples  Pro Bild werden mehrere Ansichten innerhalb der Belichtungszeit
+//	         (siehe [ShutterTimes]) berechnet, eingefaerbt und in linearem
+//	         RGB gemittelt. Exakt, aber entsprechend teuer.
+//	flow     Nur das Bild selber wird berechnet. Fuer die uebrigen Ansichten
+//	         innerhalb der Belichtungszeit wird es gemaess der Bewegung der
+//	         Kamera verschoben, gedreht und skaliert (siehe [FlowBlur]).
+//	         Neue Details werden dabei keine sichtbar.
+type BlurMode int
+
+const (
+	BlurOff BlurMode = iota
+	BlurSamples
+	BlurFlow
+)
+
+func (bm BlurMode) String() string {
+	switch bm {
+	case BlurOff:
+		return "off"
+	case BlurSamples:
+		return "samples"
+	case BlurFlow:
+		return "flow"
+	default:
+		return "Unknown blur mode"
+	}
+}
+
+func (bm *BlurMode) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "off":
+		*bm = BlurOff
+	case "samples":
+		*bm = BlurSamples
+	case "flow":
+		*bm = BlurFlow
+	default:
+		return errors.New("Unknown blur mode: " + s)
+	}
+	return nil
+}
+
+// ShutterTimes liefert die n Zeitpunkte, an welchen das Bild an der Stelle
+// t eines Pfades abgetastet wird. dt ist der Abstand zweier Bilder, shutter
+// der Verschlusswinkel in Grad: bei 360 Grad ist der Verschluss waehrend
+// der ganzen Zeit dt offen, bei 180 Grad (Standard beim Film) waehrend der
+// Haelfte. Die Zeitpunkte liegen symmetrisch um t und werden auf [0,1]
+// beschraenkt.
+func ShutterTimes(t, dt, shutter float64, n int) []float64 {
+	ts := make([]float64, n)
+	for j := range ts {
+		s := (float64(j)+0.5)/float64(n) - 0.5
+		ts[j] = math.Min(math.Max(t+s*dt*shutter/360.0, 0.0), 1.0)
+	}
+	return ts
+}
+
+// linearImage enthaelt die Pixel eines Bildes in linearem RGB, mit A
+// vormultipliziert. Nur in diesem Farbraum entspricht das Mitteln von
+// Farben dem Mischen von Licht.
+type linearImage struct {
+	rect image.Rectangle
+	pix  [][4]float64
+}
+
+func newLinearImage(rect image.Rectangle) *linearImage {
+	return &linearImage{rect, make([][4]float64, rect.Dx()*rect.Dy())}
+}
+
+// toLinear konvertiert das Bild img nach linearem RGB.
+func toLinear(img image.Image) *linearImage {
+	rect := img.Bounds()
+	li := newLinearImage(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			c := ColorFOf(img.At(x, y))
+			l := srgbToLinear([3]float64{c.R, c.G, c.B})
+			li.pix[li.index(x, y)] = [4]float64{l[0] * c.A, l[1] * c.A, l[2] * c.A, c.A}
+		}
+	}
+	return li
+}
+
+func (li *linearImage) index(x, y int) int {
+	return (y-li.rect.Min.Y)*li.rect.Dx() + (x - li.rect.Min.X)
+}
+
+// at interpoliert bilinear zwischen den Pixeln um (x, y). Liegt (x, y)
+// ausserhalb des Bildes, so ist ok false.
+func (li *linearImage) at(x, y float64) (c [4]float64, ok bool) {
+	if x < float64(li.rect.Min.X) || x > float64(li.rect.Max.X-1) ||
+		y < float64(li.rect.Min.Y) || y > float64(li.rect.Max.Y-1) {
+		return c, false
+	}
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, li.rect.Max.X-1), min(y0+1, li.rect.Max.Y-1)
+	fx, fy := x-float64(x0), y-float64(y0)
+	c00, c01 := li.pix[li.index(x0, y0)], li.pix[li.index(x1, y0)]
+	c10, c11 := li.pix[li.index(x0, y1)], li.pix[li.index(x1, y1)]
+	for i := range c {
+		c[i] = (1.0-fy)*((1.0-fx)*c00[i]+fx*c01[i]) + fy*((1.0-fx)*c10[i]+fx*c11[i])
+	}
+	return c, true
+}
+
+// image erstellt aus den mit scale multiplizierten Pixeln ein Bild mit dem
+// Farbmodell model (color.RGBAModel oder color.RGBA64Model).
+func (li *linearImage) image(model color.Model, scale float64) image.Image {
+	var img interface {
+		image.Image
+		Set(x, y int, c color.Color)
+	}
+	if model == color.RGBA64Model {
+		img = image.NewRGBA64(li.rect)
+	} else {
+		img = image.NewRGBA(li.rect)
+	}
+	for y := li.rect.Min.Y; y < li.rect.Max.Y; y++ {
+		for x := li.rect.Min.X; x < li.rect.Max.X; x++ {
+			p := li.pix[li.index(x, y)]
+			a := p[3] * scale
+			if a <= 0.0 {
+				continue
+			}
+			c := linearToSRGB([3]float64{p[0] * scale / a, p[1] * scale / a, p[2] * scale / a})
+			img.Set(x, y, ColorF{c[0], c[1], c[2], math.Min(a, 1.0)})
+		}
+	}
+	return img
+}
+
+// LinearAccum mittelt mehrere Bilder in linearem RGB.
+type LinearAccum struct {
+	sum    *linearImage
+	weight float64
+}
+
+// NewLinearAccum erstellt einen leeren Speicher fuer Bilder der Groesse
+// rect.
+func NewLinearAccum(rect image.Rectangle) *LinearAccum {
+	return &LinearAccum{sum: newLinearImage(rect)}
+}
+
+// Add addiert das Bild img mit dem Gewicht weight.
+func (a *LinearAccum) Add(img image.Image, weight float64) {
+	li := toLinear(img)
+	for i, p := range li.pix {
+		for j := range p {
+			a.sum.pix[i][j] += weight * p[j]
+		}
+	}
+	a.weight += weight
+}
+
+// Image liefert das gewichtete Mittel aller addierten Bilder mit dem
+// Farbmodell model.
+func (a *LinearAccum) Image(model color.Model) image.Image {
+	if a.weight == 0.0 {
+		return a.sum.image(model, 0.0)
+	}
+	return a.sum.image(model, 1.0/a.weight)
+}
+
+// pixelMap bildet die Pixel einer Ansicht auf die komplexe Ebene ab (und
+// umgekehrt), mit derselben Geometrie wie bei der Berechnung der Felder.
+type pixelMap struct {
+	x0, y0             float64
+	dxc, dyc, dxr, dyr float64
+}
+
+func newPixelMap(v View, cols, rows int) pixelMap {
+	x, y, w, _ := v.Values()
+	a, b, c, d := v.Transform().Matrix()
+	pix := w / float64(cols)
+	h := pix * float64(rows)
+	return pixelMap{
+		x0: x - a*w/2.0 + b*h/2.0, y0: y - c*w/2.0 + d*h/2.0,
+		dxc: a * pix, dyc: c * pix, dxr: -b * pix, dyr: -d * pix,
+	}
+}
+
+// point liefert den Punkt der komplexen Ebene beim Pixel (col, row).
+func (m pixelMap) point(col, row float64) (x, y float64) {
+	return m.x0 + col*m.dxc + row*m.dxr, m.y0 + col*m.dyc + row*m.dyr
+}
+
+// pixel ist die Umkehrung von point.
+func (m pixelMap) pixel(x, y float64) (col, row float64) {
+	x, y = x-m.x0, y-m.y0
+	det := m.dxc*m.dyr - m.dxr*m.dyc
+	return (x*m.dyr - y*m.dxr) / det, (y*m.dxc - x*m.dyc) / det
+}
+
+// FlowBlur erzeugt Bewegungsunschaerfe fuer das mit der Ansicht v erstellte
+// Bild img. Fuer jede Ansicht in views wird bestimmt, wo die Punkte jedes
+// Pixels in img zu liegen kommen (der optische Fluss ergibt sich damit
+// direkt aus der Bewegung der Kamera); die so verschobenen Bilder werden in
+// linearem RGB gemittelt. Punkte ausserhalb von img werden dabei
+// weggelassen. Das Resultat hat dasselbe Farbmodell wie img.
+func FlowBlur(img image.Image, v View, views []View) image.Image {
+	if len(views) == 0 {
+		return img
+	}
+	rect := img.Bounds()
+	cols, rows := rect.Dx(), rect.Dy()
+	src := toLinear(img)
+	dst := newLinearImage(rect)
+	m := newPixelMap(v, cols, rows)
+	ms := make([]pixelMap, len(views))
+	for j, vs := range views {
+		ms[j] = newPixelMap(vs, cols, rows)
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			i := dst.index(col+rect.Min.X, row+rect.Min.Y)
+			n := 0
+			for _, mj := range ms {
+				u, w := m.pixel(mj.point(float64(col), float64(row)))
+				c, ok := src.at(u+float64(rect.Min.X), w+float64(rect.Min.Y))
+				if !ok {
+					continue
+				}
+				for k := range c {
+					dst.pix[i][k] += c[k]
+				}
+				n++
+			}
+			if n == 0 {
+				dst.pix[i] = src.pix[i]
+				continue
+			}
+			for k := range dst.pix[i] {
+				dst.pix[i][k] /= float64(n)
+			}
+		}
+	}
+	return dst.image(img.ColorModel(), 1.0)
+}
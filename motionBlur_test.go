@@ -0,0 +1,68 @@
+package mandel
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestShutterTimes(t *testing.T) {
+	ts := ShutterTimes(0.5, 0.1, 180.0, 4)
+	want := []float64{0.48125, 0.49375, 0.50625, 0.51875}
+	for i := range want {
+		if math.Abs(ts[i]-want[i]) > 1e-12 {
+			t.Errorf("ShutterTimes() = %v, want %v", ts, want)
+			break
+		}
+	}
+	if ts := ShutterTimes(0.0, 0.1, 360.0, 2); ts[0] != 0.0 {
+		t.Errorf("ShutterTimes() = %v, must not be negative", ts)
+	}
+}
+
+// Schwarz und Weiss ergeben gemittelt nicht 50% Grau (sRGB 0x80), sondern
+// 50% Licht (sRGB 0xbc).
+func TestLinearAccum(t *testing.T) {
+	rect := image.Rect(0, 0, 2, 1)
+	black, white := image.NewRGBA(rect), image.NewRGBA(rect)
+	for x := 0; x < 2; x++ {
+		black.Set(x, 0, color.Black)
+		white.Set(x, 0, color.White)
+	}
+	accum := NewLinearAccum(rect)
+	accum.Add(black, 1.0)
+	accum.Add(white, 1.0)
+	img := accum.Image(color.RGBAModel).(*image.RGBA)
+	if c := img.RGBAAt(1, 0); c.R != 0xbc || c.G != 0xbc || c.B != 0xbc || c.A != 0xff {
+		t.Errorf("average of black and white = %v", c)
+	}
+}
+
+func TestFlowBlur(t *testing.T) {
+	rect := image.Rect(0, 0, 8, 6)
+	img := image.NewRGBA(rect)
+	img.Set(4, 3, color.White)
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			if x != 4 || y != 3 {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	v := &autoView{x: 0.0, y: 0.0, w: 8.0}
+
+	// Ohne Bewegung bleibt das Bild unveraendert.
+	same := FlowBlur(img, v, []View{v, v}).(*image.RGBA)
+	if c := same.RGBAAt(4, 3); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("pixel without motion = %v", c)
+	}
+
+	// Bewegt sich die Kamera um ein Pixel nach rechts, so wird das helle
+	// Pixel auf zwei Pixel verteilt.
+	right := &autoView{x: 1.0, y: 0.0, w: 8.0}
+	blurred := FlowBlur(img, v, []View{v, right}).(*image.RGBA)
+	if c0, c1 := blurred.RGBAAt(4, 3), blurred.RGBAAt(3, 3); c0.R != 0xbc || c1.R != 0xbc {
+		t.Errorf("blurred pixels = %v, %v", c0, c1)
+	}
+}
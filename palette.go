@@ -2,28 +2,40 @@ package mandel
 
 import (
     "bufio"
-    _ "errors"
+    "errors"
     "fmt"
     "image/color"
+    "io"
     _ "log"
     "math"
-    "os"
     "regexp"
+    "sort"
+    "strconv"
     "strings"
 )
 
 const (
-    // palSize ist die Groesse der Farbpalette (Anzahl RGBA-Werte). Je groesser
-    // dieser Wert, desto feiner die Aufteilung der Farben. Werte ueber 1000
-    // machen jedoch in meinen Augen keinen Sinn mehr.
+    // palSize ist die Standardgroesse der Farbtabelle (Anzahl Farben). Je
+    // groesser dieser Wert, desto feiner die Aufteilung der Farben. Da die
+    // Farben in der Tabelle als Fliesskommazahlen abgelegt und zwischen den
+    // Eintraegen interpoliert werden, reicht dieser Wert auch fuer Bilder
+    // mit 16 Bit pro Farbkanal. Pro Palette kann die Groesse mit der Option
+    // 'size' (resp. mit SetTableSize) veraendert werden.
     palSize = 512
 
+    // maxTableSize ist die maximale Groesse der Farbtabelle.
+    maxTableSize = 1 << 16
+
     // palFileName ist der Name der Datei, welche alle Farbpaletten enthaelt.
     // Diese Datei muss im aktuellen Verzeichnis zu finden sein.
     palFileName = "palette.ini"
 )
 
 var (
+    // defInsideColor ist die Farbe fuer das Innere der Mandelbrotmenge,
+    // falls bei einer Palette nichts anderes angegeben wird.
+    defInsideColor = ColorF{0.0, 0.0, 0.0, 1.0}
+
     // regxComm ist der regulaere Ausdruck, welche beim Lesen der
     // Konfigurationsdatei mit den Paletten fuer leere Zeilen und
     // ommentarzeilen
@@ -32,11 +44,63 @@ var (
     regxComm = regexp.MustCompile(`^ *(#.*)?$`)
 
     // regxSection dagegen ist der regulaere Ausdruck fuer die Erkennung der
-    // Abschnitts-Titel der einzelnen Paletten
+    // Abschnitts-Titel der einzelnen Paletten. Mit '.' getrennte Namen
+    // bezeichnen Unterabschnitte (bspw. die Paletten einer Szene, siehe
+    // [Scene]).
     //
-    regxSection = regexp.MustCompile(`^ *\[([[:alnum:]]+)\] *$`)
+    regxSection = regexp.MustCompile(`^ *\[([[:alnum:]]+(?:\.[[:alnum:]]+)*)\] *$`)
+
+    // regxOption erkennt Zeilen der Form 'schluessel = wert', mit welchen
+    // Optionen einer Palette (z.B. der Farbraum fuer die Interpolation)
+    // gesetzt werden koennen.
+    //
+    regxOption = regexp.MustCompile(`^ *([[:alpha:]][[:alnum:]]*) *= *(.*?) *$`)
 )
 
+// ErrUnknownOption wird von [Palette.SetOption] (eingepackt) retourniert,
+// falls die Palette die Option nicht kennt.
+var ErrUnknownOption = errors.New("unknown option")
+
+// paletteType beschreibt eine Implementation von [Palette]. Der Typ einer
+// Palette in palette.ini wird anhand der ersten Datenzeile bestimmt, welche
+// von genau einem der regulaeren Ausdruecke erkannt werden muss. Der Name
+// wird im Feld 'type' der JSON-Darstellung verwendet.
+type paletteType struct {
+    name       string
+    regexp     *regexp.Regexp
+    newPalette func() Palette
+}
+
+var paletteTypes = []*paletteType{
+    {gradientJSONType, gradientPalRegexp, func() Palette { return NewGradientPalette() }},
+    {procJSONType, procPalRegexp, func() Palette { return NewProcPalette() }},
+    {hsvJSONType, hsvPalRegexp, func() Palette { return NewHSVPalette() }},
+    {mapJSONType, mapPalRegexp, func() Palette { return NewMapPalette() }},
+    {cubehelixJSONType, cubehelixPalRegexp, func() Palette { return NewCubehelixPalette() }},
+    {fourierJSONType, fourierPalRegexp, func() Palette { return NewFourierPalette() }},
+}
+
+// paletteTypeOf liefert den Paletten-Typ, dessen regulaerer Ausdruck die
+// Zeile line erkennt, oder nil.
+func paletteTypeOf(line string) *paletteType {
+    for _, pt := range paletteTypes {
+        if pt.regexp.MatchString(line) {
+            return pt
+        }
+    }
+    return nil
+}
+
+// paletteTypeByName liefert den Paletten-Typ mit dem Namen name oder nil.
+func paletteTypeByName(name string) *paletteType {
+    for _, pt := range paletteTypes {
+        if pt.name == name {
+            return pt
+        }
+    }
+    return nil
+}
+
 //-----------------------------------------------------------------------------
 
 type BaseColorType int
@@ -48,6 +112,15 @@ const (
     NumBaseColors
 )
 
+// Neben den drei Grundfarben kann eine Palette optional einen Alpha-Kanal
+// (Deckkraft) besitzen. Alpha folgt direkt auf die Grundfarben, so dass
+// Listen, welche nach BaseColorType indexiert sind, einfach um einen
+// Eintrag verlaengert werden koennen.
+const (
+    Alpha       BaseColorType = NumBaseColors
+    NumChannels               = NumBaseColors + 1
+)
+
 func (c BaseColorType) String() string {
     switch c {
     case Red:
@@ -56,6 +129,8 @@ func (c BaseColorType) String() string {
         return "Green"
     case Blue:
         return "Blue"
+    case Alpha:
+        return "Alpha"
     default:
         return "(Unknown color)"
     }
@@ -63,31 +138,74 @@ func (c BaseColorType) String() string {
 
 //-----------------------------------------------------------------------------
 
-// ColorList ist ein Hilfstyp (nicht exportiert), mit dem ein Slice von
-// Farben verwaltet werden kann.
-type ColorList []color.RGBA
+// ColorF ist eine Farbe, deren Kanaele als Fliesskommazahlen in [0,1]
+// abgelegt sind. Die Farbkanaele sind nicht mit A vormultipliziert. ColorF
+// implementiert das Interface [color.Color].
+type ColorF struct {
+    R, G, B, A float64
+}
+
+// ColorFOf konvertiert eine beliebige Farbe nach ColorF.
+func ColorFOf(c color.Color) ColorF {
+    if cf, ok := c.(ColorF); ok {
+        return cf
+    }
+    r, g, b, a := c.RGBA()
+    if a == 0 {
+        return ColorF{}
+    }
+    fa := float64(a)
+    return ColorF{float64(r) / fa, float64(g) / fa, float64(b) / fa, fa / 0xffff}
+}
+
+func (c ColorF) RGBA() (r, g, b, a uint32) {
+    c64 := c.RGBA64()
+    return uint32(c64.R), uint32(c64.G), uint32(c64.B), uint32(c64.A)
+}
+
+// RGBA8 liefert die Farbe mit 8 Bit pro Kanal (vormultipliziert).
+func (c ColorF) RGBA8() color.RGBA {
+    return color.RGBA{quantize8(c.R * c.A), quantize8(c.G * c.A),
+            quantize8(c.B * c.A), quantize8(c.A)}
+}
+
+// RGBA64 liefert die Farbe mit 16 Bit pro Kanal (vormultipliziert).
+func (c ColorF) RGBA64() color.RGBA64 {
+    return color.RGBA64{quantize16(c.R * c.A), quantize16(c.G * c.A),
+            quantize16(c.B * c.A), quantize16(c.A)}
+}
+
+// Interp interpoliert linear zwischen den Farben c und d.
+func (c ColorF) Interp(d ColorF, t float64) ColorF {
+    t0 := 1.0 - t
+    return ColorF{t0*c.R + t*d.R, t0*c.G + t*d.G, t0*c.B + t*d.B, t0*c.A + t*d.A}
+}
+
+// quantize8 und quantize16 runden v in [0,1] auf eine Ganzzahl mit 8 resp.
+// 16 Bit.
+func quantize8(v float64) uint8 {
+    return uint8(math.Max(0.0, math.Min(1.0, v))*0xff + 0.5)
+}
+
+// exact8 prueft, ob v einem Wert mit 8 Bit (k/255) entspricht, wie er von
+// ParseColor erzeugt wird.
+func exact8(v float64) bool {
+    return v >= 0.0 && v <= 1.0 && float64(quantize8(v))/255.0 == v
+}
+
+func quantize16(v float64) uint16 {
+    return uint16(math.Max(0.0, math.Min(1.0, v))*0xffff + 0.5)
+}
+
+// ColorList ist ein Hilfstyp, mit dem ein Slice von Farben verwaltet werden
+// kann.
+type ColorList []ColorF
 
-// getColor dient der Ermittlung einer Farbe, welche im Array zwischen
+// InterpColor dient der Ermittlung einer Farbe, welche im Array zwischen
 // cl[i] und cl[i+1] liegt. Der Parameter t im Intervall [0,1) wird fuer
 // eine lineare Interpolation zwischen den beiden Farben verwendet.
-//
-//func (cl ColorList) getColor(i int, t float64) color.RGBA {
-//    return cl.getInterpColor(i, t, LinInterpFunc)
-//}
-
-// getInterpColor ist analog zu getColor, aber hier kann die Interpolations-
-// funktion mit dem Parameter fnc bestimmt werden.
-func (cl ColorList) InterpColor(i int, t float64) (col color.RGBA) {
-    c0 := cl[i]
-    c1 := cl[i+1]
-    r0, g0, b0 := float64(c0.R), float64(c0.G), float64(c0.B)
-    r1, g1, b1 := float64(c1.R), float64(c1.G), float64(c1.B)
-    t0 := 1.0 - t
-    col.R = uint8(t0*r0 + t*r1)
-    col.G = uint8(t0*g0 + t*g1)
-    col.B = uint8(t0*b0 + t*b1)
-    col.A = 0xff
-    return
+func (cl ColorList) InterpColor(i int, t float64) ColorF {
+    return cl[i].Interp(cl[i+1], t)
 }
 
 type ColorFunc func(t float64) color.RGBA
@@ -133,22 +251,51 @@ func CubicInterpFunc(t float64) float64 {
     return 3.0*t*t - 2.0*t*t*t
 }
 
-// Retourniert einen Slice mit den Namen aller verfuegbaren Paletten.
+// formatValue erstellt die Textdarstellung eines Wertes in palette.ini. Es
+// wird die kuerzeste Darstellung verwendet, welche beim Einlesen wieder
+// genau den gleichen Wert (float64) ergibt.
+func formatValue(v float64) string {
+    s := strconv.FormatFloat(v, 'f', -1, 64)
+    if !strings.Contains(s, ".") {
+        s += ".0"
+    }
+    return s
+}
+
+// sortedUnique sortiert die Werte in l aufsteigend und entfernt doppelte
+// Werte.
+func sortedUnique(l []float64) []float64 {
+    sort.Float64s(l)
+    res := l[:0]
+    for _, v := range l {
+        if len(res) > 0 && v == res[len(res)-1] {
+            continue
+        }
+        res = append(res, v)
+    }
+    return res
+}
+
+// Retourniert einen Slice mit den Namen aller verfuegbaren Paletten (siehe
+// [DefaultPaletteRegistry]).
 func PaletteNames() ([]string, error) {
-    var fd *os.File
+    r, err := DefaultPaletteRegistry()
+    if err != nil {
+        return nil, err
+    }
+    return r.Names(), nil
+}
+
+// Retourniert einen Slice mit den Namen aller Paletten, welche im Format von
+// palette.ini aus r gelesen werden koennen.
+func ReadPaletteNames(r io.Reader) ([]string, error) {
     var scanner *bufio.Scanner
     var line string
     var matches []string
-    var err error
     var palNames []string
 
     palNames = make([]string, 0)
-    fd, err = OpenConfFile(palFileName)
-    if err != nil {
-        return nil, err
-    }
-    defer fd.Close()
-    scanner = bufio.NewScanner(fd)
+    scanner = bufio.NewScanner(r)
     for scanner.Scan() {
         line = scanner.Text()
         if regxSection.MatchString(line) {
@@ -158,7 +305,7 @@ func PaletteNames() ([]string, error) {
             continue
         }
     }
-    return palNames, nil
+    return palNames, scanner.Err()
 }
 
 // Dieser Datentyp repraesentiert eine Palette, wobei die Implementation
@@ -167,78 +314,286 @@ func PaletteNames() ([]string, error) {
 // brotmengen verwendet werden.
 type basePalette struct {
     colorList    ColorList
+    tableSize    int
     len          int
     lenIsMaxIter bool
     offset       float64
+    inside       ColorF
+    transfer     Transfer
+    transfer1    Transfer
+    transferMix  float64
 }
 
 // Initialisiert die Felder des Basistyps einer Palette.
 func (p *basePalette) Init() {
-    p.colorList = make([]color.RGBA, palSize)
+    p.tableSize = palSize
+    p.colorList = make(ColorList, palSize)
     p.lenIsMaxIter = true
     p.offset = 0.0
+    p.inside = defInsideColor
 }
 
 // Erstellt eine neue Palette aufgrund des Paletten-Namens in palName.
+// Zeilen der Form 'schluessel = wert' werden als Optionen an die Palette
+// weitergegeben (siehe [Palette.SetOption]). Da der Typ der Palette erst
+// mit der ersten Datenzeile bekannt ist, werden Optionen, welche vorher
+// stehen, zwischengespeichert. Die Palette wird in der Registry gesucht,
+// welche von [DefaultPaletteRegistry] geliefert wird.
 func NewPalette(palName string) (Palette, error) {
-    var p Palette
-    var fd *os.File
-    var scanner *bufio.Scanner
-    var line string
-    var matches []string
-    var err error
-    var inSection bool
-
-    fd, err = OpenConfFile(palFileName)
+    r, err := DefaultPaletteRegistry()
     if err != nil {
         return nil, err
     }
-    defer fd.Close()
-    inSection = false
-    scanner = bufio.NewScanner(fd)
-    for scanner.Scan() {
-        line = scanner.Text()
-        if regxComm.MatchString(line) {
-            continue
+    return r.Palette(palName)
+}
+
+// Liest die Palette mit dem Namen palName aus r, wobei r im Format von
+// palette.ini vorliegen muss (siehe [NewPalette]).
+func ReadPalette(r io.Reader, palName string) (Palette, error) {
+    return readPalette(r, palName, 0)
+}
+
+// readPalette ist die Implementation von [ReadPalette]. lineNo ist die Anzahl
+// Zeilen, welche vor r liegen, damit die Zeilennummern in Fehlermeldungen
+// auch dann stimmen, wenn r nur einen Ausschnitt einer Datei enthaelt.
+func readPalette(r io.Reader, palName string, lineNo int) (Palette, error) {
+    var p Palette
+    var options []paletteOption
+    var sectionLine int
+
+    cs := newConfScanner(r, lineNo)
+    if !cs.Find(palName) {
+        if err := cs.Err(); err != nil {
+            return nil, err
+        }
+        return nil, fmt.Errorf("no palette '%s' found!", palName)
+    }
+    sectionLine = cs.Line().no
+    for cs.Scan() {
+        line := cs.Line()
+        if line.kind == confSection {
+            break
         }
-        if inSection {
+        if line.kind == confOption {
             if p == nil {
-                if gradientPalRegexp.MatchString(line) {
-                    p = NewGradientPalette()
-                } else if procPalRegexp.MatchString(line) {
-                    p = NewProcPalette()
-                } else {
-                    return nil, fmt.Errorf("error on line: %s", line)
-                }
+                options = append(options, paletteOption{line.key, line.value, line.no})
+            } else if err := p.SetOption(line.key, line.value); err != nil {
+                return nil, fmt.Errorf("line %d: '%s': %v", line.no, line.text, err)
             }
-            if p.GetRegexp().MatchString(line) {
-                if err := p.ProcessLine(line); err != nil {
-                    return nil, fmt.Errorf("error on line: '%s':\n%v", line, err)
-                }
-            } else if regxSection.MatchString(line) {
-                break
-            } else {
-                return nil, fmt.Errorf("error on line: %s", line)
+            continue
+        }
+        if p == nil {
+            pt := paletteTypeOf(line.text)
+            if pt == nil {
+                return nil, fmt.Errorf("line %d: unknown palette data: '%s'", line.no, line.text)
             }
-        } else {
-            if regxSection.MatchString(line) {
-                matches = regxSection.FindStringSubmatch(line)
-                if strings.Compare(matches[1], palName) == 0 {
-                    inSection = true
+            p = pt.newPalette()
+            for _, opt := range options {
+                if err := p.SetOption(opt.key, opt.value); err != nil {
+                    return nil, fmt.Errorf("line %d: option '%s': %v", opt.lineNo, opt.key, err)
                 }
             }
         }
+        if p.GetRegexp().MatchString(line.text) {
+            if err := p.ProcessLine(line.text); err != nil {
+                return nil, fmt.Errorf("line %d: '%s': %v", line.no, line.text, err)
+            }
+        } else {
+            return nil, fmt.Errorf("line %d: invalid line: '%s'", line.no, line.text)
+        }
     }
-    if !inSection {
-        return nil, fmt.Errorf("no palette '%s' found!", palName)
+    if err := cs.Err(); err != nil {
+        return nil, err
+    }
+    if p == nil {
+        return nil, fmt.Errorf("line %d: palette '%s' has no data", sectionLine, palName)
     }
     if !p.Ready() {
-        return nil, fmt.Errorf("some values are missing for this palette")
+        return nil, fmt.Errorf("line %d: some values are missing for palette '%s'",
+                sectionLine, palName)
     }
     p.Update()
     return p, nil
 }
 
+// paletteOption ist eine Option, welche beim Einlesen einer Palette
+// zwischengespeichert wird, bis der Typ der Palette bekannt ist.
+type paletteOption struct {
+    key, value string
+    lineNo     int
+}
+
+// Setzt die Option key auf den Wert value. Der Basistyp kennt (noch) keine
+// Optionen; die konkreten Paletten ueberschreiben diese Methode und rufen
+// sie fuer unbekannte Optionen auf.
+//
+//   size = n
+//       Groesse der Farbtabelle (Default: 512).
+//   inside = #rrggbb | #rrggbbaa | transparent
+//       Farbe fuer die Punkte innerhalb der Mandelbrotmenge (Default:
+//       deckendes Schwarz).
+//   transfer = linear | log | sqrt | pow e | cyclic [d]
+//       Transferfunktion fuer die Iterationswerte (siehe [Transfer]).
+func (p *basePalette) SetOption(key, value string) error {
+    switch key {
+    case "size":
+        n, err := strconv.Atoi(value)
+        if err != nil {
+            return err
+        }
+        return p.SetTableSize(n)
+    case "inside":
+        c, err := ParseColor(value)
+        if err != nil {
+            return err
+        }
+        p.inside = c
+        return nil
+    case "transfer":
+        tf, err := ParseTransfer(value)
+        if err != nil {
+            return err
+        }
+        p.SetTransfer(tf)
+        return nil
+    default:
+        return fmt.Errorf("%w '%s'", ErrUnknownOption, key)
+    }
+}
+
+// SetTableSize setzt die Groesse der internen Farbtabelle auf n Farben
+// (2 <= n <= 65536). Damit die Tabelle neu berechnet wird, muss
+// anschliessend Update aufgerufen werden.
+func (p *basePalette) SetTableSize(n int) error {
+    if n < 2 || n > maxTableSize {
+        return fmt.Errorf("table size must be in [2,%d]", maxTableSize)
+    }
+    p.tableSize = n
+    p.colorList = make(ColorList, n)
+    return nil
+}
+
+// Retourniert die Groesse der internen Farbtabelle.
+func (p *basePalette) TableSize() int {
+    return p.tableSize
+}
+
+// writeOptions schreibt die Optionen von basePalette, welche vom Standard
+// abweichen, im Format von palette.ini nach w.
+func (p *basePalette) writeOptions(w io.Writer) {
+    if p.tableSize != palSize {
+        fmt.Fprintf(w, "size   = %d\n", p.tableSize)
+    }
+    if p.inside != defInsideColor {
+        fmt.Fprintf(w, "inside = %s\n", FormatColor(p.inside))
+    }
+    if p.transfer != (Transfer{}) {
+        fmt.Fprintf(w, "transfer = %v\n", p.transfer)
+    }
+}
+
+// Setzt die Farbe, welche fuer Punkte innerhalb der Mandelbrotmenge
+// verwendet wird. Mit einer (teilweise) transparenten Farbe koennen die
+// Bilder ueber einen anderen Hintergrund gelegt werden.
+func (p *basePalette) SetInsideColor(c ColorF) {
+    p.inside = c
+}
+
+// Retourniert die Farbe fuer Punkte innerhalb der Mandelbrotmenge.
+func (p *basePalette) InsideColor() ColorF {
+    return p.inside
+}
+
+// Setzt die Transferfunktion, mit welcher die Iterationswerte vor dem
+// Nachschlagen in der Palette umgerechnet werden. Eine allfaellige
+// Ueberblendung (siehe BlendTransfer) wird aufgehoben.
+func (p *basePalette) SetTransfer(tf Transfer) {
+    p.transfer = tf
+    p.transferMix = 0.0
+}
+
+// Retourniert die Transferfunktion der Palette.
+func (p *basePalette) Transfer() Transfer {
+    return p.transfer
+}
+
+// Ueberblendet die Transferfunktion der Palette mit tf, wobei mix der
+// Anteil von tf ist (0.0 <= mix <= 1.0). Wird verwendet, um entlang eines
+// Pfades von einer Transferfunktion zur naechsten zu wechseln.
+func (p *basePalette) BlendTransfer(tf Transfer, mix float64) {
+    p.transfer1 = tf
+    p.transferMix = max(0.0, min(1.0, mix))
+}
+
+// applyTransfer rechnet den Iterationswert f mit der (allenfalls
+// ueberblendeten) Transferfunktion um.
+func (p *basePalette) applyTransfer(f float64) float64 {
+    l := float64(p.len)
+    g := p.transfer.Apply(f, l)
+    if p.transferMix > 0.0 {
+        g = (1.0-p.transferMix)*g + p.transferMix*p.transfer1.Apply(f, l)
+    }
+    return g
+}
+
+// updateFunc berechnet die Farbtabelle aus der Funktion fnc, welche fuer
+// jeden Wert f in [0,1] eine Farbe liefert. Ist alpha ungleich nil, so
+// liefert diese Funktion die Deckkraft, andernfalls ist die Palette
+// deckend. Alle Kanaele werden auf [0,1] beschraenkt.
+func (p *basePalette) updateFunc(fnc func(f float64) [3]float64,
+        alpha func(f float64) float64) {
+    for i := 0; i < len(p.colorList); i++ {
+        f := float64(i) / float64(len(p.colorList)-1)
+        a := 1.0
+        if alpha != nil {
+            a = alpha(f)
+        }
+        p.colorList[i] = clampColor(fnc(f), a)
+    }
+}
+
+// clampColor erstellt aus den Farbkanaelen c und der Deckkraft a eine
+// Farbe, wobei alle Kanaele auf [0,1] beschraenkt werden.
+func clampColor(c [3]float64, a float64) ColorF {
+    for j := range c {
+        c[j] = math.Max(0.0, math.Min(1.0, c[j]))
+    }
+    return ColorF{c[0], c[1], c[2], math.Max(0.0, math.Min(1.0, a))}
+}
+
+// ParseColor interpretiert s als Farbe in der Form '#rrggbb' (deckend),
+// '#rrggbbaa' oder als 'transparent' (vollstaendig transparentes Schwarz).
+func ParseColor(s string) (ColorF, error) {
+    if s == "transparent" {
+        return ColorF{}, nil
+    }
+    if len(s) != 7 && len(s) != 9 || s[0] != '#' {
+        return ColorF{}, fmt.Errorf("invalid color '%s'", s)
+    }
+    v, err := strconv.ParseUint(s[1:], 16, 32)
+    if err != nil {
+        return ColorF{}, fmt.Errorf("invalid color '%s'", s)
+    }
+    if len(s) == 7 {
+        v = v<<8 | 0xff
+    }
+    return ColorF{float64((v>>24)&0xff) / 255.0, float64((v>>16)&0xff) / 255.0,
+        float64((v>>8)&0xff) / 255.0, float64(v&0xff) / 255.0}, nil
+}
+
+// FormatColor ist das Gegenstueck zu [ParseColor]. Deckende Farben werden
+// als '#rrggbb', alle anderen als '#rrggbbaa' geschrieben.
+func FormatColor(c ColorF) string {
+    if c == (ColorF{}) {
+        return "transparent"
+    }
+    c8 := color.NRGBA{quantize8(c.R), quantize8(c.G), quantize8(c.B), quantize8(c.A)}
+    if c8.A == 0xff {
+        return fmt.Sprintf("#%02x%02x%02x", c8.R, c8.G, c8.B)
+    }
+    return fmt.Sprintf("#%02x%02x%02x%02x", c8.R, c8.G, c8.B, c8.A)
+}
+
 // Setzt die Laenge der Palette auf den Wert len.
 func (p *basePalette) SetLength(len int) {
     p.len = len
@@ -280,17 +635,25 @@ func (p *basePalette) Offset() float64 {
 // Mit GetColor kann eine Farbe aus der Farbpalette ermittelt werden.
 // f ist eine beliebige Zahl (>= 0.0), welche zusammen mit der hinterlegten,
 // fiktiven Palettenlaenge p.len und dem definierten Offset p.offset verwendet
-// wird, den Index der gesuchten Farbe zu bestimmen. Ist f < 0.0, dann wird
-// als Farbe IMMER Schwarz retourniert.
+// wird, den Index der gesuchten Farbe zu bestimmen. Vorher wird f mit der
+// Transferfunktion der Palette umgerechnet. Ist f < 0.0, dann wird
+// die Farbe fuer das Innere der Menge retourniert (siehe SetInsideColor).
 func (p *basePalette) GetColor(f float64) color.RGBA {
+    return p.GetColorF(f).RGBA8()
+}
+
+// GetColorF ist analog zu GetColor, liefert die Farbe aber mit voller
+// Genauigkeit (bspw. fuer Bilder mit 16 Bit pro Farbkanal).
+func (p *basePalette) GetColorF(f float64) ColorF {
     var m, d, i, t float64
 
     if f < 0.0 {
-        return color.RGBA{0, 0, 0, 0xff}
+        return p.inside
     }
+    f = p.applyTransfer(f)
     f += p.offset * float64(p.len)
     m = math.Mod(f, float64(p.len))
-    d = float64(palSize-1) * (m / float64(p.len))
+    d = float64(len(p.colorList)-1) * (m / float64(p.len))
     i, t = math.Modf(d)
     return p.colorList.InterpColor(int(i), t)
 }
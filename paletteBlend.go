@@ -0,0 +1,100 @@
+package mandel
+
+import (
+	"errors"
+	"io"
+	"math"
+	"regexp"
+)
+
+// BlendPalette ist eine Palette, deren Farbtabelle durch die Ueberblendung
+// der Farbtabellen zweier anderer Paletten entsteht. Sie wird verwendet, um
+// entlang eines Pfades von einer Palette zur naechsten zu wechseln. Laenge
+// und Offset werden von den beiden Paletten nicht uebernommen, sondern sind
+// wie bei jeder anderen Palette mit SetLength, SetOffset, etc. zu setzen.
+// Die Farbe fuer das Innere der Menge und die Transferfunktion werden
+// hingegen ebenfalls ueberblendet.
+type BlendPalette struct {
+	basePalette
+	pal0, pal1 Palette
+	mix        float64
+}
+
+// tablePalette wird von allen Paletten implementiert, welche eine interne
+// Farbtabelle besitzen (d.h. von allen Paletten, welche [basePalette]
+// einbetten).
+type tablePalette interface {
+	table() ColorList
+	InsideColor() ColorF
+	Transfer() Transfer
+}
+
+func (p *basePalette) table() ColorList {
+	return p.colorList
+}
+
+// tableColor liefert die Farbe an der Stelle f (0.0 <= f <= 1.0) der
+// Farbtabelle cl. Damit koennen Tabellen verschiedener Groesse ueberblendet
+// werden.
+func tableColor(cl ColorList, f float64) ColorF {
+	i, t := math.Modf(f * float64(len(cl)-1))
+	if int(i) >= len(cl)-1 {
+		return cl[len(cl)-1]
+	}
+	return cl.InterpColor(int(i), t)
+}
+
+// Erstellt eine neue Ueberblendung der Paletten pal0 und pal1. Zu Beginn
+// entspricht die Farbtabelle derjenigen von pal0.
+func NewBlendPalette(pal0, pal1 Palette) *BlendPalette {
+	p := &BlendPalette{}
+	p.Init()
+	p.pal0, p.pal1 = pal0, pal1
+	p.Update()
+	return p
+}
+
+// Setzt den Anteil von pal1 an der Ueberblendung (0.0 <= mix <= 1.0) und
+// berechnet die Farbtabelle neu.
+func (p *BlendPalette) SetMix(mix float64) {
+	p.mix = max(0.0, min(1.0, mix))
+	p.Update()
+}
+
+func (p *BlendPalette) Mix() float64 {
+	return p.mix
+}
+
+func (p *BlendPalette) GetRegexp() *regexp.Regexp {
+	return nil
+}
+
+func (p *BlendPalette) ProcessLine(line string) error {
+	return errors.New("blend palettes can't be read from a file")
+}
+
+func (p *BlendPalette) WriteSection(w io.Writer, name string) error {
+	return errors.New("blend palettes can't be written to a file")
+}
+
+func (p *BlendPalette) Ready() bool {
+	_, ok0 := p.pal0.(tablePalette)
+	_, ok1 := p.pal1.(tablePalette)
+	return ok0 && ok1
+}
+
+func (p *BlendPalette) Update() {
+	if !p.Ready() {
+		return
+	}
+	tp0, tp1 := p.pal0.(tablePalette), p.pal1.(tablePalette)
+	cl0, cl1 := tp0.table(), tp1.table()
+	p.inside = tp0.InsideColor().Interp(tp1.InsideColor(), p.mix)
+	tf, tfMix := tp0.Transfer().Interp(tp1.Transfer(), p.mix)
+	p.SetTransfer(tf)
+	p.BlendTransfer(tp1.Transfer(), tfMix)
+	for i := range p.colorList {
+		f := float64(i) / float64(len(p.colorList)-1)
+		p.colorList[i] = tableColor(cl0, f).Interp(tableColor(cl1, f), p.mix)
+	}
+}
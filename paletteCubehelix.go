@@ -0,0 +1,103 @@
+package mandel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Eine CubehelixPalette implementiert das Farbschema 'Cubehelix' von
+// D.A. Green (2011): die Helligkeit steigt monoton von Schwarz nach Weiss,
+// waehrend der Farbton einer Spirale um die Grauachse des RGB-Wuerfels
+// folgt. In palette.ini wird sie mit einer Zeile der Form
+//
+//	cubehelix: start rot hue gamma
+//
+// beschrieben. start ist die Anfangsfarbe (0: blau, 1: rot, 2: gruen),
+// rot die Anzahl Umdrehungen (negativ: rueckwaerts), hue die Saettigung
+// und gamma die Gamma-Korrektur der Helligkeit. Die Werte von matplotlib
+// sind 0.5, -1.5, 1.0 und 1.0.
+var (
+	cubehelixPalRegexp = regexp.MustCompile(`^ *cubehelix *: *([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) *$`)
+)
+
+type CubehelixPalette struct {
+	basePalette
+	start, rot float64
+	hue, gamma float64
+	ready      bool
+}
+
+func NewCubehelixPalette() *CubehelixPalette {
+	p := &CubehelixPalette{}
+	p.Init()
+	return p
+}
+
+func (p *CubehelixPalette) GetRegexp() *regexp.Regexp {
+	return cubehelixPalRegexp
+}
+
+func (p *CubehelixPalette) ProcessLine(line string) error {
+	var v [4]float64
+	var err error
+
+	matches := cubehelixPalRegexp.FindStringSubmatch(line)
+	for i := range v {
+		if v[i], err = strconv.ParseFloat(matches[i+1], 64); err != nil {
+			return err
+		}
+	}
+	return p.SetParams(v[0], v[1], v[2], v[3])
+}
+
+// Setzt alle Parameter der Palette (siehe [CubehelixPalette]).
+func (p *CubehelixPalette) SetParams(start, rot, hue, gamma float64) error {
+	if hue < 0.0 || gamma <= 0.0 {
+		return fmt.Errorf("hue must not be negative and gamma must be positive")
+	}
+	p.start, p.rot, p.hue, p.gamma = start, rot, hue, gamma
+	p.ready = true
+	return nil
+}
+
+func (p *CubehelixPalette) Params() (start, rot, hue, gamma float64) {
+	return p.start, p.rot, p.hue, p.gamma
+}
+
+// Value berechnet die Farbe an der Stelle f (0.0 <= f <= 1.0). Die
+// Farbkanaele koennen leicht ausserhalb von [0,1] liegen.
+func (p *CubehelixPalette) Value(f float64) [3]float64 {
+	l := math.Pow(f, p.gamma)
+	a := p.hue * l * (1.0 - l) / 2.0
+	phi := 2.0 * math.Pi * (p.start/3.0 + 1.0 + p.rot*f)
+	cp, sp := math.Cos(phi), math.Sin(phi)
+	return [3]float64{
+		l + a*(-0.14861*cp+1.78277*sp),
+		l + a*(-0.29227*cp-0.90649*sp),
+		l + a*(1.97294*cp),
+	}
+}
+
+func (p *CubehelixPalette) Update() {
+	p.updateFunc(p.Value, nil)
+}
+
+func (p *CubehelixPalette) Ready() bool {
+	return p.ready
+}
+
+// WriteSection schreibt die Palette als Abschnitt mit dem Namen name im
+// Format von palette.ini nach w.
+func (p *CubehelixPalette) WriteSection(w io.Writer, name string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", name)
+	p.writeOptions(bw)
+	fmt.Fprintf(bw, "cubehelix: %s %s %s %s\n", formatValue(p.start), formatValue(p.rot),
+		formatValue(p.hue), formatValue(p.gamma))
+	fmt.Fprintln(bw)
+	return bw.Flush()
+}
@@ -0,0 +1,278 @@
+package mandel
+
+import (
+	"errors"
+	"image"
+	"math"
+	"math/rand/v2"
+)
+
+// Mit den Funktionen in dieser Datei kann eine Palette aus einem Bild (bspw.
+// einer Fotografie) erstellt werden. Dazu werden die Farben des Bildes im
+// Farbraum OKLab mit k-means auf wenige Farben reduziert. Diese werden
+// anschliessend so angeordnet, dass der Weg von Farbe zu Farbe moeglichst
+// kurz ist, und als Stuetzstellen einer GradientPalette verwendet.
+
+const (
+	// Maximale Anzahl Pixel, welche fuer die Quantisierung verwendet werden.
+	// Bei groesseren Bildern wird nur jedes n-te Pixel beruecksichtigt.
+	extractMaxSamples = 1 << 16
+	// Maximale Anzahl Iterationen von k-means.
+	extractMaxIter = 100
+)
+
+// ImageColor ist eine der Farben, welche von [ExtractColors] in einem Bild
+// gefunden wurden. Die Farbkanaele liegen in [0,1], Weight ist der Anteil
+// der Pixel, welche dieser Farbe zugeordnet wurden.
+type ImageColor struct {
+	R, G, B float64
+	Weight  float64
+}
+
+// ExtractColors reduziert die Farben des Bildes img auf hoechstens
+// numColors Farben. Die Farben sind so sortiert, dass sie mit der dunkelsten
+// Farbe beginnen und der Weg durch alle Farben (gemessen in OKLab) moeglichst
+// kurz ist. Das Resultat ist fuer ein bestimmtes Bild immer dasselbe.
+func ExtractColors(img image.Image, numColors int) ([]ImageColor, error) {
+	if numColors < 1 {
+		return nil, errors.New("number of colors must be positive")
+	}
+	points := samplePixels(img)
+	if len(points) == 0 {
+		return nil, errors.New("image has no opaque pixels")
+	}
+	centers, counts := kMeans(points, min(numColors, len(points)))
+
+	res := make([]ImageColor, 0, len(centers))
+	labs := make([][3]float64, 0, len(centers))
+	for i, c := range centers {
+		if counts[i] == 0 {
+			continue
+		}
+		rgb := SpaceOKLab.ToRGB(c)
+		res = append(res, ImageColor{rgb[0], rgb[1], rgb[2],
+			float64(counts[i]) / float64(len(points))})
+		labs = append(labs, c)
+	}
+	order := shortestPath(labs)
+	sorted := make([]ImageColor, len(order))
+	for i, j := range order {
+		sorted[i] = res[j]
+	}
+	return sorted, nil
+}
+
+// ExtractPalette erstellt aus dem Bild img eine GradientPalette mit
+// hoechstens numColors Stuetzstellen (siehe [ExtractColors] und
+// [PaletteFromColors]).
+func ExtractPalette(img image.Image, numColors int) (*GradientPalette, error) {
+	colors, err := ExtractColors(img, numColors)
+	if err != nil {
+		return nil, err
+	}
+	return PaletteFromColors(colors)
+}
+
+// PaletteFromColors erstellt eine GradientPalette, welche die Farben colors
+// in der gegebenen Reihenfolge durchlaeuft. Die Abstaende der Stuetzstellen
+// entsprechen den Farbabstaenden in OKLab, womit der Verlauf wahrnehmungs-
+// maessig gleichmaessig wird. Interpoliert wird im Farbraum OKLab mit
+// monotonen Splines.
+func PaletteFromColors(colors []ImageColor) (*GradientPalette, error) {
+	if len(colors) == 0 {
+		return nil, errors.New("no colors")
+	}
+	if len(colors) == 1 {
+		colors = append(colors, colors[0])
+	}
+	pos := make([]float64, len(colors))
+	for i := 1; i < len(colors); i++ {
+		pos[i] = pos[i-1] + labDist(imageColorLab(colors[i-1]), imageColorLab(colors[i]))
+	}
+	total := pos[len(pos)-1]
+	stops := make([]ColorStop, len(colors))
+	for i, c := range colors {
+		p := float64(i) / float64(len(colors)-1)
+		if total > 0.0 {
+			p = pos[i] / total
+		}
+		// Die Positionen werden auf 32 Bit gerundet, damit sie in palette.ini
+		// mit wenigen Stellen geschrieben werden.
+		p = float64(float32(p))
+		stops[i] = ColorStop{Pos: p, R: c.R, G: c.G, B: c.B}
+	}
+	stops[0].Pos, stops[len(stops)-1].Pos = 0.0, 1.0
+
+	p := NewGradientPalette()
+	p.SetColorSpace(SpaceOKLab)
+	p.SetInterp(&Interp{Type: InterpMonotone})
+	for _, cs := range stops {
+		if err := p.AddColorStop(cs); err != nil {
+			return nil, err
+		}
+	}
+	p.Update()
+	return p, nil
+}
+
+func imageColorLab(c ImageColor) [3]float64 {
+	return SpaceOKLab.FromRGB([3]float64{c.R, c.G, c.B})
+}
+
+func labDist(a, b [3]float64) float64 {
+	return math.Sqrt(labDist2(a, b))
+}
+
+func labDist2(a, b [3]float64) float64 {
+	d0, d1, d2 := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return d0*d0 + d1*d1 + d2*d2
+}
+
+// samplePixels liefert die (nicht transparenten) Pixel von img in OKLab.
+func samplePixels(img image.Image) [][3]float64 {
+	b := img.Bounds()
+	step := 1
+	if n := b.Dx() * b.Dy(); n > extractMaxSamples {
+		step = int(math.Ceil(math.Sqrt(float64(n) / extractMaxSamples)))
+	}
+	points := make([][3]float64, 0, (b.Dx()/step+1)*(b.Dy()/step+1))
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			r, g, bl, a := img.At(x, y).RGBA()
+			if a == 0 {
+				continue
+			}
+			// Vormultiplizierte Werte zurueckrechnen.
+			rgb := [3]float64{float64(r) / float64(a), float64(g) / float64(a),
+				float64(bl) / float64(a)}
+			points = append(points, SpaceOKLab.FromRGB(rgb))
+		}
+	}
+	return points
+}
+
+// kMeans teilt points in k Gruppen und liefert deren Mittelpunkte sowie die
+// Anzahl Punkte pro Gruppe. Die Startwerte werden mit k-means++ bestimmt,
+// wobei der Zufallsgenerator immer gleich initialisiert wird.
+func kMeans(points [][3]float64, k int) ([][3]float64, []int) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	centers := make([][3]float64, 0, k)
+	dist := make([]float64, len(points))
+
+	centers = append(centers, points[rnd.IntN(len(points))])
+	for i := range dist {
+		dist[i] = labDist2(points[i], centers[0])
+	}
+	for len(centers) < k {
+		sum := 0.0
+		for _, d := range dist {
+			sum += d
+		}
+		if sum == 0.0 {
+			break
+		}
+		r := rnd.Float64() * sum
+		idx := len(points) - 1
+		for i, d := range dist {
+			if r -= d; r <= 0.0 && d > 0.0 {
+				idx = i
+				break
+			}
+		}
+		c := points[idx]
+		centers = append(centers, c)
+		for i := range dist {
+			dist[i] = min(dist[i], labDist2(points[i], c))
+		}
+	}
+
+	assign := make([]int, len(points))
+	counts := make([]int, len(centers))
+	for iter := 0; iter < extractMaxIter; iter++ {
+		changed := false
+		for i, pt := range points {
+			best, bestDist := 0, math.Inf(1)
+			for j, c := range centers {
+				if d := labDist2(pt, c); d < bestDist {
+					best, bestDist = j, d
+				}
+			}
+			if best != assign[i] || iter == 0 {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([][3]float64, len(centers))
+		clear(counts)
+		for i, pt := range points {
+			j := assign[i]
+			counts[j]++
+			for l := range pt {
+				sums[j][l] += pt[l]
+			}
+		}
+		for j := range centers {
+			if counts[j] == 0 {
+				continue
+			}
+			for l := range sums[j] {
+				centers[j][l] = sums[j][l] / float64(counts[j])
+			}
+		}
+	}
+	return centers, counts
+}
+
+// shortestPath bestimmt eine Reihenfolge der Punkte, welche beim Punkt mit
+// der kleinsten Helligkeit beginnt und einen moeglichst kurzen Weg durch
+// alle Punkte ergibt (naechster Nachbar, anschliessend verbessert mit
+// 2-opt).
+func shortestPath(points [][3]float64) []int {
+	n := len(points)
+	order := make([]int, 0, n)
+	used := make([]bool, n)
+	start := 0
+	for i, p := range points {
+		if p[0] < points[start][0] {
+			start = i
+		}
+	}
+	order = append(order, start)
+	used[start] = true
+	for len(order) < n {
+		last := points[order[len(order)-1]]
+		next, nextDist := -1, math.Inf(1)
+		for i, p := range points {
+			if d := labDist(last, p); !used[i] && d < nextDist {
+				next, nextDist = i, d
+			}
+		}
+		order = append(order, next)
+		used[next] = true
+	}
+
+	d := func(i, j int) float64 {
+		return labDist(points[order[i]], points[order[j]])
+	}
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < n-2; i++ {
+			for j := i + 2; j < n; j++ {
+				gain := d(i, i+1) - d(i, j)
+				if j < n-1 {
+					gain += d(j, j+1) - d(i+1, j+1)
+				}
+				if gain > 1e-12 {
+					for a, b := i+1, j; a < b; a, b = a+1, b-1 {
+						order[a], order[b] = order[b], order[a]
+					}
+					improved = true
+				}
+			}
+		}
+	}
+	return order
+}
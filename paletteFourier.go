@@ -0,0 +1,137 @@
+package mandel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bei einer FourierPalette wird jeder Farbkanal als Fourier-Reihe (d.h. als
+// Summe von Sinus- und Kosinus-Schwingungen) berechnet:
+//
+//	v(f) = a0 + a1*cos(2*pi*f) + b1*sin(2*pi*f) + a2*cos(4*pi*f) + ...
+//
+// In palette.ini werden die Koeffizienten pro Farbkanal in der Form
+//
+//	fourier r: a0 a1 b1 a2 b2 ...
+//
+// angegeben. Die Anzahl Koeffizienten ist also immer ungerade und kann pro
+// Farbkanal verschieden sein. Mit 'fourier a:' kann optional auch die
+// Deckkraft als Fourier-Reihe angegeben werden. Da alle Schwingungen ganzzahlige Frequenzen
+// haben, geht die Palette am Ende nahtlos in ihren Anfang ueber.
+var (
+	fourierPalRegexp = regexp.MustCompile(`^ *fourier +([rgba]) *: *([-+0-9\.]+(?: +[-+0-9\.]+)*) *$`)
+)
+
+type FourierPalette struct {
+	basePalette
+	coeffs [][]float64
+}
+
+func NewFourierPalette() *FourierPalette {
+	p := &FourierPalette{}
+	p.Init()
+	p.coeffs = make([][]float64, NumChannels)
+	return p
+}
+
+func (p *FourierPalette) GetRegexp() *regexp.Regexp {
+	return fourierPalRegexp
+}
+
+func (p *FourierPalette) ProcessLine(line string) error {
+	matches := fourierPalRegexp.FindStringSubmatch(line)
+	fields := strings.Fields(matches[2])
+	v := make([]float64, len(fields))
+	for i, s := range fields {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v[i] = f
+	}
+	return p.SetCoeffs(colorMap[matches[1][0]], v)
+}
+
+// Setzt die Koeffizienten a0, a1, b1, a2, b2, ... des Farbkanals col.
+func (p *FourierPalette) SetCoeffs(col BaseColorType, v []float64) error {
+	if len(v)%2 != 1 {
+		return fmt.Errorf("expected an odd number of coefficients, got %d", len(v))
+	}
+	p.coeffs[col] = v
+	return nil
+}
+
+func (p *FourierPalette) Coeffs(col BaseColorType) []float64 {
+	return p.coeffs[col]
+}
+
+func (p *FourierPalette) Value(col BaseColorType, f float64) float64 {
+	v := p.coeffs[col]
+	res := v[0]
+	for k := 1; 2*k < len(v); k++ {
+		phi := 2.0 * math.Pi * float64(k) * f
+		res += v[2*k-1]*math.Cos(phi) + v[2*k]*math.Sin(phi)
+	}
+	return res
+}
+
+func (p *FourierPalette) Update() {
+	p.updateFunc(func(f float64) [3]float64 {
+		return [3]float64{p.Value(Red, f), p.Value(Green, f), p.Value(Blue, f)}
+	}, p.alphaFunc())
+}
+
+// alphaFunc liefert die Funktion fuer den Alpha-Kanal oder nil, falls fuer
+// diesen Kanal keine Parameter angegeben wurden.
+func (p *FourierPalette) alphaFunc() func(f float64) float64 {
+	if p.coeffs[Alpha] == nil {
+		return nil
+	}
+	return func(f float64) float64 {
+		return p.Value(Alpha, f)
+	}
+}
+
+// channels liefert die Parameter aller Kanaele, wobei der Alpha-Kanal nur
+// enthalten ist, falls er gesetzt wurde.
+func (p *FourierPalette) channels() [][]float64 {
+	if p.coeffs[Alpha] == nil {
+		return p.coeffs[:NumBaseColors]
+	}
+	return p.coeffs
+}
+
+func (p *FourierPalette) Ready() bool {
+	for _, v := range p.coeffs[:NumBaseColors] {
+		if v == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteSection schreibt die Palette als Abschnitt mit dem Namen name im
+// Format von palette.ini nach w.
+func (p *FourierPalette) WriteSection(w io.Writer, name string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", name)
+	p.writeOptions(bw)
+	for col, c := range []byte{'r', 'g', 'b', 'a'} {
+		v := p.coeffs[col]
+		if v == nil {
+			continue
+		}
+		fmt.Fprintf(bw, "fourier %c:", c)
+		for _, a := range v {
+			fmt.Fprintf(bw, " %8s", formatValue(a))
+		}
+		fmt.Fprintln(bw)
+	}
+	fmt.Fprintln(bw)
+	return bw.Flush()
+}
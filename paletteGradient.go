@@ -1,36 +1,58 @@
 package mandel
 
 import (
+    "bufio"
     "container/list"
-    "image/color"
+    "fmt"
+    "io"
     "regexp"
     "strconv"
+    "strings"
 )
 
 // Dieser Typ realisiert eine Palette mit interpolierten Farbverläufen.
-// Jede Farbe kann einzeln eingestellt werden kann.
+// Jede Farbe kann einzeln eingestellt werden kann. Alternativ kann eine
+// Stuetzstelle auch als ganze Farbe in der Form '#rrggbb' angegeben werden.
+// Optional kann als vierter Wert (resp. mit '#rrggbbaa') die Deckkraft
+// angegeben werden; Paletten ohne Alpha-Werte sind deckend.
+// Nach einem '/' kann schliesslich die Interpolation bis zur naechsten
+// Stuetzstelle angegeben werden.
 var (
-    gradientPalRegexp = regexp.MustCompile(`^ *([0-9\.]+) *: *([0-9\.]+|-) +([0-9\.]+|-) +([0-9\.]+|-) *$`)
+    gradientPalRegexp = regexp.MustCompile(`^ *([0-9\.]+) *: *(?:#([[:xdigit:]]{6}(?:[[:xdigit:]]{2})?)|([0-9\.]+|-) +([0-9\.]+|-) +([0-9\.]+|-)(?: +([0-9\.]+|-))?)(?: */ *([[:alpha:]]+(?: +[-+0-9\.]+)*))? *$`)
 )
 
 type GradientPalette struct {
     basePalette
     pointList []*list.List
-    intFunc   InterpFunc
+    interp    *Interp
+    space     ColorSpace
+    huePath   HuePath
 }
 
+// Ein GradPoint ist ein Stuetzwert eines einzelnen Farbkanals. Mit Interp
+// kann fuer den Abschnitt bis zum naechsten Stuetzwert eine eigene
+// Interpolation festgelegt werden (nil: Interpolation der Palette).
 type GradPoint struct {
     Pos, Val float64
+    Interp   *Interp
+}
+
+// Ein ColorStop ist eine Stuetzstelle mit einer vollstaendigen Farbe. Die
+// Farbkanaele liegen in [0,1].
+type ColorStop struct {
+    Pos     float64
+    R, G, B float64
+    Interp  *Interp
 }
 
 func NewGradientPalette() *GradientPalette {
     p := &GradientPalette{}
     p.Init()
-    p.pointList = make([]*list.List, NumBaseColors)
-    for i := Red; i < NumBaseColors; i++ {
+    p.pointList = make([]*list.List, NumChannels)
+    for i := Red; i < NumChannels; i++ {
         p.pointList[i] = list.New()
     }
-    p.intFunc = CubicInterpFunc
+    p.interp = &Interp{Type: InterpCubic}
     return p
 }
 
@@ -41,15 +63,36 @@ func (p *GradientPalette) GetRegexp() *regexp.Regexp {
 func (p *GradientPalette) ProcessLine(line string) error {
     var matches []string
     var t, v float64
+    var ip *Interp
+    var err error
 
     matches = gradientPalRegexp.FindStringSubmatch(line)
-    t, _ = strconv.ParseFloat(matches[1], 32)
-    for i := Red; i < NumBaseColors; i++ {
-        if matches[i+2] == "-" {
+    if t, err = strconv.ParseFloat(matches[1], 64); err != nil {
+        return err
+    }
+    if matches[7] != "" {
+        if ip, err = ParseInterp(matches[7]); err != nil {
+            return err
+        }
+    }
+    if matches[2] != "" {
+        c, _ := ParseColor("#" + matches[2])
+        if err = p.AddColorStop(ColorStop{t, c.R, c.G, c.B, ip}); err != nil {
+            return err
+        }
+        if len(matches[2]) > 6 {
+            return p.AddGradPoint(Alpha, &GradPoint{t, c.A, ip})
+        }
+        return nil
+    }
+    for i := Red; i < NumChannels; i++ {
+        if matches[i+3] == "" || matches[i+3] == "-" {
             continue
         }
-        v, _ = strconv.ParseFloat(matches[i+2], 32)
-        gp := &GradPoint{t, v}
+        if v, err = strconv.ParseFloat(matches[i+3], 64); err != nil {
+            return err
+        }
+        gp := &GradPoint{t, v, ip}
         if err := p.AddGradPoint(i, gp); err != nil {
             return err
         }
@@ -57,37 +100,248 @@ func (p *GradientPalette) ProcessLine(line string) error {
     return nil
 }
 
+// hexStop liefert die Stuetzstelle an der Position pos in der Form
+// '#rrggbb' (resp. '#rrggbbaa', falls es dort einen Alpha-Wert gibt), sofern
+// alle Farbkanaele dort einen Wert mit 8 Bit haben.
+func hexStop(points [NumChannels]map[float64]*GradPoint, pos float64) (string, bool) {
+    var c [NumChannels]uint8
+
+    n := NumBaseColors
+    if gp, ok := points[Alpha][pos]; ok {
+        n = NumChannels
+        if !exact8(gp.Val) {
+            return "", false
+        }
+    }
+    for i := Red; i < n; i++ {
+        gp, ok := points[i][pos]
+        if !ok || !exact8(gp.Val) {
+            return "", false
+        }
+        c[i] = quantize8(gp.Val)
+    }
+    if n == NumChannels {
+        return fmt.Sprintf("#%02x%02x%02x%02x", c[Red], c[Green], c[Blue], c[Alpha]), true
+    }
+    return fmt.Sprintf("#%02x%02x%02x", c[Red], c[Green], c[Blue]), true
+}
+
+// Folgende Optionen werden von interpolierten Paletten unterstuetzt:
+//
+//   space  = rgb | linear | hsv | lab | oklab | oklch
+//   hue    = shortest | longest
+//   interp = linear | cubic | catmullrom | monotone | bezier [x1 y1 x2 y2]
+func (p *GradientPalette) SetOption(key, value string) error {
+    switch key {
+    case "interp":
+        ip, err := ParseInterp(value)
+        if err != nil {
+            return err
+        }
+        p.interp = ip
+        return nil
+    case "space":
+        return p.space.Set(value)
+    case "hue":
+        return p.huePath.Set(value)
+    default:
+        return p.basePalette.SetOption(key, value)
+    }
+}
+
+// Setzt die Interpolation, welche fuer alle Abschnitte ohne eigene
+// Interpolation verwendet wird (Default: kubisch).
+func (p *GradientPalette) SetInterp(ip *Interp) {
+    p.interp = ip
+}
+
+// Retourniert die Interpolation der Palette.
+func (p *GradientPalette) Interp() *Interp {
+    return p.interp
+}
+
+// Setzt den Farbraum, in welchem interpoliert werden soll.
+func (p *GradientPalette) SetColorSpace(cs ColorSpace) {
+    p.space = cs
+}
+
+// Retourniert den Farbraum, in welchem interpoliert wird.
+func (p *GradientPalette) ColorSpace() ColorSpace {
+    return p.space
+}
+
+// Legt fest, auf welchem Weg um den Farbkreis interpoliert werden soll.
+// Hat nur bei Farbraeumen mit Farbton (HSV, OKLCH) eine Wirkung.
+func (p *GradientPalette) SetHuePath(hp HuePath) {
+    p.huePath = hp
+}
+
+// Retourniert den Weg, auf welchem Farbtoene interpoliert werden.
+func (p *GradientPalette) HuePath() HuePath {
+    return p.huePath
+}
+
 func (p *GradientPalette) Update() {
-    var colVal [3]uint8
-    var e *list.Element
-    var gradPt, nextGradPt *GradPoint
+    if p.space == SpaceRGB {
+        p.updateChannels()
+    } else {
+        p.updateSpace()
+    }
+}
 
-    for i := 0; i < len(p.colorList); i++ {
-        f := float64(i) / float64(len(p.colorList)-1)
+// updateChannels interpoliert jeden Farbkanal einzeln (Farbraum SpaceRGB).
+func (p *GradientPalette) updateChannels() {
+    var curves [NumBaseColors]*curve
 
-        for j := Red; j < NumBaseColors; j++ {
-            l := p.pointList[j]
-            for e = l.Back(); e != nil; e = e.Prev() {
-                if f >= e.Value.(*GradPoint).Pos {
-                    break
-                }
+    for j := Red; j < NumBaseColors; j++ {
+        curves[j] = p.channelCurve(j)
+    }
+    p.updateFunc(func(f float64) [3]float64 {
+        return [3]float64{curves[Red].value(f), curves[Green].value(f),
+                curves[Blue].value(f)}
+    }, p.alphaFunc())
+}
+
+// alphaFunc liefert die Funktion fuer den Alpha-Kanal oder nil, falls die
+// Palette keine Alpha-Werte hat (und damit deckend ist). Der Alpha-Kanal
+// wird unabhaengig vom Farbraum immer fuer sich interpoliert.
+func (p *GradientPalette) alphaFunc() func(f float64) float64 {
+    if p.pointList[Alpha].Len() == 0 {
+        return nil
+    }
+    return p.channelCurve(Alpha).value
+}
+
+// updateSpace interpoliert zwischen vollstaendigen Farben im Farbraum
+// p.space.
+func (p *GradientPalette) updateSpace() {
+    var curves [3]*curve
+
+    stops := p.ColorStops()
+    pos := make([]float64, len(stops))
+    interp := make([]*Interp, len(stops))
+    vals := make([][3]float64, len(stops))
+    for i, cs := range stops {
+        pos[i] = cs.Pos
+        interp[i] = cs.Interp
+        vals[i] = p.space.FromRGB([3]float64{cs.R, cs.G, cs.B})
+    }
+    if hi := p.space.HueIndex(); hi >= 0 {
+        p.fixHues(vals, hi, p.space.ChromaIndex())
+    }
+    for k := range curves {
+        comp := make([]float64, len(vals))
+        for i := range vals {
+            comp[i] = vals[i][k]
+        }
+        curves[k] = newCurve(pos, comp, interp, p.interp)
+    }
+
+    p.updateFunc(func(f float64) [3]float64 {
+        var c [3]float64
+
+        for k := range c {
+            c[k] = curves[k].value(f)
+        }
+        return p.space.ToRGB(c)
+    }, p.alphaFunc())
+}
+
+// fixHues bereitet die Farbtoene (Komponente hi) fuer die Interpolation vor:
+// unbunte Farben (Komponente ci ist Null) uebernehmen den Farbton einer
+// benachbarten Stuetzstelle und aufeinanderfolgende Farbtoene werden gemaess
+// p.huePath auf dem Farbkreis 'abgewickelt'.
+func (p *GradientPalette) fixHues(vals [][3]float64, hi, ci int) {
+    const minChroma = 1.0e-4
+
+    for i := range vals {
+        if vals[i][ci] >= minChroma {
+            continue
+        }
+        for d := 1; d < len(vals); d++ {
+            if i+d < len(vals) && vals[i+d][ci] >= minChroma {
+                vals[i][hi] = vals[i+d][hi]
+                break
             }
-            gradPt = e.Value.(*GradPoint)
-            if f == gradPt.Pos {
-                colVal[j] = uint8(255.0 * gradPt.Val)
-            } else {
-                nextGradPt = e.Next().Value.(*GradPoint)
-                t := (f - gradPt.Pos) / (nextGradPt.Pos - gradPt.Pos)
-                colVal[j] = uint8(255.0 * InterpValue(gradPt.Val, nextGradPt.Val, t, p.intFunc))
+            if i-d >= 0 && vals[i-d][ci] >= minChroma {
+                vals[i][hi] = vals[i-d][hi]
+                break
             }
         }
-        p.colorList[i] = color.RGBA{colVal[0], colVal[1], colVal[2], 0xff}
+    }
+    for i := 1; i < len(vals); i++ {
+        vals[i][hi] = p.huePath.Unwrap(vals[i-1][hi], vals[i][hi])
     }
 }
 
-func (p *GradientPalette) Ready() bool {
+// channelCurve erstellt aus den Stuetzwerten des Farbkanals col die Kurve
+// fuer die Interpolation.
+func (p *GradientPalette) channelCurve(col BaseColorType) *curve {
+    l := p.pointList[col]
+    pos := make([]float64, 0, l.Len())
+    val := make([]float64, 0, l.Len())
+    interp := make([]*Interp, 0, l.Len())
+    for e := l.Front(); e != nil; e = e.Next() {
+        gp := e.Value.(*GradPoint)
+        pos = append(pos, gp.Pos)
+        val = append(val, gp.Val)
+        interp = append(interp, gp.Interp)
+    }
+    return newCurve(pos, val, interp, p.interp)
+}
+
+// ColorStops liefert die Stuetzstellen der Palette als vollstaendige
+// Farben. Fuer jede Position, an welcher mindestens ein Farbkanal einen
+// Stuetzwert hat, wird eine Stuetzstelle erzeugt; fehlende Kanaele werden
+// dabei wie bisher kanalweise interpoliert.
+func (p *GradientPalette) ColorStops() []ColorStop {
+    var posList []float64
+    var curves [NumBaseColors]*curve
+
+    interp := make(map[float64]*Interp)
     for i := Red; i < NumBaseColors; i++ {
+        curves[i] = p.channelCurve(i)
+        for e := p.pointList[i].Front(); e != nil; e = e.Next() {
+            gp := e.Value.(*GradPoint)
+            posList = append(posList, gp.Pos)
+            if gp.Interp != nil {
+                interp[gp.Pos] = gp.Interp
+            }
+        }
+    }
+    posList = sortedUnique(posList)
+    stops := make([]ColorStop, 0, len(posList))
+    for _, pos := range posList {
+        stops = append(stops, ColorStop{pos, curves[Red].value(pos),
+                curves[Green].value(pos), curves[Blue].value(pos), interp[pos]})
+    }
+    return stops
+}
+
+// Fuegt der Palette eine Stuetzstelle mit einer vollstaendigen Farbe hinzu,
+// d.h. in jedem Farbkanal wird ein Stuetzwert an der Position cs.Pos
+// erstellt.
+func (p *GradientPalette) AddColorStop(cs ColorStop) error {
+    vals := [NumBaseColors]float64{cs.R, cs.G, cs.B}
+    for i := Red; i < NumBaseColors; i++ {
+        if err := p.AddGradPoint(i, &GradPoint{cs.Pos, vals[i], cs.Interp}); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Ready prueft, ob jeder Farbkanal Stuetzwerte bei 0.0 und 1.0 hat. Fuer
+// den Alpha-Kanal gilt dies nur, falls er ueberhaupt Stuetzwerte hat.
+func (p *GradientPalette) Ready() bool {
+    for i := Red; i < NumChannels; i++ {
         l := p.pointList[i]
+        if l.Len() == 0 {
+            if i == Alpha {
+                continue
+            }
+            return false
+        }
         e := l.Front()
         if e.Value.(*GradPoint).Pos != 0.0 {
             return false
@@ -100,6 +354,9 @@ func (p *GradientPalette) Ready() bool {
     return true
 }
 
+// Fuegt dem Farbkanal col den Stuetzwert gp hinzu. Pro Kanal darf es an
+// jeder Position nur einen Stuetzwert geben, da sonst die Steigungen der
+// Interpolation nicht definiert sind.
 func (p *GradientPalette) AddGradPoint(col BaseColorType, gp *GradPoint) error {
     var e *list.Element
     l := p.pointList[col]
@@ -109,7 +366,11 @@ func (p *GradientPalette) AddGradPoint(col BaseColorType, gp *GradPoint) error {
         return nil
     }
     for e = l.Front(); e != nil; e = e.Next() {
-        if gp.Pos < e.Value.(*GradPoint).Pos {
+        pos := e.Value.(*GradPoint).Pos
+        if gp.Pos == pos {
+            return fmt.Errorf("duplicate stop position %g", gp.Pos)
+        }
+        if gp.Pos < pos {
             break
         }
     }
@@ -162,3 +423,72 @@ func (p *GradientPalette) NumGradPoints(col BaseColorType) (int) {
     return p.pointList[col].Len()
 }
 
+// WriteSection schreibt die Palette als Abschnitt mit dem Namen name im
+// Format von palette.ini nach w. Stuetzwerte, welche nur fuer einzelne
+// Farbkanaele definiert sind, werden mit '-' fuer die uebrigen Kanaele
+// geschrieben, so dass beim erneuten Einlesen die gleiche Palette entsteht.
+// Sind alle Farbkanaele einer Stuetzstelle Werte mit 8 Bit (bspw. weil sie
+// als '#rrggbb' angegeben wurden), so wird die Stuetzstelle wieder in dieser
+// Form geschrieben. Die Spalte fuer den Alpha-Kanal wird nur geschrieben,
+// falls die Palette Alpha-Werte hat.
+func (p *GradientPalette) WriteSection(w io.Writer, name string) error {
+    var posList []float64
+    var points [NumChannels]map[float64]*GradPoint
+
+    numCols := NumBaseColors
+    if p.pointList[Alpha].Len() > 0 {
+        numCols = NumChannels
+    }
+    bw := bufio.NewWriter(w)
+    fmt.Fprintf(bw, "[%s]\n", name)
+    p.writeOptions(bw)
+    if p.space != SpaceRGB {
+        fmt.Fprintf(bw, "space  = %v\n", p.space)
+    }
+    if p.huePath != HueShortest {
+        fmt.Fprintf(bw, "hue    = %v\n", p.huePath)
+    }
+    if p.interp.Type != InterpCubic {
+        fmt.Fprintf(bw, "interp = %v\n", p.interp)
+    }
+    for i := Red; i < numCols; i++ {
+        points[i] = make(map[float64]*GradPoint)
+        for e := p.pointList[i].Front(); e != nil; e = e.Next() {
+            gp := e.Value.(*GradPoint)
+            if _, ok := points[i][gp.Pos]; !ok {
+                posList = append(posList, gp.Pos)
+            }
+            points[i][gp.Pos] = gp
+        }
+    }
+    for _, pos := range sortedUnique(posList) {
+        var ip *Interp
+
+        line := fmt.Sprintf("%-10s:", formatValue(pos))
+        if hex, ok := hexStop(points, pos); ok {
+            line += " " + hex
+            for i := Red; i < NumChannels; i++ {
+                if gp, ok := points[i][pos]; ok && gp.Interp != nil {
+                    ip = gp.Interp
+                }
+            }
+        } else {
+            for i := Red; i < numCols; i++ {
+                if gp, ok := points[i][pos]; ok {
+                    line += fmt.Sprintf(" %-10s", formatValue(gp.Val))
+                    if gp.Interp != nil {
+                        ip = gp.Interp
+                    }
+                } else {
+                    line += fmt.Sprintf(" %-10s", "-")
+                }
+            }
+        }
+        if ip != nil {
+            line += fmt.Sprintf(" / %v", ip)
+        }
+        fmt.Fprintln(bw, strings.TrimRight(line, " "))
+    }
+    fmt.Fprintln(bw)
+    return bw.Flush()
+}
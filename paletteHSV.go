@@ -0,0 +1,90 @@
+package mandel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Eine HSVPalette durchlaeuft den Farbkreis im HSV-Farbraum mit konstanter
+// Saettigung und Helligkeit. In palette.ini wird sie mit einer einzigen
+// Zeile der Form
+//
+//	hsv: h0 h1 s v
+//
+// beschrieben. h0 und h1 sind Anfangs- und Endwert des Farbtons in Umdre-
+// hungen (1.0 entspricht dem ganzen Farbkreis), h1 darf also auch groesser
+// als 1.0 sein. s und v muessen in [0,1] liegen.
+var (
+	hsvPalRegexp = regexp.MustCompile(`^ *hsv *: *([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) *$`)
+)
+
+type HSVPalette struct {
+	basePalette
+	hue0, hue1 float64
+	sat, val   float64
+	ready      bool
+}
+
+func NewHSVPalette() *HSVPalette {
+	p := &HSVPalette{}
+	p.Init()
+	return p
+}
+
+func (p *HSVPalette) GetRegexp() *regexp.Regexp {
+	return hsvPalRegexp
+}
+
+func (p *HSVPalette) ProcessLine(line string) error {
+	var v [4]float64
+	var err error
+
+	matches := hsvPalRegexp.FindStringSubmatch(line)
+	for i := range v {
+		if v[i], err = strconv.ParseFloat(matches[i+1], 64); err != nil {
+			return err
+		}
+	}
+	return p.SetParams(v[0], v[1], v[2], v[3])
+}
+
+// Setzt alle Parameter der Palette (siehe [HSVPalette]).
+func (p *HSVPalette) SetParams(hue0, hue1, sat, val float64) error {
+	if sat < 0.0 || sat > 1.0 || val < 0.0 || val > 1.0 {
+		return fmt.Errorf("saturation and value must be in [0,1]")
+	}
+	p.hue0, p.hue1, p.sat, p.val = hue0, hue1, sat, val
+	p.ready = true
+	return nil
+}
+
+func (p *HSVPalette) Params() (hue0, hue1, sat, val float64) {
+	return p.hue0, p.hue1, p.sat, p.val
+}
+
+func (p *HSVPalette) Update() {
+	p.updateFunc(func(f float64) [3]float64 {
+		h := p.hue0 + f*(p.hue1-p.hue0)
+		return hsvToRGB([3]float64{h - math.Floor(h), p.sat, p.val})
+	}, nil)
+}
+
+func (p *HSVPalette) Ready() bool {
+	return p.ready
+}
+
+// WriteSection schreibt die Palette als Abschnitt mit dem Namen name im
+// Format von palette.ini nach w.
+func (p *HSVPalette) WriteSection(w io.Writer, name string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", name)
+	p.writeOptions(bw)
+	fmt.Fprintf(bw, "hsv: %s %s %s %s\n", formatValue(p.hue0), formatValue(p.hue1),
+		formatValue(p.sat), formatValue(p.val))
+	fmt.Fprintln(bw)
+	return bw.Flush()
+}
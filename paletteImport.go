@@ -0,0 +1,385 @@
+package mandel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Die Funktionen in dieser Datei lesen Paletten, resp. Farbverlaeufe aus den
+// Dateiformaten anderer Fraktal- und Grafikprogramme ein und erstellen daraus
+// Paletten vom Typ [GradientPalette]. Da die Formate teilweise mehrere
+// Verlaeufe in einer Datei enthalten koennen, wird jeweils eine Liste von
+// NamedPalette retourniert.
+
+// NamedPalette verbindet eine importierte Palette mit ihrem Namen.
+type NamedPalette struct {
+	Name    string
+	Palette *GradientPalette
+}
+
+// SectionName erstellt aus einem beliebigen Namen einen gueltigen Namen fuer
+// einen Abschnitt in palette.ini. Es werden nur Buchstaben und Ziffern
+// uebernommen, Wortanfaenge werden dabei gross geschrieben.
+func SectionName(s string) string {
+	var sb strings.Builder
+
+	upper := true
+	for _, r := range s {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			sb.WriteRune(r)
+			upper = false
+		} else {
+			upper = true
+		}
+	}
+	if sb.Len() == 0 {
+		return "Imported"
+	}
+	return sb.String()
+}
+
+// newImportPalette erstellt aus einer Liste von Stuetzstellen eine neue
+// Palette mit der Interpolation ip.
+func newImportPalette(stops []ColorStop, ip InterpType) (*GradientPalette, error) {
+	p := NewGradientPalette()
+	p.SetInterp(&Interp{Type: ip})
+	for _, cs := range stops {
+		if err := p.AddColorStop(cs); err != nil {
+			return nil, err
+		}
+	}
+	if !p.Ready() {
+		return nil, errors.New("palette does not cover the interval [0,1]")
+	}
+	p.Update()
+	return p, nil
+}
+
+//-----------------------------------------------------------------------------
+//
+// Fractint (.map)
+//
+// Eine Map-Datei enthaelt pro Zeile eine Farbe als drei Ganzzahlen in [0,255],
+// optional gefolgt von einem Kommentar. Ueblich sind 256 Farben.
+
+var (
+	regxMapLine = regexp.MustCompile(`^\s*([0-9]+)\s+([0-9]+)\s+([0-9]+)`)
+)
+
+// Liest eine Fractint-Palette ein. Die Farben werden gleichmaessig ueber das
+// Intervall [0,1] verteilt und linear interpoliert.
+func ReadFractintMap(r io.Reader, name string) ([]NamedPalette, error) {
+	var colors [][3]float64
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		matches := regxMapLine.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, fmt.Errorf("line %d: invalid color '%s'", lineNo, line)
+		}
+		var c [3]float64
+		for i := range c {
+			v, err := strconv.Atoi(matches[i+1])
+			if err != nil || v > 255 {
+				return nil, fmt.Errorf("line %d: invalid color value '%s'", lineNo, matches[i+1])
+			}
+			c[i] = float64(v) / 255.0
+		}
+		colors = append(colors, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(colors) < 2 {
+		return nil, errors.New("a map file needs at least two colors")
+	}
+	stops := make([]ColorStop, len(colors))
+	for i, c := range colors {
+		stops[i] = ColorStop{Pos: float64(i) / float64(len(colors)-1),
+			R: c[0], G: c[1], B: c[2]}
+	}
+	p, err := newImportPalette(stops, InterpLinear)
+	if err != nil {
+		return nil, err
+	}
+	return []NamedPalette{{SectionName(name), p}}, nil
+}
+
+//-----------------------------------------------------------------------------
+//
+// Ultra Fractal (.ugr)
+//
+// Eine UGR-Datei enthaelt beliebig viele Verlaeufe der Form
+//
+//	name {
+//	gradient:
+//	  title="..." smooth=yes
+//	  index=0 color=16777215
+//	  index=100 color=255
+//	  ...
+//	opacity:
+//	  ...
+//	}
+//
+// Die Indizes liegen in [0,400) und der Verlauf ist zyklisch. Die Farben
+// sind als Ganzzahl im Format 0xBBGGRR abgelegt.
+
+const (
+	ugrNumIndices = 400
+)
+
+var (
+	regxUgrStart = regexp.MustCompile(`^\s*([^\s{]+)\s*\{\s*$`)
+	regxUgrTitle = regexp.MustCompile(`title="([^"]*)"`)
+	regxUgrNode  = regexp.MustCompile(`index=(-?[0-9]+)\s+color=([0-9]+)`)
+	regxUgrMode  = regexp.MustCompile(`^\s*([a-z]+):\s*$`)
+)
+
+type ugrNode struct {
+	index int
+	color [3]float64
+}
+
+// Liest alle Verlaeufe aus einer Ultra Fractal Gradient-Datei ein. Die
+// Deckkraft (Abschnitt 'opacity:') wird ignoriert.
+func ReadUltraFractalUgr(r io.Reader) ([]NamedPalette, error) {
+	var pals []NamedPalette
+	var nodes []ugrNode
+	var name, title, mode string
+	var smooth, inEntry bool
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if !inEntry {
+			if matches := regxUgrStart.FindStringSubmatch(line); matches != nil {
+				inEntry = true
+				name, title, mode = matches[1], "", ""
+				smooth = false
+				nodes = nodes[:0]
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "}" {
+			inEntry = false
+			if title != "" {
+				name = title
+			}
+			p, err := ugrPalette(nodes, smooth)
+			if err != nil {
+				return nil, fmt.Errorf("gradient '%s': %v", name, err)
+			}
+			pals = append(pals, NamedPalette{SectionName(name), p})
+			continue
+		}
+		if matches := regxUgrMode.FindStringSubmatch(line); matches != nil {
+			mode = matches[1]
+			continue
+		}
+		if mode != "gradient" {
+			continue
+		}
+		if matches := regxUgrTitle.FindStringSubmatch(line); matches != nil {
+			title = matches[1]
+		}
+		if strings.Contains(line, "smooth=yes") {
+			smooth = true
+		}
+		for _, matches := range regxUgrNode.FindAllStringSubmatch(line, -1) {
+			idx, err := strconv.Atoi(matches[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid index '%s'", lineNo, matches[1])
+			}
+			col, err := strconv.ParseUint(matches[2], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid color '%s'", lineNo, matches[2])
+			}
+			idx %= ugrNumIndices
+			if idx < 0 {
+				idx += ugrNumIndices
+			}
+			nodes = append(nodes, ugrNode{idx, [3]float64{
+				float64(col&0xff) / 255.0,
+				float64((col>>8)&0xff) / 255.0,
+				float64((col>>16)&0xff) / 255.0,
+			}})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if inEntry {
+		return nil, fmt.Errorf("gradient '%s' is not terminated", name)
+	}
+	return pals, nil
+}
+
+// ugrPalette erstellt aus den Knoten eines zyklischen UF-Verlaufs eine
+// Palette. Da die Paletten in palette.ini Stuetzstellen bei 0.0 und 1.0
+// brauchen, wird die Farbe an diesen Stellen aus dem letzten und dem ersten
+// Knoten interpoliert.
+func ugrPalette(nodes []ugrNode, smooth bool) (*GradientPalette, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("gradient has no colors")
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].index < nodes[j].index
+	})
+	first, last := nodes[0], nodes[len(nodes)-1]
+	span := float64(first.index + ugrNumIndices - last.index)
+	t := 0.0
+	if span > 0.0 {
+		t = float64(ugrNumIndices-last.index) / span
+	}
+	var wrap [3]float64
+	for i := range wrap {
+		wrap[i] = (1.0-t)*last.color[i] + t*first.color[i]
+	}
+
+	stops := make([]ColorStop, 0, len(nodes)+2)
+	if first.index != 0 {
+		stops = append(stops, ColorStop{Pos: 0.0, R: wrap[0], G: wrap[1], B: wrap[2]})
+	}
+	for i, n := range nodes {
+		if i > 0 && n.index == nodes[i-1].index {
+			continue
+		}
+		stops = append(stops, ColorStop{Pos: float64(n.index) / ugrNumIndices,
+			R: n.color[0], G: n.color[1], B: n.color[2]})
+	}
+	if first.index != 0 {
+		stops = append(stops, ColorStop{Pos: 1.0, R: wrap[0], G: wrap[1], B: wrap[2]})
+	} else {
+		stops = append(stops, ColorStop{Pos: 1.0, R: first.color[0],
+			G: first.color[1], B: first.color[2]})
+	}
+	ip := InterpLinear
+	if smooth {
+		ip = InterpCatmullRom
+	}
+	return newImportPalette(stops, ip)
+}
+
+//-----------------------------------------------------------------------------
+//
+// GIMP (.ggr)
+//
+// Eine GGR-Datei beginnt mit 'GIMP Gradient', dem Namen ('Name: ...') und
+// der Anzahl Segmente. Jedes Segment ist eine Zeile der Form
+//
+//	left mid right  r0 g0 b0 a0  r1 g1 b1 a1  type coloring [lflag rflag]
+//
+// Die Segmente werden mit je drei Stuetzstellen (links, Mitte, rechts)
+// nachgebildet. Die verschiedenen Blend-Typen von GIMP (curved, sine, ...)
+// und die Interpolation in HSV werden dabei durch lineare Interpolation
+// angenaehert, die Deckkraft wird ignoriert.
+
+const (
+	// ggrEdge ist der Abstand, um welchen die rechte Farbe eines Segmentes
+	// nach links verschoben wird, falls sie nicht mit der linken Farbe des
+	// naechsten Segmentes uebereinstimmt (harte Kante).
+	ggrEdge = 1.0e-4
+)
+
+// Liest einen GIMP-Farbverlauf ein.
+func ReadGimpGgr(r io.Reader) ([]NamedPalette, error) {
+	var name string
+	var segs [][11]float64
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "GIMP Gradient" {
+		return nil, errors.New("not a GIMP gradient file")
+	}
+	lineNo := 1
+	numSegs := -1
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "Name:") {
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Name:"))
+			continue
+		}
+		fields := strings.Fields(line)
+		if numSegs < 0 {
+			n, err := strconv.Atoi(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: expected number of segments", lineNo)
+			}
+			numSegs = n
+			continue
+		}
+		if len(fields) < 11 {
+			return nil, fmt.Errorf("line %d: segment has too few values", lineNo)
+		}
+		var seg [11]float64
+		for i := range seg {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value '%s'", lineNo, fields[i])
+			}
+			seg[i] = v
+		}
+		segs = append(segs, seg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(segs) == 0 || len(segs) != numSegs {
+		return nil, fmt.Errorf("expected %d segments, found %d", numSegs, len(segs))
+	}
+
+	stops := make([]ColorStop, 0, 3*len(segs))
+	for i, seg := range segs {
+		left, mid, right := seg[0], seg[1], seg[2]
+		c0 := [3]float64{seg[3], seg[4], seg[5]}
+		c1 := [3]float64{seg[7], seg[8], seg[9]}
+		if i == 0 || !ggrSameColor(segs[i-1], c0) {
+			stops = append(stops, ColorStop{Pos: left, R: c0[0], G: c0[1], B: c0[2]})
+		}
+		if mid > left && mid < right {
+			stops = append(stops, ColorStop{Pos: mid, R: 0.5 * (c0[0] + c1[0]),
+				G: 0.5 * (c0[1] + c1[1]), B: 0.5 * (c0[2] + c1[2])})
+		}
+		pos := right
+		if i < len(segs)-1 && !ggrSameColor(seg, [3]float64{segs[i+1][3],
+			segs[i+1][4], segs[i+1][5]}) {
+			pos = math.Max(right-ggrEdge, 0.5*(mid+right))
+		}
+		stops = append(stops, ColorStop{Pos: pos, R: c1[0], G: c1[1], B: c1[2]})
+	}
+	stops[0].Pos = 0.0
+	stops[len(stops)-1].Pos = 1.0
+	p, err := newImportPalette(stops, InterpLinear)
+	if err != nil {
+		return nil, err
+	}
+	return []NamedPalette{{SectionName(name), p}}, nil
+}
+
+// ggrSameColor prueft, ob die rechte Farbe des Segmentes seg mit der Farbe c
+// uebereinstimmt.
+func ggrSameColor(seg [11]float64, c [3]float64) bool {
+	return seg[7] == c[0] && seg[8] == c[1] && seg[9] == c[2]
+}
@@ -0,0 +1,120 @@
+package mandel
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestImportPalettes(t *testing.T) {
+	const ugr = `Test {
+gradient:
+  title="My Grad" smooth=no
+  index=100 color=255
+  index=700 color=16711680
+opacity:
+  index=0 opacity=255
+}
+`
+	const ggr = `GIMP Gradient
+Name: Hard Edge
+2
+0.0 0.25 0.5  1 0 0 1  0 1 0 1  0 0
+0.5 0.75 1.0  0 0 1 1  1 1 1 1  0 0
+`
+	tests := []struct {
+		name  string
+		read  func(s string) ([]NamedPalette, error)
+		data  string
+		want  string
+		stops []ColorStop
+	}{
+		{"fractint", func(s string) ([]NamedPalette, error) {
+			return ReadFractintMap(strings.NewReader(s), "my map")
+		}, "0 0 0\n\n255 51 0  Kommentar\n", "MyMap", []ColorStop{
+			{Pos: 0.0},
+			{Pos: 1.0, R: 1.0, G: 0.2},
+		}},
+		// Farben im Format 0xBBGGRR; der Index 700 liegt zyklisch bei 300
+		// und bei 0.0 und 1.0 wird zwischen den beiden Farben interpoliert.
+		{"ugr", func(s string) ([]NamedPalette, error) {
+			return ReadUltraFractalUgr(strings.NewReader(s))
+		}, ugr, "MyGrad", []ColorStop{
+			{Pos: 0.0, R: 0.5, B: 0.5},
+			{Pos: 0.25, R: 1.0},
+			{Pos: 0.75, B: 1.0},
+			{Pos: 1.0, R: 0.5, B: 0.5},
+		}},
+		// Die Mitte jedes Segmentes ergibt eine eigene Stuetzstelle, der
+		// Farbwechsel bei 0.5 eine harte Kante.
+		{"ggr", func(s string) ([]NamedPalette, error) {
+			return ReadGimpGgr(strings.NewReader(s))
+		}, ggr, "HardEdge", []ColorStop{
+			{Pos: 0.0, R: 1.0},
+			{Pos: 0.25, R: 0.5, G: 0.5},
+			{Pos: 0.5 - ggrEdge, G: 1.0},
+			{Pos: 0.5, B: 1.0},
+			{Pos: 0.75, R: 0.5, G: 0.5, B: 1.0},
+			{Pos: 1.0, R: 1.0, G: 1.0, B: 1.0},
+		}},
+	}
+	for _, test := range tests {
+		pals, err := test.read(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(pals) != 1 {
+			t.Errorf("%s: got %d palettes, want 1", test.name, len(pals))
+			continue
+		}
+		if pals[0].Name != test.want {
+			t.Errorf("%s: name is '%s', want '%s'", test.name, pals[0].Name, test.want)
+		}
+		stops := pals[0].Palette.ColorStops()
+		if len(stops) != len(test.stops) {
+			t.Errorf("%s: got %d stops, want %d: %v", test.name, len(stops), len(test.stops), stops)
+			continue
+		}
+		for i, s := range stops {
+			w := test.stops[i]
+			d := math.Max(math.Abs(s.Pos-w.Pos), math.Max(math.Abs(s.R-w.R),
+				math.Max(math.Abs(s.G-w.G), math.Abs(s.B-w.B))))
+			if d > 1e-9 {
+				t.Errorf("%s: stop %d = %+v, want %+v", test.name, i, s, w)
+			}
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(s string) ([]NamedPalette, error)
+		data string
+	}{
+		{"fractint value", func(s string) ([]NamedPalette, error) {
+			return ReadFractintMap(strings.NewReader(s), "M")
+		}, "0 0 0\n256 0 0\n"},
+		{"fractint truncated", func(s string) ([]NamedPalette, error) {
+			return ReadFractintMap(strings.NewReader(s), "M")
+		}, "0 0 0\n255 255\n"},
+		{"ugr truncated", func(s string) ([]NamedPalette, error) {
+			return ReadUltraFractalUgr(strings.NewReader(s))
+		}, "Test {\ngradient:\n  index=0 color=255\n"},
+		{"ugr index", func(s string) ([]NamedPalette, error) {
+			return ReadUltraFractalUgr(strings.NewReader(s))
+		}, "Test {\ngradient:\n  index=0 color=255\n  index=99999999999999999999 color=0\n}\n"},
+		{"ggr segments", func(s string) ([]NamedPalette, error) {
+			return ReadGimpGgr(strings.NewReader(s))
+		}, "GIMP Gradient\nName: G\n2\n0.0 0.5 1.0  0 0 0 1  1 1 1 1  0 0\n"},
+		{"ggr truncated", func(s string) ([]NamedPalette, error) {
+			return ReadGimpGgr(strings.NewReader(s))
+		}, "GIMP Gradient\nName: G\n1\n0.0 0.5 1.0  0 0 0 1  1 1\n"},
+	}
+	for _, test := range tests {
+		if _, err := test.read(test.data); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package mandel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Der Typ InterpType bezeichnet die Art der Kurve, mit welcher zwischen zwei
+// Stuetzwerten einer [GradientPalette] interpoliert wird. Linear, kubisch und
+// Bezier sind reine Ueberblendfunktionen (siehe [InterpFunc]) und beruecksich-
+// tigen nur die beiden Stuetzwerte eines Abschnittes. Catmull-Rom und die
+// monotone kubische Interpolation sind Splines, welche auch die benachbarten
+// Stuetzwerte einbeziehen und so fliessende Uebergaenge ueber die Stuetz-
+// stellen hinweg ergeben. Die monotone Variante ueberschwingt dabei nie.
+type InterpType int
+
+const (
+	InterpLinear InterpType = iota
+	InterpCubic
+	InterpCatmullRom
+	InterpMonotone
+	InterpBezier
+)
+
+func (it InterpType) String() string {
+	switch it {
+	case InterpLinear:
+		return "linear"
+	case InterpCubic:
+		return "cubic"
+	case InterpCatmullRom:
+		return "catmullrom"
+	case InterpMonotone:
+		return "monotone"
+	case InterpBezier:
+		return "bezier"
+	default:
+		return "Unknown interpolation"
+	}
+}
+
+func (it *InterpType) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "linear":
+		*it = InterpLinear
+	case "cubic":
+		*it = InterpCubic
+	case "catmullrom":
+		*it = InterpCatmullRom
+	case "monotone":
+		*it = InterpMonotone
+	case "bezier":
+		*it = InterpBezier
+	default:
+		return errors.New("Unknown interpolation: " + s)
+	}
+	return nil
+}
+
+var (
+	// defBezierCtrl sind die Kontrollpunkte fuer eine Bezier-Interpolation,
+	// falls keine angegeben werden ('ease-in-out').
+	defBezierCtrl = [4]float64{0.42, 0.0, 0.58, 1.0}
+)
+
+// Interp beschreibt die Interpolation zwischen Stuetzwerten vollstaendig:
+// die Art der Kurve und - bei Bezier - die beiden inneren Kontrollpunkte
+// (x1, y1, x2, y2) der Ueberblendfunktion.
+type Interp struct {
+	Type InterpType
+	Ctrl [4]float64
+}
+
+// Erstellt aus einem Text wie 'cubic' oder 'bezier 0.25 0.1 0.25 1.0' eine
+// neue Interpolation.
+func ParseInterp(s string) (*Interp, error) {
+	ip := &Interp{}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, errors.New("missing interpolation")
+	}
+	if err := ip.Type.Set(fields[0]); err != nil {
+		return nil, err
+	}
+	args := fields[1:]
+	if ip.Type != InterpBezier {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("interpolation '%s' takes no arguments", fields[0])
+		}
+		return ip, nil
+	}
+	switch len(args) {
+	case 0:
+		ip.Ctrl = defBezierCtrl
+	case 4:
+		for i, arg := range args {
+			v, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, err
+			}
+			ip.Ctrl[i] = v
+		}
+		if ip.Ctrl[0] < 0.0 || ip.Ctrl[0] > 1.0 || ip.Ctrl[2] < 0.0 || ip.Ctrl[2] > 1.0 {
+			return nil, errors.New("bezier x values must be in [0,1]")
+		}
+	default:
+		return nil, errors.New("bezier needs 0 or 4 arguments")
+	}
+	return ip, nil
+}
+
+func (ip *Interp) String() string {
+	if ip.Type == InterpBezier {
+		return fmt.Sprintf("%v %g %g %g %g", ip.Type, ip.Ctrl[0], ip.Ctrl[1],
+			ip.Ctrl[2], ip.Ctrl[3])
+	}
+	return ip.Type.String()
+}
+
+// Func liefert fuer die Ueberblend-Interpolationen (linear, kubisch, Bezier)
+// die entsprechende [InterpFunc]. Fuer Splines wird nil retourniert.
+func (ip *Interp) Func() InterpFunc {
+	switch ip.Type {
+	case InterpLinear:
+		return LinInterpFunc
+	case InterpCubic:
+		return CubicInterpFunc
+	case InterpBezier:
+		return NewBezierInterpFunc(ip.Ctrl[0], ip.Ctrl[1], ip.Ctrl[2], ip.Ctrl[3])
+	default:
+		return nil
+	}
+}
+
+// Erstellt eine Interpolationsfunktion aus einer kubischen Bezier-Kurve mit
+// den Endpunkten (0,0) und (1,1) und den Kontrollpunkten (x1,y1) und (x2,y2)
+// - analog zu 'cubic-bezier' in CSS. x1 und x2 muessen in [0,1] liegen.
+func NewBezierInterpFunc(x1, y1, x2, y2 float64) InterpFunc {
+	bez := func(s, p1, p2 float64) float64 {
+		r := 1.0 - s
+		return 3.0*r*r*s*p1 + 3.0*r*s*s*p2 + s*s*s
+	}
+	return func(t float64) float64 {
+		// Die Endpunkte werden direkt retourniert, da die Bisektion bei
+		// Kontrollpunkten mit x=0 oder x=1 dort nicht genau genug ist.
+		if t <= 0.0 {
+			return 0.0
+		}
+		if t >= 1.0 {
+			return 1.0
+		}
+		// Da x(s) monoton ist, kann s mit Bisektion bestimmt werden.
+		s0, s1 := 0.0, 1.0
+		for range 40 {
+			s := 0.5 * (s0 + s1)
+			if bez(s, x1, x2) < t {
+				s0 = s
+			} else {
+				s1 = s
+			}
+		}
+		return bez(0.5*(s0+s1), y1, y2)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// curve ist eine Kurve durch die Stuetzwerte (pos[i], val[i]). Fuer jeden
+// Abschnitt i (zwischen pos[i] und pos[i+1]) kann eine eigene Interpolation
+// verwendet werden. Die Tangenten fuer die Splines werden beim Erstellen der
+// Kurve einmal berechnet.
+type curve struct {
+	pos, val       []float64
+	interp         []*Interp
+	fnc            []InterpFunc
+	tanCR, tanMono []float64
+}
+
+// Erstellt eine neue Kurve. interp enthaelt fuer jeden Stuetzwert die
+// Interpolation zum naechsten Stuetzwert; nil-Werte werden durch def ersetzt.
+func newCurve(pos, val []float64, interp []*Interp, def *Interp) *curve {
+	n := len(pos)
+	c := &curve{pos: pos, val: val}
+	c.interp = make([]*Interp, n)
+	c.fnc = make([]InterpFunc, n)
+	for i := range n {
+		if i < len(interp) && interp[i] != nil {
+			c.interp[i] = interp[i]
+		} else {
+			c.interp[i] = def
+		}
+		c.fnc[i] = c.interp[i].Func()
+	}
+	c.tanCR = make([]float64, n)
+	c.tanMono = make([]float64, n)
+	if n < 2 {
+		return c
+	}
+
+	// Steigungen der einzelnen Abschnitte.
+	d := make([]float64, n-1)
+	for i := range n - 1 {
+		d[i] = (val[i+1] - val[i]) / (pos[i+1] - pos[i])
+	}
+
+	// Catmull-Rom (fuer ungleichmaessige Abstaende): zentrale Differenzen im
+	// Innern, einseitige Differenzen an den Enden.
+	c.tanCR[0], c.tanCR[n-1] = d[0], d[n-2]
+	for i := 1; i < n-1; i++ {
+		c.tanCR[i] = (val[i+1] - val[i-1]) / (pos[i+1] - pos[i-1])
+	}
+
+	// Monotone kubische Interpolation nach Fritsch-Carlson.
+	c.tanMono[0], c.tanMono[n-1] = d[0], d[n-2]
+	for i := 1; i < n-1; i++ {
+		if d[i-1]*d[i] <= 0.0 {
+			c.tanMono[i] = 0.0
+		} else {
+			c.tanMono[i] = 0.5 * (d[i-1] + d[i])
+		}
+	}
+	for i := range n - 1 {
+		if d[i] == 0.0 {
+			c.tanMono[i], c.tanMono[i+1] = 0.0, 0.0
+			continue
+		}
+		a := c.tanMono[i] / d[i]
+		b := c.tanMono[i+1] / d[i]
+		if h := a*a + b*b; h > 9.0 {
+			tau := 3.0 / math.Sqrt(h)
+			c.tanMono[i] = tau * a * d[i]
+			c.tanMono[i+1] = tau * b * d[i]
+		}
+	}
+	return c
+}
+
+// value liefert den Wert der Kurve an der Stelle f. Ausserhalb des Bereichs
+// der Stuetzstellen wird der erste, resp. letzte Stuetzwert retourniert.
+func (c *curve) value(f float64) float64 {
+	n := len(c.pos)
+	i := sort.SearchFloat64s(c.pos, f)
+	if i < n && c.pos[i] == f {
+		return c.val[i]
+	}
+	if i == 0 {
+		return c.val[0]
+	}
+	if i == n {
+		return c.val[n-1]
+	}
+	i--
+	h := c.pos[i+1] - c.pos[i]
+	t := (f - c.pos[i]) / h
+	switch c.interp[i].Type {
+	case InterpCatmullRom:
+		return hermite(c.val[i], c.val[i+1], h*c.tanCR[i], h*c.tanCR[i+1], t)
+	case InterpMonotone:
+		return hermite(c.val[i], c.val[i+1], h*c.tanMono[i], h*c.tanMono[i+1], t)
+	default:
+		return InterpValue(c.val[i], c.val[i+1], t, c.fnc[i])
+	}
+}
+
+// hermite berechnet den Wert eines kubischen Hermite-Splines mit den
+// Endwerten v0, v1 und den (bereits auf das Intervall skalierten)
+// Tangenten m0, m1 an der Stelle t in [0,1].
+func hermite(v0, v1, m0, m1, t float64) float64 {
+	t2 := t * t
+	t3 := t2 * t
+	return (2.0*t3-3.0*t2+1.0)*v0 + (t3-2.0*t2+t)*m0 +
+		(-2.0*t3+3.0*t2)*v1 + (t3-t2)*m1
+}
@@ -0,0 +1,86 @@
+package mandel
+
+import (
+	"math"
+	"testing"
+)
+
+// curveOvershoot liefert die groesste Abweichung der Kurve c ausserhalb der
+// Werte der jeweils benachbarten Stuetzstellen.
+func curveOvershoot(c *curve) float64 {
+	const samples = 200
+
+	res := 0.0
+	for i := 0; i < len(c.pos)-1; i++ {
+		lo := math.Min(c.val[i], c.val[i+1])
+		hi := math.Max(c.val[i], c.val[i+1])
+		for j := 0; j <= samples; j++ {
+			f := c.pos[i] + (c.pos[i+1]-c.pos[i])*float64(j)/samples
+			v := c.value(f)
+			res = math.Max(res, math.Max(lo-v, v-hi))
+		}
+	}
+	return res
+}
+
+func TestMonotoneCurve(t *testing.T) {
+	// Treppenartige Daten mit ungleichmaessigen Abstaenden.
+	pos := []float64{0.0, 0.2, 0.25, 0.5, 0.55, 0.6, 0.9, 1.0}
+	val := []float64{0.0, 0.0, 1.0, 1.0, 0.1, 0.1, 0.9, 1.0}
+
+	mono := newCurve(pos, val, nil, &Interp{Type: InterpMonotone})
+	if d := curveOvershoot(mono); d > 1.0e-12 {
+		t.Errorf("monotone curve overshoots by %v", d)
+	}
+	for i := range pos {
+		if v := mono.value(pos[i]); v != val[i] {
+			t.Errorf("value at %v is %v, want %v", pos[i], v, val[i])
+		}
+	}
+
+	// Mit den gleichen Daten schwingt Catmull-Rom ueber; sonst waeren die
+	// Daten fuer diesen Test ungeeignet.
+	cr := newCurve(pos, val, nil, &Interp{Type: InterpCatmullRom})
+	if d := curveOvershoot(cr); d < 0.01 {
+		t.Errorf("catmull-rom curve doesn't overshoot (%v)", d)
+	}
+}
+
+func TestBezierInterp(t *testing.T) {
+	const samples = 1000
+
+	for _, ctrl := range [][4]float64{
+		defBezierCtrl,
+		{0.25, 0.1, 0.25, 1.0},
+		{0.42, 0.0, 1.0, 1.0},
+		{0.0, 0.0, 0.58, 1.0},
+		{1.0 / 3.0, 1.0 / 3.0, 2.0 / 3.0, 2.0 / 3.0},
+		{0.0, 1.0, 1.0, 0.0},
+	} {
+		fnc := NewBezierInterpFunc(ctrl[0], ctrl[1], ctrl[2], ctrl[3])
+		if v := fnc(0.0); v != 0.0 {
+			t.Errorf("%v: f(0) = %v, want 0", ctrl, v)
+		}
+		if v := fnc(1.0); v != 1.0 {
+			t.Errorf("%v: f(1) = %v, want 1", ctrl, v)
+		}
+		prev := fnc(0.0)
+		for i := 1; i <= samples; i++ {
+			v := fnc(float64(i) / samples)
+			if v < prev-1.0e-9 {
+				t.Errorf("%v: not monotone at %v (%v < %v)", ctrl,
+					float64(i)/samples, v, prev)
+				break
+			}
+			prev = v
+		}
+	}
+
+	// Die Kontrollpunkte (1/3,1/3) und (2/3,2/3) ergeben eine Gerade.
+	fnc := NewBezierInterpFunc(1.0/3.0, 1.0/3.0, 2.0/3.0, 2.0/3.0)
+	for _, f := range []float64{0.1, 0.5, 0.8} {
+		if v := fnc(f); math.Abs(v-f) > 1.0e-9 {
+			t.Errorf("linear bezier: f(%v) = %v", f, v)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package mandel
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Neben dem Format von palette.ini koennen Paletten auch als JSON
+// gespeichert werden. Damit beim Einlesen der Typ der Palette bekannt ist,
+// enthaelt jedes JSON-Objekt das Feld 'type'. Fuer das Lesen und Schreiben
+// von Paletten beliebigen Typs sind [MarshalPalette] und [UnmarshalPalette]
+// zu verwenden.
+
+const (
+	gradientJSONType  = "gradient"
+	procJSONType      = "procedural"
+	hsvJSONType       = "hsv"
+	mapJSONType       = "map"
+	cubehelixJSONType = "cubehelix"
+	fourierJSONType   = "fourier"
+)
+
+// baseJSON enthaelt die Felder, welche bei allen Paletten vorkommen.
+type baseJSON struct {
+	Type     string `json:"type"`
+	Size     int    `json:"size,omitempty"`
+	Inside   string `json:"inside,omitempty"`
+	Transfer string `json:"transfer,omitempty"`
+}
+
+// baseJSON liefert die gemeinsamen Felder der JSON-Darstellung fuer eine
+// Palette vom Typ typ.
+func (p *basePalette) baseJSON(typ string) baseJSON {
+	bj := baseJSON{Type: typ}
+	if p.tableSize != palSize {
+		bj.Size = p.tableSize
+	}
+	if p.inside != defInsideColor {
+		bj.Inside = FormatColor(p.inside)
+	}
+	if p.transfer != (Transfer{}) {
+		bj.Transfer = p.transfer.String()
+	}
+	return bj
+}
+
+// setBaseJSON uebernimmt die gemeinsamen Felder aus bj, wobei geprueft wird,
+// ob die JSON-Darstellung vom Typ typ ist.
+func (p *basePalette) setBaseJSON(bj baseJSON, typ string) error {
+	if bj.Type != typ {
+		return fmt.Errorf("not a %s palette", typ)
+	}
+	if bj.Inside != "" {
+		if err := p.SetOption("inside", bj.Inside); err != nil {
+			return err
+		}
+	}
+	if bj.Transfer != "" {
+		if err := p.SetOption("transfer", bj.Transfer); err != nil {
+			return err
+		}
+	}
+	if bj.Size != 0 {
+		return p.SetTableSize(bj.Size)
+	}
+	return nil
+}
+
+type gradientJSON struct {
+	baseJSON
+	Space  string         `json:"space,omitempty"`
+	Hue    string         `json:"hue,omitempty"`
+	Interp string         `json:"interp,omitempty"`
+	Stops  []gradStopJSON `json:"stops"`
+}
+
+// Bei einer Stuetzstelle ist der Wert eines Farbkanals null, falls dieser
+// Kanal an dieser Position keinen Stuetzwert hat ('-' in palette.ini). Der
+// Alpha-Kanal wird separat und nur bei Bedarf abgelegt.
+type gradStopJSON struct {
+	Pos    float64     `json:"pos"`
+	Values [3]*float64 `json:"values"`
+	Alpha  *float64    `json:"alpha,omitempty"`
+	Interp string      `json:"interp,omitempty"`
+}
+
+// value liefert einen Zeiger auf den Wert des Kanals col.
+func (sj *gradStopJSON) value(col BaseColorType) **float64 {
+	if col == Alpha {
+		return &sj.Alpha
+	}
+	return &sj.Values[col]
+}
+
+type procJSON struct {
+	baseJSON
+	Params [][]float64 `json:"params"`
+}
+
+type hsvJSON struct {
+	baseJSON
+	Hue [2]float64 `json:"hue"`
+	Sat float64    `json:"sat"`
+	Val float64    `json:"val"`
+}
+
+type mapJSON struct {
+	baseJSON
+	Map string `json:"map"`
+}
+
+type cubehelixJSON struct {
+	baseJSON
+	Start     float64 `json:"start"`
+	Rotations float64 `json:"rotations"`
+	Hue       float64 `json:"hue"`
+	Gamma     float64 `json:"gamma"`
+}
+
+type fourierJSON struct {
+	baseJSON
+	Coeffs [][]float64 `json:"coeffs"`
+}
+
+// Erstellt die JSON-Darstellung der Palette p.
+func MarshalPalette(p Palette) ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// Erstellt aus der JSON-Darstellung in data eine neue Palette. Der Typ der
+// Palette wird dem Feld 'type' entnommen.
+func UnmarshalPalette(data []byte) (Palette, error) {
+	var head struct {
+		Type string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	pt := paletteTypeByName(head.Type)
+	if pt == nil {
+		return nil, fmt.Errorf("unknown palette type '%s'", head.Type)
+	}
+	p := pt.newPalette()
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	if !p.Ready() {
+		return nil, fmt.Errorf("some values are missing for this palette")
+	}
+	p.Update()
+	return p, nil
+}
+
+func (p *GradientPalette) MarshalJSON() ([]byte, error) {
+	var posList []float64
+
+	pj := gradientJSON{baseJSON: p.baseJSON(gradientJSONType)}
+	if p.space != SpaceRGB {
+		pj.Space = p.space.String()
+	}
+	if p.huePath != HueShortest {
+		pj.Hue = p.huePath.String()
+	}
+	if p.interp.Type != InterpCubic {
+		pj.Interp = p.interp.String()
+	}
+	stops := make(map[float64]*gradStopJSON)
+	for i := Red; i < NumChannels; i++ {
+		for _, gp := range p.GradPointList(i) {
+			sj, ok := stops[gp.Pos]
+			if !ok {
+				sj = &gradStopJSON{Pos: gp.Pos}
+				stops[gp.Pos] = sj
+				posList = append(posList, gp.Pos)
+			}
+			v := gp.Val
+			*sj.value(i) = &v
+			if gp.Interp != nil {
+				sj.Interp = gp.Interp.String()
+			}
+		}
+	}
+	for _, pos := range sortedUnique(posList) {
+		pj.Stops = append(pj.Stops, *stops[pos])
+	}
+	return json.Marshal(pj)
+}
+
+func (p *GradientPalette) UnmarshalJSON(data []byte) error {
+	var pj gradientJSON
+	var ip *Interp
+	var err error
+
+	if err = json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if err = p.setBaseJSON(pj.baseJSON, gradientJSONType); err != nil {
+		return err
+	}
+	if pj.Space != "" {
+		if err = p.space.Set(pj.Space); err != nil {
+			return err
+		}
+	}
+	if pj.Hue != "" {
+		if err = p.huePath.Set(pj.Hue); err != nil {
+			return err
+		}
+	}
+	if pj.Interp != "" {
+		if p.interp, err = ParseInterp(pj.Interp); err != nil {
+			return err
+		}
+	}
+	for _, sj := range pj.Stops {
+		ip = nil
+		if sj.Interp != "" {
+			if ip, err = ParseInterp(sj.Interp); err != nil {
+				return err
+			}
+		}
+		for i := Red; i < NumChannels; i++ {
+			v := *sj.value(i)
+			if v == nil {
+				continue
+			}
+			if err = p.AddGradPoint(i, &GradPoint{sj.Pos, *v, ip}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *ProcPalette) MarshalJSON() ([]byte, error) {
+	return json.Marshal(procJSON{p.baseJSON(procJSONType), p.channels()})
+}
+
+func (p *ProcPalette) UnmarshalJSON(data []byte) error {
+	var pj procJSON
+
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if err := p.setBaseJSON(pj.baseJSON, procJSONType); err != nil {
+		return err
+	}
+	if len(pj.Params) != int(NumBaseColors) && len(pj.Params) != int(NumChannels) {
+		return fmt.Errorf("expected parameters for %d colors (and alpha)", NumBaseColors)
+	}
+	for col, v := range pj.Params {
+		if len(v) != int(NumProcParams) {
+			return fmt.Errorf("expected %d parameters for color %v",
+				NumProcParams, BaseColorType(col))
+		}
+		p.SetParamList(BaseColorType(col), v)
+	}
+	return nil
+}
+
+func (p *HSVPalette) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hsvJSON{p.baseJSON(hsvJSONType), [2]float64{p.hue0, p.hue1}, p.sat, p.val})
+}
+
+func (p *HSVPalette) UnmarshalJSON(data []byte) error {
+	var pj hsvJSON
+
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if err := p.setBaseJSON(pj.baseJSON, hsvJSONType); err != nil {
+		return err
+	}
+	return p.SetParams(pj.Hue[0], pj.Hue[1], pj.Sat, pj.Val)
+}
+
+func (p *MapPalette) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mapJSON{p.baseJSON(mapJSONType), p.name})
+}
+
+func (p *MapPalette) UnmarshalJSON(data []byte) error {
+	var pj mapJSON
+
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if err := p.setBaseJSON(pj.baseJSON, mapJSONType); err != nil {
+		return err
+	}
+	return p.SetMap(pj.Map)
+}
+
+func (p *CubehelixPalette) MarshalJSON() ([]byte, error) {
+	return json.Marshal(cubehelixJSON{p.baseJSON(cubehelixJSONType), p.start, p.rot, p.hue, p.gamma})
+}
+
+func (p *CubehelixPalette) UnmarshalJSON(data []byte) error {
+	var pj cubehelixJSON
+
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if err := p.setBaseJSON(pj.baseJSON, cubehelixJSONType); err != nil {
+		return err
+	}
+	return p.SetParams(pj.Start, pj.Rotations, pj.Hue, pj.Gamma)
+}
+
+func (p *FourierPalette) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fourierJSON{p.baseJSON(fourierJSONType), p.channels()})
+}
+
+func (p *FourierPalette) UnmarshalJSON(data []byte) error {
+	var pj fourierJSON
+
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if err := p.setBaseJSON(pj.baseJSON, fourierJSONType); err != nil {
+		return err
+	}
+	if len(pj.Coeffs) != int(NumBaseColors) && len(pj.Coeffs) != int(NumChannels) {
+		return fmt.Errorf("expected coefficients for %d colors (and alpha)", NumBaseColors)
+	}
+	for col, v := range pj.Coeffs {
+		if err := p.SetCoeffs(BaseColorType(col), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package mandel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic beschreibt ein Problem, welches beim Pruefen einer Datei mit
+// Paletten gefunden wurde. Zeile und Spalte beginnen jeweils bei 1.
+type Diagnostic struct {
+	File      string
+	Line, Col int
+	Msg       string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Col, d.Msg)
+}
+
+// lintSection enthaelt den Zustand beim Pruefen eines einzelnen Abschnittes.
+type lintSection struct {
+	name      string
+	line      int
+	pal       Palette
+	palType   *paletteType
+	options   []paletteOption
+	optCols   []int
+	stopLines map[float64]int
+	lastPos   float64
+	procLines map[byte]int
+}
+
+// linter sammelt die Diagnosen fuer eine Datei.
+type linter struct {
+	fileName string
+	diags    []Diagnostic
+	sections map[string]int
+	sect     *lintSection
+}
+
+func (l *linter) report(line, col int, format string, args ...any) {
+	l.diags = append(l.diags, Diagnostic{l.fileName, line, col, fmt.Sprintf(format, args...)})
+}
+
+// LintPalettes prueft alle Paletten, welche im Format von palette.ini aus r
+// gelesen werden, und liefert eine Liste mit allen gefundenen Problemen.
+// Im Gegensatz zu [ReadPalette] bricht die Pruefung beim ersten Fehler nicht
+// ab. Gemeldet werden u.A. doppelte Abschnittsnamen, unbekannte Optionen,
+// ungueltige Zahlen, Werte ausserhalb von [0,1], doppelte oder nicht
+// aufsteigend sortierte Stuetzstellen und Farbkanaele, denen die
+// Stuetzstellen bei 0.0 oder 1.0 fehlen. fileName wird nur fuer die
+// Diagnosen verwendet. Der Fehler ist nur bei Lesefehlern ungleich nil.
+func LintPalettes(r io.Reader, fileName string) ([]Diagnostic, error) {
+	l := &linter{fileName: fileName, sections: make(map[string]int)}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		l.lintLine(scanner.Text(), lineNo)
+	}
+	l.finishSection()
+	return l.diags, scanner.Err()
+}
+
+// firstCol liefert die Spalte des ersten Zeichens, welches kein Leerzeichen
+// ist.
+func firstCol(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t")) + 1
+}
+
+func (l *linter) lintLine(line string, lineNo int) {
+	if regxComm.MatchString(line) {
+		return
+	}
+	if m := regxSection.FindStringSubmatchIndex(line); m != nil {
+		l.finishSection()
+		name := line[m[2]:m[3]]
+		if first, ok := l.sections[name]; ok {
+			l.report(lineNo, m[2]+1, "duplicate palette name '%s' (first defined on line %d)",
+				name, first)
+		} else {
+			l.sections[name] = lineNo
+		}
+		l.sect = &lintSection{name: name, line: lineNo,
+			stopLines: make(map[float64]int), lastPos: -1.0,
+			procLines: make(map[byte]int)}
+		return
+	}
+	if l.sect == nil {
+		l.report(lineNo, firstCol(line), "data outside of a palette section")
+		return
+	}
+	s := l.sect
+	if m := regxOption.FindStringSubmatchIndex(line); m != nil {
+		opt := paletteOption{line[m[2]:m[3]], line[m[4]:m[5]], lineNo}
+		if s.pal == nil {
+			s.options = append(s.options, opt)
+			s.optCols = append(s.optCols, m[2]+1, m[4]+1)
+		} else {
+			l.lintOption(opt, m[2]+1, m[4]+1)
+		}
+		return
+	}
+	pt := paletteTypeOf(line)
+	if pt == nil {
+		l.report(lineNo, firstCol(line), "unrecognized line '%s'", strings.TrimSpace(line))
+		return
+	}
+	if s.pal == nil {
+		s.palType = pt
+		l.createPalette(pt.newPalette())
+	}
+	if pt != s.palType {
+		l.report(lineNo, firstCol(line), "%s data in a %s palette", pt.name, s.palType.name)
+		return
+	}
+	switch s.pal.(type) {
+	case *GradientPalette:
+		l.lintGradientLine(line, lineNo)
+	case *ProcPalette:
+		l.lintProcLine(line, lineNo)
+	default:
+		if err := s.pal.ProcessLine(line); err != nil {
+			l.report(lineNo, firstCol(line), "%v", err)
+		}
+	}
+}
+
+// createPalette hinterlegt die Palette p fuer den aktuellen Abschnitt und
+// wendet die bisher gesammelten Optionen darauf an.
+func (l *linter) createPalette(p Palette) {
+	l.sect.pal = p
+	for i, opt := range l.sect.options {
+		l.lintOption(opt, l.sect.optCols[2*i], l.sect.optCols[2*i+1])
+	}
+}
+
+func (l *linter) lintOption(opt paletteOption, keyCol, valCol int) {
+	if err := l.sect.pal.SetOption(opt.key, opt.value); err != nil {
+		if errors.Is(err, ErrUnknownOption) {
+			l.report(opt.lineNo, keyCol, "unknown keyword '%s'", opt.key)
+		} else {
+			l.report(opt.lineNo, valCol, "invalid value for '%s': %v", opt.key, err)
+		}
+	}
+}
+
+// lintNumber prueft die Zahl in line[start:end]; ist sie gueltig, wird sie
+// retourniert. Mit checkRange wird ausserdem geprueft, ob sie in [0,1] liegt.
+func (l *linter) lintNumber(line string, lineNo, start, end int,
+	checkRange bool) (float64, bool) {
+	v, err := strconv.ParseFloat(line[start:end], 64)
+	if err != nil {
+		l.report(lineNo, start+1, "invalid number '%s'", line[start:end])
+		return 0.0, false
+	}
+	if checkRange && (v < 0.0 || v > 1.0) {
+		l.report(lineNo, start+1, "value %s is outside of [0,1]", line[start:end])
+		return v, false
+	}
+	return v, true
+}
+
+func (l *linter) lintGradientLine(line string, lineNo int) {
+	s := l.sect
+	m := gradientPalRegexp.FindStringSubmatchIndex(line)
+	pos, ok := l.lintNumber(line, lineNo, m[2], m[3], true)
+	if !ok {
+		return
+	}
+	if first, dup := s.stopLines[pos]; dup {
+		l.report(lineNo, m[2]+1, "duplicate stop position %s (first defined on line %d)",
+			line[m[2]:m[3]], first)
+	} else {
+		s.stopLines[pos] = lineNo
+		if pos < s.lastPos {
+			l.report(lineNo, m[2]+1, "stop position %s is out of order (previous: %s)",
+				line[m[2]:m[3]], formatValue(s.lastPos))
+		}
+	}
+	s.lastPos = max(s.lastPos, pos)
+
+	valid := true
+	if m[4] < 0 {
+		numValues := 0
+		for i := 3; i < 7; i++ {
+			start, end := m[2*i], m[2*i+1]
+			if start < 0 || line[start:end] == "-" {
+				continue
+			}
+			numValues++
+			if _, ok := l.lintNumber(line, lineNo, start, end, true); !ok {
+				valid = false
+			}
+		}
+		if numValues == 0 {
+			l.report(lineNo, m[6]+1, "stop defines no values")
+		}
+	}
+	if m[14] >= 0 {
+		if _, err := ParseInterp(line[m[14]:m[15]]); err != nil {
+			l.report(lineNo, m[14]+1, "invalid interpolation: %v", err)
+			valid = false
+		}
+	}
+	if valid {
+		s.pal.ProcessLine(line)
+	}
+}
+
+func (l *linter) lintProcLine(line string, lineNo int) {
+	s := l.sect
+	m := procPalRegexp.FindStringSubmatchIndex(line)
+	col := line[m[2]]
+	if first, dup := s.procLines[col]; dup {
+		l.report(lineNo, m[2]+1, "duplicate parameters for channel '%c' (first defined on line %d)",
+			col, first)
+	} else {
+		s.procLines[col] = lineNo
+	}
+	valid := true
+	for i := 2; i < 6; i++ {
+		if _, ok := l.lintNumber(line, lineNo, m[2*i], m[2*i+1], false); !ok {
+			valid = false
+		}
+	}
+	if valid {
+		s.pal.ProcessLine(line)
+	}
+}
+
+// finishSection fuehrt die Pruefungen durch, welche erst am Ende eines
+// Abschnittes moeglich sind.
+func (l *linter) finishSection() {
+	s := l.sect
+	if s == nil {
+		return
+	}
+	l.sect = nil
+	switch p := s.pal.(type) {
+	case nil:
+		l.report(s.line, 1, "palette '%s' has no data", s.name)
+	case *GradientPalette:
+		for i := Red; i < NumChannels; i++ {
+			gpl := p.GradPointList(i)
+			if len(gpl) == 0 && i == Alpha {
+				continue
+			}
+			if len(gpl) == 0 {
+				l.report(s.line, 1, "palette '%s': channel %v has no stops", s.name, i)
+				continue
+			}
+			if gpl[0].Pos != 0.0 {
+				l.report(s.line, 1, "palette '%s': channel %v has no stop at 0.0", s.name, i)
+			}
+			if gpl[len(gpl)-1].Pos != 1.0 {
+				l.report(s.line, 1, "palette '%s': channel %v has no stop at 1.0", s.name, i)
+			}
+		}
+	case *ProcPalette:
+		for _, c := range []byte{'r', 'g', 'b'} {
+			if _, ok := s.procLines[c]; !ok {
+				l.report(s.line, 1, "palette '%s': missing parameters for channel '%c'",
+					s.name, c)
+			}
+		}
+	default:
+		if !p.Ready() {
+			l.report(s.line, 1, "palette '%s': some values are missing", s.name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package mandel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// Eine MapPalette stellt eine der wahrnehmungsgleichfoermigen Farbskalen von
+// matplotlib (viridis, magma, inferno, plasma) zur Verfuegung. In
+// palette.ini genuegt dafuer die Zeile
+//
+//	map: viridis
+//
+// Die Farbskalen werden nicht als Tabellen mitgeliefert, sondern durch
+// Polynome 6. Grades pro Farbkanal angenaehert (Fits von Matt Zucker). Die
+// Abweichung von den Originalen liegt unter 2% pro Farbkanal.
+var (
+	mapPalRegexp = regexp.MustCompile(`^ *map *: *([[:alpha:]]+) *$`)
+)
+
+// mapCoeffs enthaelt fuer jede Farbskala die Koeffizienten c0, ..., c6 der
+// Polynome c0 + c1*f + ... + c6*f^6 fuer die Farbkanaele Rot, Gruen und
+// Blau.
+var mapCoeffs = map[string][7][3]float64{
+	"viridis": {
+		{0.2777273272234177, 0.005407344544966578, 0.3340998053353061},
+		{0.1050930431085774, 1.404613529898575, 1.384590162594685},
+		{-0.3308618287255563, 0.214847559468213, 0.09509516302823659},
+		{-4.634230498983486, -5.799100973351585, -19.33244095627987},
+		{6.228269936347081, 14.17993336680509, 56.69055260068105},
+		{4.776384997670288, -13.74514537774601, -65.35303263337234},
+		{-5.435455855934631, 4.645852612178535, 26.3124352495832},
+	},
+	"plasma": {
+		{0.05873234392399702, 0.02333670892565664, 0.5433401826748754},
+		{2.176514634195958, 0.2383834171260182, 0.7539604599784036},
+		{-2.689460476458034, -7.455851135738909, 3.110799939717086},
+		{6.130348345893603, 42.3461881477227, -28.51885465332158},
+		{-11.10743619062271, -82.66631109428045, 60.13984767418263},
+		{10.02306557647065, 71.41361770095349, -54.07218655560067},
+		{-3.658713842777788, -22.93153465461149, 18.19190778539828},
+	},
+	"magma": {
+		{-0.002136485053939582, -0.000749655052795221, -0.005386127855323933},
+		{0.2516605407371642, 0.6775232436837668, 2.494026599312351},
+		{8.353717279216625, -3.577719514958484, 0.3144679030132573},
+		{-27.66873308576866, 14.26473078096533, -13.64921318813922},
+		{52.17613981234068, -27.94360607168351, 12.94416944238394},
+		{-50.76852536473588, 29.04658282127291, 4.23415299384598},
+		{18.65570506591883, -11.48977351997711, -5.601961508734096},
+	},
+	"inferno": {
+		{0.0002189403691192265, 0.001651004631001012, -0.01948089843709184},
+		{0.1065134194856116, 0.5639564367884091, 3.932712388889277},
+		{11.60249308247187, -3.972853965665698, -15.9423941062914},
+		{-41.70399613139459, 17.43639888205313, 44.35414519872813},
+		{77.162935699427, -33.40235894210092, -81.80730925738993},
+		{-71.31942824499214, 32.62606426397723, 73.20951985803202},
+		{25.13112622477341, -12.24266895238567, -23.07032500287172},
+	},
+}
+
+// Liefert die Namen aller Farbskalen, welche mit [MapPalette] verwendet
+// werden koennen (alphabetisch sortiert).
+func ColorMapNames() []string {
+	names := make([]string, 0, len(mapCoeffs))
+	for name := range mapCoeffs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type MapPalette struct {
+	basePalette
+	name string
+}
+
+func NewMapPalette() *MapPalette {
+	p := &MapPalette{}
+	p.Init()
+	return p
+}
+
+func (p *MapPalette) GetRegexp() *regexp.Regexp {
+	return mapPalRegexp
+}
+
+func (p *MapPalette) ProcessLine(line string) error {
+	matches := mapPalRegexp.FindStringSubmatch(line)
+	return p.SetMap(matches[1])
+}
+
+// Waehlt die Farbskala name (siehe [ColorMapNames]).
+func (p *MapPalette) SetMap(name string) error {
+	if _, ok := mapCoeffs[name]; !ok {
+		return fmt.Errorf("unknown color map '%s' (known maps: %v)", name, ColorMapNames())
+	}
+	p.name = name
+	return nil
+}
+
+func (p *MapPalette) Map() string {
+	return p.name
+}
+
+// Value berechnet die Farbe der gewaehlten Farbskala an der Stelle f
+// (0.0 <= f <= 1.0).
+func (p *MapPalette) Value(f float64) (c [3]float64) {
+	coeffs := mapCoeffs[p.name]
+	for _, ck := range slices.Backward(coeffs[:]) {
+		for j := range c {
+			c[j] = c[j]*f + ck[j]
+		}
+	}
+	return c
+}
+
+func (p *MapPalette) Update() {
+	p.updateFunc(p.Value, nil)
+}
+
+func (p *MapPalette) Ready() bool {
+	return p.name != ""
+}
+
+// WriteSection schreibt die Palette als Abschnitt mit dem Namen name im
+// Format von palette.ini nach w.
+func (p *MapPalette) WriteSection(w io.Writer, name string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", name)
+	p.writeOptions(bw)
+	fmt.Fprintf(bw, "map: %s\n", p.name)
+	fmt.Fprintln(bw)
+	return bw.Flush()
+}
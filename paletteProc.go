@@ -1,15 +1,17 @@
 package mandel
 
 import (
-	"image/color"
+	"bufio"
+	"fmt"
+	"io"
 	"math"
 	"regexp"
 	"strconv"
 )
 
 var (
-	procPalRegexp = regexp.MustCompile(`^ *([rgb]) *: *([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) *$`)
-	colorMap      = map[byte]BaseColorType{'r': Red, 'g': Green, 'b': Blue}
+	procPalRegexp = regexp.MustCompile(`^ *([rgba]) *: *([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) +([-+0-9\.]+) *$`)
+	colorMap      = map[byte]BaseColorType{'r': Red, 'g': Green, 'b': Blue, 'a': Alpha}
 )
 
 type ProcParamType int
@@ -31,7 +33,7 @@ type ProcPalette struct {
 func NewProcPalette() *ProcPalette {
 	p := &ProcPalette{}
 	p.Init()
-	p.v = make([][]float64, NumBaseColors)
+	p.v = make([][]float64, NumChannels)
 	// p.rexp = procPalRegexp
 	return p
 }
@@ -45,25 +47,50 @@ func (p *ProcPalette) ProcessLine(line string) error {
 
 	matches = procPalRegexp.FindStringSubmatch(line)
 	colIdx := colorMap[matches[1][0]]
-	a, _ := strconv.ParseFloat(matches[2], 32)
-	b, _ := strconv.ParseFloat(matches[3], 32)
-	c, _ := strconv.ParseFloat(matches[4], 32)
-	d, _ := strconv.ParseFloat(matches[5], 32)
-	p.SetParamList(colIdx, []float64{a, b, c, d})
+	v := make([]float64, NumProcParams)
+	for i := range v {
+		f, err := strconv.ParseFloat(matches[i+2], 64)
+		if err != nil {
+			return err
+		}
+		v[i] = f
+	}
+	p.SetParamList(colIdx, v)
 	return nil
 }
 
 func (p *ProcPalette) Update() {
-	for i := 0; i < len(p.colorList); i++ {
-		f := float64(i) / float64(len(p.colorList)-1)
-		r := 255.0 * p.Value(Red, f)
-		g := 255.0 * p.Value(Green, f)
-		b := 255.0 * p.Value(Blue, f)
-		p.colorList[i] = color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}
+	p.updateFunc(func(f float64) [3]float64 {
+		return [3]float64{p.Value(Red, f), p.Value(Green, f), p.Value(Blue, f)}
+	}, p.alphaFunc())
+}
+
+// alphaFunc liefert die Funktion fuer den Alpha-Kanal oder nil, falls fuer
+// diesen Kanal keine Parameter angegeben wurden.
+func (p *ProcPalette) alphaFunc() func(f float64) float64 {
+	if p.v[Alpha] == nil {
+		return nil
+	}
+	return func(f float64) float64 {
+		return p.Value(Alpha, f)
+	}
+}
+
+// channels liefert die Parameter aller Kanaele, wobei der Alpha-Kanal nur
+// enthalten ist, falls er gesetzt wurde.
+func (p *ProcPalette) channels() [][]float64 {
+	if p.v[Alpha] == nil {
+		return p.v[:NumBaseColors]
 	}
+	return p.v
 }
 
 func (p *ProcPalette) Ready() bool {
+	for _, v := range p.v[:NumBaseColors] {
+		if v == nil {
+			return false
+		}
+	}
 	return true
 }
 
@@ -88,3 +115,20 @@ func (p *ProcPalette) Value(col BaseColorType, f float64) float64 {
 	return v[0] + v[1]*math.Cos(2*math.Pi*(v[2]*f+v[3]))
 }
 
+// WriteSection schreibt die Palette als Abschnitt mit dem Namen name im
+// Format von palette.ini nach w.
+func (p *ProcPalette) WriteSection(w io.Writer, name string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", name)
+	p.writeOptions(bw)
+	for col, c := range []byte{'r', 'g', 'b', 'a'} {
+		v := p.v[col]
+		if v == nil {
+			continue
+		}
+		fmt.Fprintf(bw, "%c: %8s %8s %8s %8s\n", c, formatValue(v[ParA]),
+			formatValue(v[ParB]), formatValue(v[ParC]), formatValue(v[ParD]))
+	}
+	fmt.Fprintln(bw)
+	return bw.Flush()
+}
@@ -0,0 +1,228 @@
+package mandel
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Die Paletten, welche mit dem Package ausgeliefert werden. Sie stehen in
+// jeder Registry zur Verfuegung, welche mit
+// [PaletteRegistry.LoadDefaults] geladen wurde, auch wenn im Konfigurations-
+// verzeichnis keine palette.ini existiert.
+//
+//go:embed palette.ini
+var defPaletteData []byte
+
+// PaletteRegistry verwaltet eine Menge von benannten Paletten. Die Paletten
+// koennen aus beliebig vielen Quellen (Dateien, eingebettete Daten, etc.)
+// im Format von palette.ini geladen werden. Kommt ein Name in mehreren
+// Quellen vor, so gilt die Palette aus der zuletzt geladenen Quelle;
+// innerhalb derselben Quelle gilt (wie bei [ReadPalette]) der erste
+// Abschnitt mit diesem Namen. Die Paletten werden erst bei Bedarf erstellt,
+// so dass fehlerhafte Paletten das Laden einer Quelle nicht verhindern.
+// Alle Methoden koennen gleichzeitig aus mehreren Go-Routinen aufgerufen
+// werden.
+type PaletteRegistry struct {
+	mu       sync.RWMutex
+	sections map[string]paletteSection
+	names    []string
+}
+
+// paletteSection ist der Text eines einzelnen Abschnittes, so wie er aus
+// einer Quelle gelesen wurde.
+type paletteSection struct {
+	source string
+	line   int
+	data   []byte
+}
+
+// Erstellt eine neue, leere Registry.
+func NewPaletteRegistry() *PaletteRegistry {
+	return &PaletteRegistry{sections: make(map[string]paletteSection)}
+}
+
+// Load liest alle Paletten, welche im Format von palette.ini aus rd gelesen
+// werden koennen, und ueberschreibt damit allenfalls bereits vorhandene
+// Paletten mit demselben Namen. source ist der Name der Quelle, welcher in
+// Fehlermeldungen verwendet wird.
+func (r *PaletteRegistry) Load(rd io.Reader, source string) error {
+	var sect *paletteSection
+	var name string
+
+	sections := make(map[string]paletteSection)
+	var names []string
+	flush := func() {
+		if sect == nil {
+			return
+		}
+		if _, ok := sections[name]; !ok {
+			sections[name] = *sect
+			names = append(names, name)
+		}
+		sect = nil
+	}
+
+	scanner := bufio.NewScanner(rd)
+	lineNo := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		lineNo++
+		if m := regxSection.FindSubmatch(line); m != nil {
+			flush()
+			name = string(m[1])
+			sect = &paletteSection{source: source, line: lineNo}
+		}
+		if sect != nil {
+			sect.data = append(sect.data, line...)
+			sect.data = append(sect.data, '\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	flush()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		if _, ok := r.sections[name]; !ok {
+			r.names = append(r.names, name)
+		}
+		r.sections[name] = sections[name]
+	}
+	return nil
+}
+
+// add fuegt den Abschnitt sect unter dem Namen name hinzu (bspw. die
+// Paletten einer Szene, welche nicht aus einer eigenen Quelle stammen).
+func (r *PaletteRegistry) add(name string, sect paletteSection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sections[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.sections[name] = sect
+}
+
+// LoadFile laedt die Paletten aus der Datei fileName (siehe
+// [PaletteRegistry.Load]).
+func (r *PaletteRegistry) LoadFile(fileName string) error {
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return r.Load(fd, fileName)
+}
+
+// LoadDefaults laedt die Paletten, welche mit dem Package ausgeliefert
+// werden.
+func (r *PaletteRegistry) LoadDefaults() error {
+	return r.Load(bytes.NewReader(defPaletteData), "(default) "+palFileName)
+}
+
+// Names liefert die Namen aller Paletten in der Reihenfolge, in welcher sie
+// zum ersten Mal geladen wurden.
+func (r *PaletteRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, len(r.names))
+	copy(names, r.names)
+	return names
+}
+
+// Has prueft, ob eine Palette mit dem Namen name vorhanden ist.
+func (r *PaletteRegistry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.sections[name]
+	return ok
+}
+
+// Source liefert den Namen der Quelle und die Zeile, aus welcher die
+// Palette name stammt.
+func (r *PaletteRegistry) Source(name string) (source string, line int, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sect, ok := r.sections[name]
+	return sect.source, sect.line, ok
+}
+
+// data liefert den Text des Abschnittes der Palette name (inkl. der Zeile
+// mit dem Namen).
+func (r *PaletteRegistry) data(name string) []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sections[name].data
+}
+
+// Palette erstellt eine neue Palette mit dem Namen name. Jeder Aufruf
+// liefert eine eigene Instanz, welche (bspw. mit SetLength oder SetOffset)
+// beliebig veraendert werden kann.
+func (r *PaletteRegistry) Palette(name string) (Palette, error) {
+	r.mu.RLock()
+	sect, ok := r.sections[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no palette '%s' found!", name)
+	}
+	p, err := readPalette(bytes.NewReader(sect.data), name, sect.line-1)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sect.source, err)
+	}
+	return p, nil
+}
+
+var (
+	defRegistry     *PaletteRegistry
+	defRegistryErr  error
+	defRegistryOnce sync.Once
+	defRegistryMu   sync.Mutex
+)
+
+// DefaultPaletteRegistry liefert die Registry, welche von [NewPalette] und
+// [PaletteNames] verwendet wird. Beim ersten Aufruf werden die mitgelieferten
+// Paletten und anschliessend alle Dateien palette.ini im Suchpfad (siehe
+// [ConfFiles]) geladen. Paletten aus diesen Dateien haben also Vorrang vor
+// den mitgelieferten.
+func DefaultPaletteRegistry() (*PaletteRegistry, error) {
+	defRegistryOnce.Do(func() {
+		r := NewPaletteRegistry()
+		if err := r.LoadDefaults(); err != nil {
+			defRegistryErr = err
+			return
+		}
+		for _, fileName := range ConfFiles(palFileName) {
+			if err := r.LoadFile(fileName); err != nil {
+				defRegistryErr = err
+				return
+			}
+		}
+		defRegistryMu.Lock()
+		if defRegistry == nil {
+			defRegistry = r
+		}
+		defRegistryMu.Unlock()
+	})
+	defRegistryMu.Lock()
+	defer defRegistryMu.Unlock()
+	if defRegistry != nil {
+		return defRegistry, nil
+	}
+	return nil, defRegistryErr
+}
+
+// SetDefaultPaletteRegistry ersetzt die Registry, welche von [NewPalette]
+// und [PaletteNames] verwendet wird, bspw. in Tests oder in Programmen,
+// welche ihre Paletten aus anderen Quellen beziehen. r darf nicht nil sein.
+func SetDefaultPaletteRegistry(r *PaletteRegistry) {
+	defRegistryOnce.Do(func() {})
+	defRegistryMu.Lock()
+	defRegistry = r
+	defRegistryMu.Unlock()
+}
@@ -0,0 +1,163 @@
+package mandel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Bevor ein Iterationswert in der Palette nachgeschlagen wird, kann er mit
+// einer Transferfunktion umgerechnet werden. Damit laesst sich steuern, wie
+// die Farben ueber die Iterationswerte verteilt werden: mit 'log' oder
+// 'sqrt' werden bspw. die (haeufigen) kleinen Werte gestreckt und die
+// grossen gestaucht, womit auch bei hohen Iterationszahlen alle Farben der
+// Palette sichtbar bleiben. Die Funktionen sind so normiert, dass der Wert
+// 0 auf 0 und die Palettenlaenge auf die Palettenlaenge abgebildet wird.
+type TransferType int
+
+const (
+	TransferLinear TransferType = iota
+	TransferLog
+	TransferSqrt
+	TransferPow
+	TransferCyclic
+)
+
+func (tt TransferType) String() string {
+	switch tt {
+	case TransferLinear:
+		return "linear"
+	case TransferLog:
+		return "log"
+	case TransferSqrt:
+		return "sqrt"
+	case TransferPow:
+		return "pow"
+	case TransferCyclic:
+		return "cyclic"
+	default:
+		return "Unknown transfer function"
+	}
+}
+
+func (tt *TransferType) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "linear":
+		*tt = TransferLinear
+	case "log":
+		*tt = TransferLog
+	case "sqrt":
+		*tt = TransferSqrt
+	case "pow":
+		*tt = TransferPow
+	case "cyclic":
+		*tt = TransferCyclic
+	default:
+		return errors.New("Unknown transfer function: " + s)
+	}
+	return nil
+}
+
+// Transfer beschreibt eine Transferfunktion vollstaendig. Param ist bei
+// 'pow' der Exponent und bei 'cyclic' die Dichte, d.h. die Anzahl Zyklen
+// pro Palettenlaenge. Der Nullwert ist die lineare Transferfunktion.
+type Transfer struct {
+	Type  TransferType
+	Param float64
+}
+
+// Erstellt aus einem Text wie 'log', 'pow 0.5' oder 'cyclic 4' eine neue
+// Transferfunktion. Anstelle des Leerzeichens kann auch ein ':' verwendet
+// werden (bspw. 'pow:0.5'), womit sich die Funktion auch in path.ini
+// angeben laesst. Bei 'cyclic' ist die Dichte optional (Default: 1).
+func ParseTransfer(s string) (Transfer, error) {
+	var tf Transfer
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ':'
+	})
+	if len(fields) == 0 {
+		return tf, errors.New("missing transfer function")
+	}
+	if err := tf.Type.Set(fields[0]); err != nil {
+		return tf, err
+	}
+	args := fields[1:]
+	switch tf.Type {
+	case TransferPow:
+		if len(args) != 1 {
+			return tf, errors.New("pow needs an exponent")
+		}
+	case TransferCyclic:
+		tf.Param = 1.0
+		if len(args) > 1 {
+			return tf, errors.New("cyclic needs 0 or 1 arguments")
+		}
+	default:
+		if len(args) != 0 {
+			return tf, fmt.Errorf("transfer function '%s' takes no arguments", fields[0])
+		}
+		return tf, nil
+	}
+	if len(args) == 1 {
+		v, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return tf, err
+		}
+		if v <= 0.0 {
+			return tf, fmt.Errorf("argument of '%s' must be positive", fields[0])
+		}
+		tf.Param = v
+	}
+	return tf, nil
+}
+
+func (tf Transfer) String() string {
+	switch tf.Type {
+	case TransferPow, TransferCyclic:
+		return fmt.Sprintf("%v %g", tf.Type, tf.Param)
+	default:
+		return tf.Type.String()
+	}
+}
+
+// Apply wendet die Transferfunktion auf den Iterationswert f (>= 0) an,
+// wobei length die Laenge der Palette ist.
+//
+//	linear  f
+//	log     length * log(1+f) / log(1+length)
+//	sqrt    length * sqrt(f/length)
+//	pow     length * (f/length)^Param
+//	cyclic  length * (1 - cos(2*pi*Param*f/length)) / 2
+//
+// Bei 'cyclic' wird die Palette abwechselnd vorwaerts und rueckwaerts
+// durchlaufen, womit auch bei Paletten, deren Ende nicht zum Anfang passt,
+// keine Spruenge entstehen.
+func (tf Transfer) Apply(f, length float64) float64 {
+	switch tf.Type {
+	case TransferLog:
+		return length * math.Log1p(f) / math.Log1p(length)
+	case TransferSqrt:
+		return length * math.Sqrt(f/length)
+	case TransferPow:
+		return length * math.Pow(f/length, tf.Param)
+	case TransferCyclic:
+		return 0.5 * length * (1.0 - math.Cos(2.0*math.Pi*tf.Param*f/length))
+	default:
+		return f
+	}
+}
+
+// Interp interpoliert zwischen den Transferfunktionen tf und u. Sind beide
+// vom gleichen Typ, so wird der Parameter interpoliert und mix ist 0.0.
+// Andernfalls wird tf retourniert und mix ist gleich t, d.h. die Resultate
+// der beiden Funktionen muessen ueberblendet werden (siehe
+// [basePalette.BlendTransfer]).
+func (tf Transfer) Interp(u Transfer, t float64) (Transfer, float64) {
+	if tf.Type != u.Type {
+		return tf, t
+	}
+	return Transfer{tf.Type, (1.0-t)*tf.Param + t*u.Param}, 0.0
+}
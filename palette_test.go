@@ -0,0 +1,460 @@
+package mandel
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const (
+	testPalFile = "palette.ini"
+)
+
+// readTestPalette liest die Palette name aus der mitgelieferten palette.ini.
+func readTestPalette(t *testing.T, name string) Palette {
+	fd, err := os.Open(testPalFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	p, err := ReadPalette(fd, name)
+	if err != nil {
+		t.Fatalf("couldn't read palette '%s': %v", name, err)
+	}
+	return p
+}
+
+func testPaletteNames(t *testing.T) []string {
+	fd, err := os.Open(testPalFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	names, err := ReadPaletteNames(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatalf("no palettes found in %s", testPalFile)
+	}
+	return names
+}
+
+// colorTable liefert die interne Farbtabelle einer Palette.
+func colorTable(p Palette) ColorList {
+	if tp, ok := p.(tablePalette); ok {
+		return tp.table()
+	}
+	return nil
+}
+
+func TestWriteSectionRoundTrip(t *testing.T) {
+	for _, name := range testPaletteNames(t) {
+		p1 := readTestPalette(t, name)
+		buf := &bytes.Buffer{}
+		if err := p1.WriteSection(buf, name); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		p2, err := ReadPalette(bytes.NewReader(buf.Bytes()), name)
+		if err != nil {
+			t.Fatalf("%s: couldn't parse written section: %v\n%s", name, err, buf)
+		}
+		if !slices.Equal(colorTable(p1), colorTable(p2)) {
+			t.Errorf("%s: color table differs after round trip:\n%s", name, buf)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, name := range testPaletteNames(t) {
+		p1 := readTestPalette(t, name)
+		data, err := MarshalPalette(p1)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		p2, err := UnmarshalPalette(data)
+		if err != nil {
+			t.Fatalf("%s: couldn't parse JSON: %v\n%s", name, err, data)
+		}
+		if !slices.Equal(colorTable(p1), colorTable(p2)) {
+			t.Errorf("%s: color table differs after JSON round trip:\n%s", name, data)
+		}
+	}
+}
+
+func TestLintPalettes(t *testing.T) {
+	const data = `[A]
+foo = 1
+0.0 : 0.0 0.0 0.0
+0.5 : 1.5 - -
+0.5 : 0.2 0.2 0.2
+[A]
+r: 1 2 3 4
+`
+	want := []string{
+		"test.ini:2:1: unknown keyword 'foo'",
+		"test.ini:4:7: value 1.5 is outside of [0,1]",
+		"test.ini:5:1: duplicate stop position 0.5 (first defined on line 4)",
+		"test.ini:1:1: palette 'A': channel Red has no stop at 1.0",
+		"test.ini:1:1: palette 'A': channel Green has no stop at 1.0",
+		"test.ini:1:1: palette 'A': channel Blue has no stop at 1.0",
+		"test.ini:6:2: duplicate palette name 'A' (first defined on line 1)",
+		"test.ini:6:1: palette 'A': missing parameters for channel 'g'",
+		"test.ini:6:1: palette 'A': missing parameters for channel 'b'",
+	}
+	diags, err := LintPalettes(strings.NewReader(data), "test.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(diags))
+	for i, d := range diags {
+		got[i] = d.String()
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got diagnostics:\n%s\nwant:\n%s",
+			strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// Doppelte Stuetzstellen wuerden bei 'catmullrom' und 'monotone' zu einer
+// Division durch 0 fuehren und muessen daher abgewiesen werden.
+func TestDuplicateStops(t *testing.T) {
+	const stops = "0.0: #000000\n0.5: #ff0000\n0.5: #00ff00\n1.0: #ffffff\n"
+	for _, interp := range []string{"cubic", "catmullrom", "monotone"} {
+		ini := "[G]\ninterp = " + interp + "\n" + stops
+		if _, err := ReadPalette(strings.NewReader(ini), "G"); err == nil {
+			t.Errorf("%s: duplicate stop position accepted", interp)
+		}
+	}
+	const chanIni = "[G]\n0.0: #000000\n0.5: 1.0 - -\n0.5: - 1.0 -\n1.0: #ffffff\n"
+	if _, err := ReadPalette(strings.NewReader(chanIni), "G"); err != nil {
+		t.Errorf("stops at the same position in different channels: %v", err)
+	}
+}
+
+func TestColorMaps(t *testing.T) {
+	// Referenzwerte aus den Tabellen von matplotlib (Eintraege 0, 128 und
+	// 255 von 256).
+	refs := map[string][3][3]float64{
+		"viridis": {{0.267004, 0.004874, 0.329415}, {0.127568, 0.566949, 0.550556},
+			{0.993248, 0.906157, 0.143936}},
+		"magma": {{0.001462, 0.000466, 0.013866}, {0.716387, 0.214982, 0.475290},
+			{0.987053, 0.991438, 0.749504}},
+		"inferno": {{0.001462, 0.000466, 0.013866}, {0.735683, 0.215906, 0.330245},
+			{0.988362, 0.998364, 0.644924}},
+		"plasma": {{0.050383, 0.029803, 0.527975}, {0.798216, 0.280197, 0.469538},
+			{0.940015, 0.975158, 0.131326}},
+	}
+	for name, ref := range refs {
+		p := NewMapPalette()
+		if err := p.SetMap(name); err != nil {
+			t.Fatal(err)
+		}
+		for i, f := range []float64{0.0, 128.0 / 255.0, 1.0} {
+			c := p.Value(f)
+			for j := range c {
+				v := math.Max(0.0, math.Min(1.0, c[j]))
+				if math.Abs(v-ref[i][j]) > 0.02 {
+					t.Errorf("%s(%.3f): channel %d is %.4f, want %.4f", name, f, j, v, ref[i][j])
+				}
+			}
+		}
+	}
+}
+
+// readIniPalette liest die Palette P aus dem Text ini.
+func readIniPalette(t *testing.T, ini string) Palette {
+	p, err := ReadPalette(strings.NewReader("[P]\n"+ini), "P")
+	if err != nil {
+		t.Fatalf("couldn't read palette %q: %v", ini, err)
+	}
+	return p
+}
+
+// checkColor vergleicht die Farbe der Palette p an der Stelle f mit want.
+func checkColor(t *testing.T, name string, p Palette, f float64, want [3]float64) {
+	c := tableColor(colorTable(p), f)
+	got := [3]float64{c.R, c.G, c.B}
+	for j := range got {
+		if math.Abs(got[j]-want[j]) > 0.02 {
+			t.Errorf("%s(%.3f) = %.4f, want %.4f", name, f, got, want)
+			return
+		}
+	}
+}
+
+func TestHSVPalette(t *testing.T) {
+	p := readIniPalette(t, "hsv: 0.0 1.0 1.0 1.0\n")
+	checkColor(t, "hsv", p, 0.0, [3]float64{1.0, 0.0, 0.0})
+	checkColor(t, "hsv", p, 1.0/3.0, [3]float64{0.0, 1.0, 0.0})
+	checkColor(t, "hsv", p, 2.0/3.0, [3]float64{0.0, 0.0, 1.0})
+	checkColor(t, "hsv", p, 1.0, [3]float64{1.0, 0.0, 0.0})
+
+	// Saettigung und Helligkeit: bei h0 = h1 ist die Palette einfarbig.
+	p = readIniPalette(t, "hsv: 1.5 1.5 0.5 0.8\n")
+	for _, f := range []float64{0.0, 0.5, 1.0} {
+		checkColor(t, "hsv", p, f, [3]float64{0.4, 0.8, 0.8})
+	}
+
+	if _, err := ReadPalette(strings.NewReader("[P]\nhsv: 0.0 1.0 1.5 1.0\n"), "P"); err == nil {
+		t.Errorf("saturation outside of [0,1] accepted")
+	}
+}
+
+func TestCubehelixPalette(t *testing.T) {
+	p := readIniPalette(t, "cubehelix: 0.5 -1.5 1.0 1.0\n")
+	checkColor(t, "cubehelix", p, 0.0, [3]float64{0.0, 0.0, 0.0})
+	checkColor(t, "cubehelix", p, 1.0, [3]float64{1.0, 1.0, 1.0})
+
+	// Die Helligkeit (nach der Gewichtung von Green) steigt linear an.
+	ch := NewCubehelixPalette()
+	if err := ch.SetParams(0.5, -1.5, 1.0, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+		c := ch.Value(f)
+		if l := 0.30*c[0] + 0.59*c[1] + 0.11*c[2]; math.Abs(l-f) > 1.0e-3 {
+			t.Errorf("cubehelix(%.2f): brightness is %.4f, want %.4f", f, l, f)
+		}
+	}
+
+	if err := ch.SetParams(0.5, -1.5, 1.0, 0.0); err == nil {
+		t.Errorf("gamma 0 accepted")
+	}
+}
+
+func TestFourierPalette(t *testing.T) {
+	p := readIniPalette(t, "fourier r: 0.5 0.5 0.0\n"+
+		"fourier g: 0.5 0.0 0.5\nfourier b: 0.25\n")
+	checkColor(t, "fourier", p, 0.0, [3]float64{1.0, 0.5, 0.25})
+	checkColor(t, "fourier", p, 0.25, [3]float64{0.5, 1.0, 0.25})
+	checkColor(t, "fourier", p, 0.5, [3]float64{0.0, 0.5, 0.25})
+
+	// Die Palette geht am Ende nahtlos in ihren Anfang ueber.
+	cl := colorTable(p)
+	first, last := cl[0], cl[len(cl)-1]
+	if math.Abs(first.R-last.R)+math.Abs(first.G-last.G)+math.Abs(first.B-last.B) > 1.0e-9 {
+		t.Errorf("first color %v differs from last color %v", first, last)
+	}
+
+	for _, ini := range []string{"fourier r: 0.5 0.5\n", "fourier r: 0.5\nfourier g: 0.5\n"} {
+		if _, err := ReadPalette(strings.NewReader("[P]\n"+ini), "P"); err == nil {
+			t.Errorf("%q accepted", ini)
+		}
+	}
+}
+
+func TestExtractColors(t *testing.T) {
+	cols := []color.RGBA{{0xff, 0xff, 0xff, 0xff}, {0x00, 0x00, 0x00, 0xff},
+		{0xc0, 0x20, 0x20, 0xff}}
+	img := image.NewRGBA(image.Rect(0, 0, 40, 10))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 10; y++ {
+			img.Set(x, y, cols[min(x/10, 2)])
+		}
+	}
+	colors, err := ExtractColors(img, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Erwartet wird die Reihenfolge schwarz, rot, weiss.
+	want := []ImageColor{{0.0, 0.0, 0.0, 0.25}, {0.75, 0.125, 0.125, 0.5},
+		{1.0, 1.0, 1.0, 0.25}}
+	if len(colors) != len(want) {
+		t.Fatalf("got %d colors, want %d", len(colors), len(want))
+	}
+	for i, c := range colors {
+		w := want[i]
+		if math.Abs(c.R-w.R) > 0.01 || math.Abs(c.G-w.G) > 0.01 ||
+			math.Abs(c.B-w.B) > 0.01 || math.Abs(c.Weight-w.Weight) > 1e-9 {
+			t.Errorf("color %d: got %+v, want %+v", i, c, w)
+		}
+	}
+
+	p, err := PaletteFromColors(colors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := p.WriteSection(buf, "Extracted"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPalette(bytes.NewReader(buf.Bytes()), "Extracted"); err != nil {
+		t.Errorf("couldn't parse extracted palette: %v\n%s", err, buf)
+	}
+}
+
+func TestTableSize(t *testing.T) {
+	p := readTestPalette(t, "Sunset")
+	if n := p.(*GradientPalette).TableSize(); n != 4096 {
+		t.Errorf("TableSize() = %d, want 4096", n)
+	}
+	if err := p.SetOption("size", "1"); err == nil {
+		t.Errorf("table size 1 accepted")
+	}
+
+	// Bei einer langen Palette muessen sich benachbarte Farben mit 16 Bit
+	// unterscheiden lassen (keine Streifenbildung).
+	p = readTestPalette(t, "Default")
+	p.LenIsNotMaxIter()
+	p.SetLength(1 << 16)
+	steps := 0
+	prev := p.GetColorF(0.0).RGBA64()
+	for i := 1; i < 4096; i++ {
+		c := p.GetColorF(float64(i)).RGBA64()
+		if c != prev {
+			steps++
+		}
+		prev = c
+	}
+	if steps < 2048 {
+		t.Errorf("only %d of 4095 neighbouring colors differ", steps)
+	}
+}
+
+func TestAlpha(t *testing.T) {
+	p := readTestPalette(t, "Smoke")
+	if c := p.GetColorF(-1.0); c != (ColorF{}) {
+		t.Errorf("inside color = %v, want transparent", c)
+	}
+	cl := colorTable(p)
+	if a := cl[0].A; a != 0.0 {
+		t.Errorf("alpha at 0.0 = %v, want 0", a)
+	}
+	if a := tableColor(cl, 0.7).A; math.Abs(a-0xe6/255.0) > 1.0e-3 {
+		t.Errorf("alpha at 0.7 = %v, want %v", a, 0xe6/255.0)
+	}
+
+	var buf bytes.Buffer
+	if err := p.WriteSection(&buf, "Smoke"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "inside = transparent") {
+		t.Errorf("inside color not written:\n%s", buf.String())
+	}
+
+	// Prozedurale Paletten ohne 'a:' sind deckend, mit 'a:' nicht.
+	const procIni = "[P]\nr: 0.5 0.5 1.0 0.0\ng: 0.5 0.5 1.0 0.1\nb: 0.5 0.5 1.0 0.2\n"
+	p, err := ReadPalette(strings.NewReader(procIni), "P")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range colorTable(p) {
+		if c.A != 1.0 {
+			t.Fatalf("procedural palette without alpha isn't opaque: %v", c)
+		}
+	}
+	p, err = ReadPalette(strings.NewReader(procIni+"a: 0.5 0.5 1.0 0.0\n"), "P")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := colorTable(p)[0].A; a != 1.0 {
+		t.Errorf("alpha at 0.0 = %v, want 1", a)
+	}
+	if a := tableColor(colorTable(p), 0.5).A; a > 1.0e-4 {
+		t.Errorf("alpha at 0.5 = %v, want 0", a)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	for _, s := range []string{"linear", "log", "sqrt", "pow 0.5", "cyclic 4"} {
+		tf, err := ParseTransfer(s)
+		if err != nil {
+			t.Fatalf("ParseTransfer(%q): %v", s, err)
+		}
+		if tf.String() != s {
+			t.Errorf("String() = %q, want %q", tf.String(), s)
+		}
+		if tf.Type == TransferCyclic {
+			continue
+		}
+		if v := tf.Apply(0.0, 256.0); math.Abs(v) > 1.0e-9 {
+			t.Errorf("%s: Apply(0) = %v, want 0", s, v)
+		}
+		if v := tf.Apply(256.0, 256.0); math.Abs(v-256.0) > 1.0e-9 {
+			t.Errorf("%s: Apply(256) = %v, want 256", s, v)
+		}
+	}
+	for _, s := range []string{"pow", "log 2", "cyclic -1", "exp"} {
+		if _, err := ParseTransfer(s); err == nil {
+			t.Errorf("ParseTransfer(%q) accepted", s)
+		}
+	}
+	if tf, _ := ParseTransfer("pow:2"); tf != (Transfer{TransferPow, 2.0}) {
+		t.Errorf("ParseTransfer(\"pow:2\") = %v", tf)
+	}
+
+	p := readTestPalette(t, "DeepRainbow")
+	if tf := p.Transfer(); tf.Type != TransferLog {
+		t.Fatalf("Transfer() = %v, want log", tf)
+	}
+	p.LenIsNotMaxIter()
+	p.SetLength(256)
+	tf := p.Transfer()
+	if c0, c1 := p.GetColorF(100.0), p.GetColorF(tf.Apply(100.0, 256.0)); c0 == c1 {
+		t.Errorf("transfer function isn't applied")
+	}
+	p.SetTransfer(Transfer{})
+	want := p.GetColorF(100.0)
+	p.SetTransfer(tf)
+	p.BlendTransfer(Transfer{}, 1.0)
+	if c := p.GetColorF(100.0); c != want {
+		t.Errorf("blended transfer: got %v, want %v", c, want)
+	}
+}
+
+func TestPaletteRegistry(t *testing.T) {
+	r := NewPaletteRegistry()
+	if err := r.LoadDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if names := r.Names(); !slices.Equal(names, testPaletteNames(t)) {
+		t.Errorf("Names() = %v", names)
+	}
+	// Die mitgelieferten Paletten muessen fehlerfrei sein.
+	diags, err := LintPalettes(bytes.NewReader(defPaletteData), "palette.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diags {
+		t.Errorf("default palettes: %v", d)
+	}
+	p0, err := r.Palette("Default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(colorTable(p0), colorTable(readTestPalette(t, "Default"))) {
+		t.Errorf("palette from registry differs from palette.ini")
+	}
+
+	const override = "# Kommentar\n\n[Default]\n0.0: #000000\n1.0: #ffffff\n\n" +
+		"[Broken]\n0.0: #000000\n0.5: 2.0 x\n"
+	if err := r.Load(strings.NewReader(override), "override.ini"); err != nil {
+		t.Fatal(err)
+	}
+	if src, line, _ := r.Source("Default"); src != "override.ini" || line != 3 {
+		t.Errorf("Source() = %s, %d", src, line)
+	}
+	if n := len(r.Names()); n != len(testPaletteNames(t))+1 {
+		t.Errorf("%d palettes after override, want %d", n, len(testPaletteNames(t))+1)
+	}
+	p1, err := r.Palette("Default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := colorTable(p1)[0]; c != (ColorF{0, 0, 0, 1}) {
+		t.Errorf("palette wasn't overridden: %v", c)
+	}
+	if _, err := r.Palette("Broken"); err == nil ||
+		!strings.Contains(err.Error(), "override.ini: line 9:") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := r.Palette("Missing"); err == nil {
+		t.Errorf("missing palette found")
+	}
+}
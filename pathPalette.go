@@ -0,0 +1,297 @@
+package mandel
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Neben dem Ausschnitt der komplexen Ebene kann jede Stuetzstelle eines
+// Pfades auch Angaben zur Palette enthalten. In path.ini werden diese als
+// 'schluessel=wert' hinter den Werten einer Stuetzstelle angegeben:
+//
+//	-1.0  0.0  3.5  80  pal=Default off=0.25 len=512 cps=0.5 tf=pow:0.5
+//
+// Alle Angaben sind optional. Fehlt eine Angabe bei einer Stuetzstelle, so
+// wird der Wert der vorangehenden Stuetzstelle verwendet (bzw. der nach-
+// folgenden, falls keine vorangehende Stuetzstelle einen Wert hat). Fehlt
+// eine Angabe bei allen Stuetzstellen, gilt der Wert der Szene (siehe
+// [PaletteTrack.SetDefaultKey]) und erst danach die Einstellungen des
+// aufrufenden Programms.
+
+var (
+	// regxKeyName prueft die Namen von Paletten, welche bei einer
+	// Stuetzstelle angegeben werden (siehe auch regxSection).
+	regxKeyName = regexp.MustCompile(`^[[:alnum:]]+$`)
+)
+
+// KeyField ist eine Bitmaske, welche angibt, welche Angaben in einem
+// [PaletteKey] oder einem [PaletteFrame] gesetzt sind.
+type KeyField int
+
+const (
+	KeyName KeyField = 1 << iota
+	KeyOffset
+	KeyLength
+	KeyCPS
+	KeyTransfer
+)
+
+// PaletteKey enthaelt die Angaben zur Palette bei einer Stuetzstelle.
+//
+//	Name   Name der Palette (Schluessel 'pal')
+//	Offset Verschiebung der Palette in [0,1) (Schluessel 'off')
+//	Length Laenge der Palette; -1 bedeutet, dass die Laenge der Anzahl
+//	       Iterationen entspricht (Schluessel 'len')
+//	CPS    Geschwindigkeit der Farbrotation in Zyklen pro Sekunde
+//	       (Schluessel 'cps')
+//	Transfer
+//	       Transferfunktion, bspw. 'log' oder 'pow:0.5' (Schluessel 'tf',
+//	       siehe [ParseTransfer])
+type PaletteKey struct {
+	Name     string
+	Offset   float64
+	Length   int
+	CPS      float64
+	Transfer Transfer
+	Fields   KeyField
+}
+
+// Setzt die Angabe key auf den Wert value. Unbekannte Schluessel werden mit
+// [ErrUnknownOption] quittiert.
+func (k *PaletteKey) SetOption(key, value string) error {
+	var err error
+
+	switch key {
+	case "pal":
+		if !regxKeyName.MatchString(value) {
+			return fmt.Errorf("invalid palette name '%s'", value)
+		}
+		k.Name = value
+		k.Fields |= KeyName
+	case "off":
+		if k.Offset, err = strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		k.Fields |= KeyOffset
+	case "len":
+		if k.Length, err = strconv.Atoi(value); err != nil {
+			return err
+		}
+		if k.Length == 0 || k.Length < -1 {
+			return fmt.Errorf("invalid palette length %d", k.Length)
+		}
+		k.Fields |= KeyLength
+	case "cps":
+		if k.CPS, err = strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		k.Fields |= KeyCPS
+	case "tf":
+		if k.Transfer, err = ParseTransfer(value); err != nil {
+			return err
+		}
+		k.Fields |= KeyTransfer
+	default:
+		return fmt.Errorf("%w '%s'", ErrUnknownOption, key)
+	}
+	return nil
+}
+
+// ParseOptions verarbeitet eine Liste von Angaben der Form 'schluessel=wert',
+// welche durch Leerzeichen getrennt sind.
+func (k *PaletteKey) ParseOptions(s string) error {
+	for _, opt := range strings.Fields(s) {
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok {
+			return fmt.Errorf("invalid option '%s'", opt)
+		}
+		if err := k.SetOption(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String erstellt die Darstellung aller gesetzten Angaben, wie sie in
+// path.ini verwendet wird.
+func (k PaletteKey) String() string {
+	var opts []string
+
+	if k.Fields&KeyName != 0 {
+		opts = append(opts, "pal="+k.Name)
+	}
+	if k.Fields&KeyOffset != 0 {
+		opts = append(opts, "off="+strconv.FormatFloat(k.Offset, 'f', -1, 64))
+	}
+	if k.Fields&KeyLength != 0 {
+		opts = append(opts, "len="+strconv.Itoa(k.Length))
+	}
+	if k.Fields&KeyCPS != 0 {
+		opts = append(opts, "cps="+strconv.FormatFloat(k.CPS, 'f', -1, 64))
+	}
+	if k.Fields&KeyTransfer != 0 {
+		opts = append(opts, "tf="+strings.ReplaceAll(k.Transfer.String(), " ", ":"))
+	}
+	return strings.Join(opts, " ")
+}
+
+// PaletteFrame beschreibt die Palette an einer bestimmten Stelle des
+// Pfades. Liegt diese Stelle zwischen zwei Stuetzstellen mit verschiedenen
+// Paletten, so wird zwischen Name0 und Name1 ueberblendet; Mix gibt dabei
+// den Anteil von Name1 an. Phase ist die Anzahl Farbzyklen, welche seit
+// Beginn des Pfades vergangen sind, gemessen in den Zeiteinheiten der
+// [Timeline] (d.h. fuer eine Zeiteinheit von einer Sekunde). Sind die Transferfunktionen
+// der beiden Stuetzstellen vom gleichen Typ, so wird deren Parameter
+// interpoliert (Transfer); andernfalls werden die Resultate von Transfer und
+// Transfer1 mit dem Anteil TransferMix ueberblendet. Welche Angaben
+// ueberhaupt gesetzt sind, ist in Fields ersichtlich.
+type PaletteFrame struct {
+	Name0, Name1        string
+	Mix                 float64
+	Offset              float64
+	Length              int
+	Phase               float64
+	Transfer, Transfer1 Transfer
+	TransferMix         float64
+	Fields              KeyField
+}
+
+// CycleOffset liefert den Offset der Palette inkl. Farbrotation, wobei
+// unit die Dauer einer Zeiteinheit in Sekunden ist (1.0, falls die Dauer
+// der Segmente in Sekunden angegeben wurde). Der Wert liegt immer in [0,1).
+func (f PaletteFrame) CycleOffset(unit float64) float64 {
+	off := math.Mod(f.Offset+f.Phase*unit, 1.0)
+	if off < 0.0 {
+		off += 1.0
+	}
+	return off
+}
+
+// PaletteTrack verwaltet die Angaben zur Palette fuer alle Stuetzstellen
+// eines Pfades sowie die Dauer der Segmente (siehe [Timeline]). Der Typ ist
+// dafuer gedacht, in die Implementationen von [Path] eingebettet zu werden.
+type PaletteTrack struct {
+	keyList []PaletteKey
+	defKey  PaletteKey
+	Timeline
+}
+
+// Setzt die Angaben, welche verwendet werden, falls sie bei keiner
+// Stuetzstelle gemacht werden (bspw. die Angaben zur Palette einer Szene).
+func (pt *PaletteTrack) SetDefaultKey(k PaletteKey) {
+	pt.defKey = k
+}
+
+// Fuegt am Ende eine weitere Stuetzstelle hinzu.
+func (pt *PaletteTrack) AddPaletteKey(k PaletteKey) {
+	pt.keyList = append(pt.keyList, k)
+}
+
+// Fuegt vor der Stuetzstelle i eine weitere ein (mit i gleich der Anzahl
+// Stuetzstellen am Ende).
+func (pt *PaletteTrack) InsertPaletteKey(i int, k PaletteKey) {
+	pt.keyList = slices.Insert(pt.keyList, i, k)
+}
+
+// Entfernt die Stuetzstelle i.
+func (pt *PaletteTrack) RemovePaletteKey(i int) {
+	pt.keyList = slices.Delete(pt.keyList, i, i+1)
+}
+
+// Ersetzt die Angaben zur Palette der Stuetzstelle i.
+func (pt *PaletteTrack) SetPaletteKey(i int, k PaletteKey) {
+	pt.keyList[i] = k
+}
+
+// Liefert die Angaben zur Palette der Stuetzstelle i, so wie sie angegeben
+// wurden (d.h. ohne die Werte benachbarter Stuetzstellen).
+func (pt *PaletteTrack) PaletteKey(i int) PaletteKey {
+	return pt.keyList[i]
+}
+
+// resolve liefert die Liste der Stuetzstellen, bei welchen die fehlenden
+// Angaben durch die Werte der Nachbarn ergaenzt wurden.
+func (pt *PaletteTrack) resolve() []PaletteKey {
+	keys := make([]PaletteKey, len(pt.keyList))
+	copy(keys, pt.keyList)
+	fill := func(dst *PaletteKey, src PaletteKey) {
+		missing := src.Fields &^ dst.Fields
+		if missing&KeyName != 0 {
+			dst.Name = src.Name
+		}
+		if missing&KeyOffset != 0 {
+			dst.Offset = src.Offset
+		}
+		if missing&KeyLength != 0 {
+			dst.Length = src.Length
+		}
+		if missing&KeyCPS != 0 {
+			dst.CPS = src.CPS
+		}
+		if missing&KeyTransfer != 0 {
+			dst.Transfer = src.Transfer
+		}
+		dst.Fields |= missing
+	}
+	for i := 1; i < len(keys); i++ {
+		fill(&keys[i], keys[i-1])
+	}
+	for i := len(keys) - 2; i >= 0; i-- {
+		fill(&keys[i], keys[i+1])
+	}
+	for i := range keys {
+		fill(&keys[i], pt.defKey)
+	}
+	return keys
+}
+
+// GetPalette berechnet die Angaben zur Palette an der Stelle t des Pfades
+// (0.0 <= t <= 1.0). Die Ueberblendung zwischen den Paletten, der Offset und
+// die Laenge verwenden dieselbe Beschleunigung wie die Ansichten des Pfades;
+// die Geschwindigkeit der Farbrotation aendert linear. Phase wird in den
+// Zeiteinheiten der [Timeline] gemessen.
+func (pt *PaletteTrack) GetPalette(t float64) PaletteFrame {
+	var f PaletteFrame
+	var i int
+	var s float64
+
+	keys := pt.resolve()
+	if len(keys) == 0 {
+		return f
+	}
+	i, s = pt.Locate(t, len(keys))
+
+	k0 := keys[i]
+	f.Fields = k0.Fields
+	f.Name0, f.Name1 = k0.Name, k0.Name
+	f.Offset = k0.Offset
+	f.Length = k0.Length
+	f.Transfer, f.Transfer1 = k0.Transfer, k0.Transfer
+	for j := 0; j < i; j++ {
+		f.Phase += 0.5 * (keys[j].CPS + keys[j+1].CPS) * pt.SegmentDuration(j)
+	}
+	if i == len(keys)-1 {
+		return f
+	}
+
+	k1 := keys[i+1]
+	ss := 0.5 * (1.0 - math.Cos(s*math.Pi))
+	f.Name1 = k1.Name
+	if f.Name0 != f.Name1 {
+		f.Mix = ss
+	}
+	f.Offset = (1.0-ss)*k0.Offset + ss*k1.Offset
+	if k0.Length > 0 && k1.Length > 0 {
+		f.Length = int(math.Round((1.0-ss)*float64(k0.Length) + ss*float64(k1.Length)))
+	} else if ss >= 0.5 {
+		f.Length = k1.Length
+	}
+	f.Transfer, f.TransferMix = k0.Transfer.Interp(k1.Transfer, ss)
+	f.Transfer1 = k1.Transfer
+	f.Phase += (k0.CPS*s + 0.5*(k1.CPS-k0.CPS)*s*s) * pt.SegmentDuration(i)
+	return f
+}
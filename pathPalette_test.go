@@ -0,0 +1,92 @@
+package mandel
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPaletteTrack(t *testing.T) {
+	var pt PaletteTrack
+
+	for _, opts := range []string{"pal=Default cps=1", "pal=Fire off=0.5", "cps=0"} {
+		var k PaletteKey
+		if err := k.ParseOptions(opts); err != nil {
+			t.Fatal(err)
+		}
+		if k.String() != opts {
+			t.Errorf("String() = '%s', want '%s'", k.String(), opts)
+		}
+		pt.AddPaletteKey(k)
+	}
+
+	f := pt.GetPalette(0.0)
+	if f.Name0 != "Default" || f.Name1 != "Fire" || f.Mix != 0.0 {
+		t.Errorf("t=0.0: got %+v", f)
+	}
+	if f.Offset != 0.5 || f.Fields&KeyLength != 0 {
+		t.Errorf("t=0.0: offset must be taken from the next key: %+v", f)
+	}
+	f = pt.GetPalette(0.25)
+	if math.Abs(f.Mix-0.5) > 1e-9 || math.Abs(f.Phase-0.5) > 1e-9 {
+		t.Errorf("t=0.25: got %+v", f)
+	}
+	f = pt.GetPalette(1.0)
+	if f.Name0 != "Fire" || f.Name1 != "Fire" || math.Abs(f.Phase-1.5) > 1e-9 {
+		t.Errorf("t=1.0: got %+v", f)
+	}
+	if off := f.CycleOffset(2.0); math.Abs(off-0.5) > 1e-9 {
+		t.Errorf("CycleOffset(2.0) = %v, want 0.5", off)
+	}
+
+	var k PaletteKey
+	if err := k.SetOption("speed", "1"); err == nil {
+		t.Errorf("unknown option accepted")
+	}
+}
+
+func TestPaletteTrackTransfer(t *testing.T) {
+	var pt PaletteTrack
+
+	for _, opts := range []string{"tf=pow:0.5", "tf=pow:1", "tf=log"} {
+		var k PaletteKey
+		if err := k.ParseOptions(opts); err != nil {
+			t.Fatal(err)
+		}
+		if k.String() != opts {
+			t.Errorf("String() = '%s', want '%s'", k.String(), opts)
+		}
+		pt.AddPaletteKey(k)
+	}
+	f := pt.GetPalette(0.25)
+	if f.Transfer.Type != TransferPow || math.Abs(f.Transfer.Param-0.75) > 1e-9 ||
+		f.TransferMix != 0.0 {
+		t.Errorf("t=0.25: got %+v", f)
+	}
+	f = pt.GetPalette(0.75)
+	if f.Transfer.Type != TransferPow || f.Transfer1.Type != TransferLog ||
+		math.Abs(f.TransferMix-0.5) > 1e-9 {
+		t.Errorf("t=0.75: got %+v", f)
+	}
+}
+
+func TestPaletteTrackTimeline(t *testing.T) {
+	var pt PaletteTrack
+
+	for _, opts := range []string{"cps=1", "cps=1", "cps=1"} {
+		var k PaletteKey
+		if err := k.ParseOptions(opts); err != nil {
+			t.Fatal(err)
+		}
+		pt.AddPaletteKey(k)
+	}
+	pt.SetDurations([]float64{3.0, 1.0})
+	if i, s := pt.Locate(0.5, 3); i != 0 || math.Abs(s-2.0/3.0) > 1e-9 {
+		t.Errorf("Locate(0.5) = %d, %v", i, s)
+	}
+	if i, s := pt.Locate(0.875, 3); i != 1 || math.Abs(s-0.5) > 1e-9 {
+		t.Errorf("Locate(0.875) = %d, %v", i, s)
+	}
+	if f := pt.GetPalette(0.875); math.Abs(f.Phase-3.5) > 1e-9 {
+		t.Errorf("Phase = %v, want 3.5", f.Phase)
+	}
+}
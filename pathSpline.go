@@ -0,0 +1,173 @@
+package mandel
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// PathInterp gibt an, wie zwischen den Stuetzstellen eines Pfades
+// interpoliert wird.
+//
+//	ease    Jedes Segment wird einzeln mit einer Cosinus-Beschleunigung
+//	        durchlaufen; die Position folgt dabei der Breite, so dass das Ziel
+//	        auch bei tiefen Zooms im Bild bleibt. An den Stuetzstellen kommt
+//	        die Kamera jeweils zum Stillstand.
+//	spline  x, y und log(w) werden mit einem Kochanek-Bartels-Spline ueber
+//	        alle Stuetzstellen interpoliert (siehe [SplineParams]). Die
+//	        Kamera faehrt ohne Geschwindigkeitsspruenge durch die
+//	        Stuetzstellen hindurch.
+type PathInterp int
+
+const (
+	InterpEase PathInterp = iota
+	InterpSpline
+)
+
+func (pi PathInterp) String() string {
+	switch pi {
+	case InterpEase:
+		return "ease"
+	case InterpSpline:
+		return "spline"
+	default:
+		return "Unknown path interpolation"
+	}
+}
+
+func (pi *PathInterp) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "ease":
+		*pi = InterpEase
+	case "spline":
+		*pi = InterpSpline
+	default:
+		return errors.New("Unknown path interpolation: " + s)
+	}
+	return nil
+}
+
+// SplineParams sind die Parameter eines Kochanek-Bartels-Splines. Mit dem
+// Nullwert entsteht ein Catmull-Rom-Spline, welcher an den Stuetzstellen
+// stetig differenzierbar (C1) ist.
+//
+//	Tension     1 verkuerzt die Tangenten (engere Kurven), -1 verlaengert sie
+//	Bias        1 betont das vorangehende, -1 das nachfolgende Segment
+//	Continuity  != 0 erzeugt bewusst Knicke an den Stuetzstellen (nicht C1)
+type SplineParams struct {
+	Tension, Bias, Continuity float64
+}
+
+// Eval berechnet den Wert des Splines durch die Punkte p an der Stelle s
+// (0.0 <= s <= 1.0) im Segment i, d.h. zwischen p[i] und p[i+1]. durs
+// enthaelt die Dauer der Segmente; die Tangenten werden so angepasst, dass
+// die Geschwindigkeit auch bei unterschiedlich langen Segmenten stetig
+// bleibt. Ist durs nil, so dauern alle Segmente gleich lang. Am Anfang und
+// am Ende werden die Punkte gespiegelt, so dass ein Spline durch zwei
+// Punkte eine Gerade mit konstanter Geschwindigkeit ist.
+func (sp SplineParams) Eval(p, durs []float64, i int, s float64) float64 {
+	n := len(p)
+	if n == 1 {
+		return p[0]
+	}
+	if i >= n-1 {
+		i, s = n-2, 1.0
+	}
+	d1, d2 := sp.tangents(p, durs, i)
+	return hermite(p[i], p[i+1], d1, d2, s)
+}
+
+// tangents liefert die Tangenten am Anfang und am Ende des Segmentes i des
+// Splines durch die Punkte p (siehe [SplineParams.Eval]).
+func (sp SplineParams) tangents(p, durs []float64, i int) (d1, d2 float64) {
+	n := len(p)
+	delta := func(j int) float64 {
+		j = min(max(j, 0), n-2)
+		return p[j+1] - p[j]
+	}
+	return sp.kbTangents(delta(i-1), delta(i), delta(i), delta(i+1),
+		segDur(durs, n, i-1), segDur(durs, n, i), segDur(durs, n, i+1))
+}
+
+// segDur liefert die Dauer des Segmentes j eines Pfades mit n Punkten. Vor
+// dem ersten und nach dem letzten Segment wird gespiegelt.
+func segDur(durs []float64, n, j int) float64 {
+	if durs == nil {
+		return 1.0
+	}
+	return durs[min(max(j, 0), n-2)]
+}
+
+// kbTangents berechnet die Tangenten am Anfang und am Ende eines Segmentes
+// mit der Dauer n1 aus der Aenderung im Segment davor (q0, Dauer n0) und
+// danach (q2, Dauer n2). q1a und q1b sind die Aenderung im Segment selber,
+// gemessen am Anfang resp. am Ende (bei Eval identisch).
+func (sp SplineParams) kbTangents(q0, q1a, q1b, q2, n0, n1, n2 float64) (d1, d2 float64) {
+	t, b, c := sp.Tension, sp.Bias, sp.Continuity
+	d1 = 0.5*(1-t)*(1+b)*(1+c)*q0 + 0.5*(1-t)*(1-b)*(1-c)*q1a
+	d2 = 0.5*(1-t)*(1+b)*(1-c)*q1b + 0.5*(1-t)*(1-b)*(1+c)*q2
+	if n0+n1 > 0.0 {
+		d1 *= 2.0 * n1 / (n0 + n1)
+	}
+	if n1+n2 > 0.0 {
+		d2 *= 2.0 * n1 / (n1 + n2)
+	}
+	return d1, d2
+}
+
+// EvalPos berechnet wie [SplineParams.Eval] eine Koordinate des
+// Mittelpunktes, wobei lw die Logarithmen der Breiten an den Stuetzstellen
+// enthaelt. Innerhalb eines Segmentes wird die Position so an die Breite
+// gekoppelt, dass um einen im Bild festen Punkt gezoomt wird; der Spline
+// bestimmt nur noch die Abweichung davon, und zwar gemessen in
+// Bildbreiten. Damit bleibt das Ziel auch bei tiefen Zooms im Bild. Ohne
+// Zoom (gleiche Breiten) entspricht das Resultat Eval.
+func (sp SplineParams) EvalPos(p, lw, durs []float64, i int, s float64) float64 {
+	n := len(p)
+	if n == 1 {
+		return p[0]
+	}
+	if i >= n-1 {
+		i, s = n-2, 1.0
+	}
+
+	// Die Basis im Segment j ist linear in der Breite w, resp. linear in s,
+	// falls sich w nicht aendert. vel liefert deren Ableitung nach s am
+	// Anfang (end = 0) oder am Ende (end = 1) des Segmentes; vor dem ersten
+	// und nach dem letzten Segment wird gespiegelt.
+	widths := func(j int) (w0, w1 float64, zoom bool) {
+		w0, w1 = math.Exp(lw[j]), math.Exp(lw[j+1])
+		return w0, w1, math.Abs(w0-w1) > 1e-9*math.Max(w0, w1)
+	}
+	vel := func(j, end int) float64 {
+		if j < 0 {
+			j, end = 0, 0
+		} else if j > n-2 {
+			j, end = n-2, 1
+		}
+		w0, w1, zoom := widths(j)
+		if !zoom {
+			return p[j+1] - p[j]
+		}
+		l1, l2 := sp.tangents(lw, durs, j)
+		dw := w0 * l1
+		if end == 1 {
+			dw = w1 * l2
+		}
+		return (p[j] - p[j+1]) * dw / (w0 - w1)
+	}
+	d1, d2 := sp.kbTangents(vel(i-1, 1), vel(i, 0), vel(i, 1), vel(i+1, 0),
+		segDur(durs, n, i-1), segDur(durs, n, i), segDur(durs, n, i+1))
+
+	// Die Abweichung von der Basis wird in Bildbreiten gemessen und ist so
+	// gewaehlt, dass die Tangenten an den Stuetzstellen d1 und d2 sind.
+	w := math.Exp(sp.Eval(lw, durs, i, s))
+	w0, w1, zoom := widths(i)
+	base := p[i] + s*(p[i+1]-p[i])
+	if zoom {
+		base = p[i+1] + (p[i]-p[i+1])*(w-w1)/(w0-w1)
+	}
+	e1 := (d1 - vel(i, 0)) / w0
+	e2 := (d2 - vel(i, 1)) / w1
+	return base + w*hermite(0.0, 0.0, e1, e2, s)
+}
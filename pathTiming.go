@@ -0,0 +1,244 @@
+package mandel
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Timing gibt an, wie die Dauer einer Animation auf die Segmente eines
+// Pfades verteilt wird, sofern bei den Stuetzstellen keine Dauer (Schluessel
+// 'dur') angegeben ist.
+//
+//	uniform  Alle Segmente dauern gleich lang.
+//	zoom     Die Dauer eines Segmentes ist proportional zur Aenderung von
+//	         log(w), womit die Zoom-Geschwindigkeit konstant bleibt.
+//	         Verschiebungen werden relativ zur Breite gemessen, so dass auch
+//	         Segmente ohne Zoom eine angemessene Dauer erhalten.
+type Timing int
+
+const (
+	TimingUniform Timing = iota
+	TimingZoom
+)
+
+// minZoomWeight ist das kleinste Gewicht eines Segmentes bei TimingZoom
+// (entspricht einem Zoom um ca. 10%). Damit erhalten auch Segmente, bei
+// welchen sich nur die Anzahl Iterationen aendert, einige Bilder.
+const minZoomWeight = 0.1
+
+func (tm Timing) String() string {
+	switch tm {
+	case TimingUniform:
+		return "uniform"
+	case TimingZoom:
+		return "zoom"
+	default:
+		return "Unknown timing"
+	}
+}
+
+func (tm *Timing) Set(s string) error {
+	switch strings.ToLower(s) {
+	case "uniform":
+		*tm = TimingUniform
+	case "zoom":
+		*tm = TimingZoom
+	default:
+		return errors.New("Unknown timing: " + s)
+	}
+	return nil
+}
+
+// zoomWeight liefert das Gewicht des Segmentes zwischen k0 und k1 fuer
+// TimingZoom.
+func zoomWeight(k0, k1 SceneKey) float64 {
+	x0, y0, w0, _ := k0.Values()
+	x1, y1, w1, _ := k1.Values()
+	d := math.Hypot(math.Log(w1/w0), math.Hypot(x1-x0, y1-y0)/math.Max(w0, w1))
+	return math.Max(d, minZoomWeight)
+}
+
+// Durations berechnet die Dauer (in Sekunden) jedes Segmentes der Szene.
+// Segmente mit einer Angabe 'dur' bei der ersten Stuetzstelle erhalten
+// genau diese Dauer. Die restliche Zeit wird gemaess timing auf die
+// uebrigen Segmente verteilt. Ist total > 0, so ist dies die Gesamtdauer;
+// andernfalls dauern die uebrigen Segmente zusammen so lange, wie wenn
+// jedes segDur Sekunden dauern wuerde.
+func (s *Scene) Durations(timing Timing, total, segDur float64) ([]float64, error) {
+	if len(s.Keys) < 2 {
+		return nil, nil
+	}
+	durs := make([]float64, len(s.Keys)-1)
+	weights := make([]float64, len(durs))
+	fixed, free := 0.0, 0.0
+	for i := range durs {
+		if d := s.Keys[i].Dur; d > 0.0 {
+			durs[i] = d
+			fixed += d
+			continue
+		}
+		weights[i] = 1.0
+		if timing == TimingZoom {
+			weights[i] = zoomWeight(s.Keys[i], s.Keys[i+1])
+		}
+		free += weights[i]
+	}
+	if free == 0.0 {
+		return durs, nil
+	}
+	rest := 0.0
+	for _, w := range weights {
+		if w > 0.0 {
+			rest += segDur
+		}
+	}
+	if total > 0.0 {
+		rest = total - fixed
+		if rest <= 0.0 {
+			return nil, fmt.Errorf("duration of %gs is too short for the segments with a fixed duration (%gs)",
+				total, fixed)
+		}
+	}
+	for i, w := range weights {
+		if w > 0.0 {
+			durs[i] = rest * w / free
+		}
+	}
+	return durs, nil
+}
+
+// Timeline enthaelt die Dauer der Segmente eines Pfades und bildet die
+// Position t auf dem Pfad (0.0 <= t <= 1.0, proportional zur Zeit) auf die
+// Segmente ab. Im Nullwert dauert jedes Segment eine Zeiteinheit (bspw.
+// eine Sekunde). Zu jedem Segment wird ausserdem festgehalten, ob seine
+// Dauer fest vorgegeben ist (Schluessel 'dur' in der Szene).
+type Timeline struct {
+	durs  []float64
+	fixed []bool
+}
+
+// Setzt die Dauer der Segmente; durs[i] ist die Dauer des Segmentes
+// zwischen den Stuetzstellen i und i+1. Mit nil dauern wieder alle
+// Segmente eine Zeiteinheit.
+func (tl *Timeline) SetDurations(durs []float64) {
+	tl.durs = durs
+}
+
+// SetFixed legt fest, welche Segmente eine fest vorgegebene Dauer haben;
+// fixed[i] gilt fuer das Segment zwischen den Stuetzstellen i und i+1.
+func (tl *Timeline) SetFixed(fixed []bool) {
+	tl.fixed = fixed
+}
+
+// FixedDuration liefert die Dauer des Segmentes i, sofern diese fest
+// vorgegeben ist, und sonst 0.
+func (tl *Timeline) FixedDuration(i int) float64 {
+	if i < len(tl.fixed) && tl.fixed[i] {
+		return tl.SegmentDuration(i)
+	}
+	return 0.0
+}
+
+// fixedList liefert eine Kopie der Angaben zu den fest vorgegebenen
+// Segmenten mit der gleichen Laenge wie die Liste der Dauern.
+func (tl *Timeline) fixedList() []bool {
+	fixed := make([]bool, len(tl.durs))
+	copy(fixed, tl.fixed)
+	return fixed
+}
+
+// InsertKey passt die Dauer der Segmente an, wenn vor der Stuetzstelle i
+// eine neue eingefuegt wird: das geteilte Segment wird halbiert, ein neues
+// Segment am Anfang oder am Ende dauert gleich lang wie sein Nachbar. Die
+// Gesamtdauer aendert sich also nur beim Verlaengern des Pfades. Die Haelften
+// eines fest vorgegebenen Segmentes sind ebenfalls fest vorgegeben, neue
+// Segmente am Anfang oder am Ende nicht.
+func (tl *Timeline) InsertKey(i int) {
+	if len(tl.durs) == 0 {
+		return
+	}
+	durs := slices.Clone(tl.durs)
+	fixed := tl.fixedList()
+	switch {
+	case i <= 0:
+		durs = slices.Insert(durs, 0, durs[0])
+		fixed = slices.Insert(fixed, 0, false)
+	case i <= len(durs):
+		durs[i-1] /= 2.0
+		durs = slices.Insert(durs, i, durs[i-1])
+		fixed = slices.Insert(fixed, i, fixed[i-1])
+	case i == len(durs)+1:
+		durs = append(durs, durs[len(durs)-1])
+		fixed = append(fixed, false)
+	}
+	tl.durs, tl.fixed = durs, fixed
+}
+
+// RemoveKey passt die Dauer der Segmente an, wenn die Stuetzstelle i
+// entfernt wird: die beiden Segmente um die Stuetzstelle werden zu einem
+// zusammengefasst, am Anfang oder am Ende faellt ein Segment weg. Das
+// zusammengefasste Segment ist nur dann fest vorgegeben, wenn es beide
+// Teile waren.
+func (tl *Timeline) RemoveKey(i int) {
+	if len(tl.durs) == 0 || i < 0 || i > len(tl.durs) {
+		return
+	}
+	durs := slices.Clone(tl.durs)
+	fixed := tl.fixedList()
+	switch {
+	case i == 0:
+		durs, fixed = durs[1:], fixed[1:]
+	case i == len(durs):
+		durs, fixed = durs[:i-1], fixed[:i-1]
+	default:
+		durs[i-1] += durs[i]
+		durs = slices.Delete(durs, i, i+1)
+		fixed[i-1] = fixed[i-1] && fixed[i]
+		fixed = slices.Delete(fixed, i, i+1)
+	}
+	tl.durs, tl.fixed = durs, fixed
+}
+
+// SegmentDuration liefert die Dauer des Segmentes i.
+func (tl *Timeline) SegmentDuration(i int) float64 {
+	if i < len(tl.durs) {
+		return tl.durs[i]
+	}
+	return 1.0
+}
+
+// Duration liefert die Gesamtdauer eines Pfades mit n Stuetzstellen.
+func (tl *Timeline) Duration(n int) float64 {
+	d := 0.0
+	for i := 0; i < n-1; i++ {
+		d += tl.SegmentDuration(i)
+	}
+	return d
+}
+
+// Locate bestimmt fuer die Position t auf einem Pfad mit n Stuetzstellen
+// das Segment i und die Position s (0.0 <= s < 1.0) innerhalb dieses
+// Segmentes. Am Ende des Pfades ist i = n-1 und s = 0.
+func (tl *Timeline) Locate(t float64, n int) (i int, s float64) {
+	if n < 2 || t >= 1.0 {
+		return max(n-1, 0), 0.0
+	}
+	if t <= 0.0 {
+		return 0, 0.0
+	}
+	tau := t * tl.Duration(n)
+	for i = 0; i < n-2; i++ {
+		d := tl.SegmentDuration(i)
+		if tau < d {
+			break
+		}
+		tau -= d
+	}
+	if d := tl.SegmentDuration(i); d > 0.0 {
+		s = math.Min(tau/d, 1.0)
+	}
+	return i, s
+}
@@ -0,0 +1,560 @@
+package mandel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Eine Szene beschreibt eine Animation vollstaendig: die Formel, die
+// Stuetzstellen des Pfades, die Paletten, die Bildgroesse, das Sampling und
+// die Ausgabe. Szenen werden in path.ini abgelegt, wobei ein Abschnitt ohne
+// Optionen einem gewoehnlichen Pfad entspricht:
+//
+//	[Sunset]
+//	formula  = mandelbrot
+//	size     = 640x480
+//	sampling = 2x2
+//	images   = 256
+//	pal      = Glow
+//	len      = 256
+//
+//	-1.0             0.0            3.5              80
+//	-0.745428000525  0.11300999994  0.00000000005  1200  cps=0.5
+//
+//	[Sunset.Glow]
+//	0.0: #000000
+//	0.5: #ff8000
+//	1.0: #ffffc0
+//
+// Die Optionen werden gemaess sceneSchema geprueft (siehe
+// [Scene.SetOption]). Zeilen mit Zahlen sind die Stuetzstellen (siehe
+// [SceneKey]). Unterabschnitte der Form [Szene.Palette] enthalten Paletten
+// im Format von palette.ini, welche nur in dieser Szene verfuegbar sind und
+// Paletten mit gleichem Namen aus palette.ini verdecken.
+
+const (
+	// sceneFileName ist der Name der Datei, in welcher Szenen gesucht
+	// werden.
+	sceneFileName = "path.ini"
+
+	// numPattern erkennt Gleitkommazahlen, auch in Exponentialschreibweise.
+	numPattern = `[+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[Ee][+-]?[0-9]+)?`
+
+	// Die Formeln, welche in einer Szene verwendet werden koennen.
+	FormulaMandelbrot = "mandelbrot"
+)
+
+var (
+	// regxSceneKey erkennt die Stuetzstellen einer Szene: x und y des
+	// Mittelpunktes, Breite, max. Anzahl Iterationen und optional die
+	// Angaben zur Palette.
+	regxSceneKey = regexp.MustCompile(`^ *(` + numPattern + `) +(` + numPattern + `) +(` + numPattern + `) +([0-9]+)((?: +[[:alpha:]]+=[^ ]+)*) *$`)
+)
+
+// Scene enthaelt alle Angaben einer Szene. Ob eine Option in der Szene
+// angegeben wurde, kann mit [Scene.IsSet] geprueft werden; nicht angegebene
+// Optionen haben den Nullwert und sollten durch die Einstellungen des
+// aufrufenden Programms ersetzt werden.
+//
+//	Option     Feld            Wert
+//	formula    Formula         mandelbrot
+//	size       Cols, Rows      Breite x Hoehe in Pixel, bspw. 640x480
+//	sampling   Sampling        1x1, 2x2, 4x4 oder 8x8
+//	images     Images          Anzahl Bilder zwischen zwei Stuetzstellen
+//	fps        FPS             Bilder pro Sekunde
+//	bits       Bits            Bits pro Farbkanal (8 oder 16)
+//	imgdir     ImgDir          Verzeichnis fuer die Bilder
+//	layers     Layers          Name eines Ebenen-Stapels aus layer.ini
+//	                           (dessen Paletten werden nicht animiert, daher
+//	                           nicht zusammen mit pal, off, len, cps, tf)
+//	histogram  Histogram       true oder false
+//	lighting   Lighting        true oder false
+//	inside     Inside          Farbe innerhalb der Menge (siehe [ParseColor])
+//	interp     Interp          ease oder spline (siehe [PathInterp])
+//	timing     Timing          uniform oder zoom (siehe [Timing])
+//	autoiter   AutoIter        true: die Anzahl Iterationen wird geschaetzt
+//	                           (siehe [AutoIter])
+//	render     Render          frames, expmap oder keyframes (siehe
+//	                           [RenderMode])
+//	quality    Quality         Qualitaet bei keyframes (>= 1, siehe
+//	                           [KeyframePlan])
+//	blur       Blur            off, samples oder flow (siehe [BlurMode])
+//	blursamples
+//	           BlurSamples     Anzahl Ansichten pro Bild
+//	shutter    Shutter         Verschlusswinkel in Grad (siehe
+//	                           [ShutterTimes])
+//	duration   Duration        Gesamtdauer in Sekunden (siehe
+//	                           [Scene.Durations])
+//	tension, bias, continuity
+//	           Spline          Parameter des Splines in [-1,1] (siehe
+//	                           [SplineParams])
+//	pal, off, len, cps, tf
+//	           Palette         Angaben zur Palette fuer alle Stuetzstellen
+//	                           (siehe [PaletteKey])
+type Scene struct {
+	Name        string
+	Formula     string
+	Cols, Rows  int
+	Sampling    SampleMode
+	Images      int
+	FPS         float64
+	Bits        int
+	ImgDir      string
+	Layers      string
+	Histogram   bool
+	Lighting    bool
+	Inside      string
+	Interp      PathInterp
+	Timing      Timing
+	AutoIter    bool
+	Render      RenderMode
+	Quality     float64
+	Blur        BlurMode
+	BlurSamples int
+	Shutter     float64
+	Duration    float64
+	Spline      SplineParams
+	Palette     PaletteKey
+	Keys        []SceneKey
+	Palettes    *PaletteRegistry
+	opts        map[string]string
+}
+
+// sceneOption ist ein Eintrag im Schema der Szenen: der Schluessel und die
+// Funktion, welche den Wert prueft und in der Szene ablegt.
+type sceneOption struct {
+	key string
+	set func(s *Scene, value string) error
+}
+
+// sceneSchema enthaelt alle Optionen, welche in einer Szene erlaubt sind.
+var sceneSchema = []sceneOption{
+	{"formula", func(s *Scene, value string) error {
+		if value != FormulaMandelbrot {
+			return fmt.Errorf("unknown formula '%s'", value)
+		}
+		s.Formula = value
+		return nil
+	}},
+	{"size", func(s *Scene, value string) error {
+		cols, rows, ok := strings.Cut(value, "x")
+		if !ok {
+			return fmt.Errorf("invalid size '%s' (expected WxH)", value)
+		}
+		var err error
+		if s.Cols, err = parsePositive(cols); err != nil {
+			return err
+		}
+		s.Rows, err = parsePositive(rows)
+		return err
+	}},
+	{"sampling", func(s *Scene, value string) error {
+		return s.Sampling.Set(value)
+	}},
+	{"images", func(s *Scene, value string) (err error) {
+		s.Images, err = parsePositive(value)
+		return err
+	}},
+	{"fps", func(s *Scene, value string) (err error) {
+		if s.FPS, err = strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		if s.FPS <= 0.0 {
+			return fmt.Errorf("fps must be positive")
+		}
+		return nil
+	}},
+	{"bits", func(s *Scene, value string) (err error) {
+		if s.Bits, err = strconv.Atoi(value); err != nil {
+			return err
+		}
+		if s.Bits != 8 && s.Bits != 16 {
+			return fmt.Errorf("invalid number of bits per color channel: %d", s.Bits)
+		}
+		return nil
+	}},
+	{"imgdir", func(s *Scene, value string) error {
+		s.ImgDir = value
+		return nil
+	}},
+	{"layers", func(s *Scene, value string) error {
+		s.Layers = value
+		return nil
+	}},
+	{"histogram", func(s *Scene, value string) (err error) {
+		s.Histogram, err = strconv.ParseBool(value)
+		return err
+	}},
+	{"lighting", func(s *Scene, value string) (err error) {
+		s.Lighting, err = strconv.ParseBool(value)
+		return err
+	}},
+	{"inside", func(s *Scene, value string) error {
+		if _, err := ParseColor(value); err != nil {
+			return err
+		}
+		s.Inside = value
+		return nil
+	}},
+	{"interp", func(s *Scene, value string) error {
+		return s.Interp.Set(value)
+	}},
+	{"timing", func(s *Scene, value string) error {
+		return s.Timing.Set(value)
+	}},
+	{"autoiter", func(s *Scene, value string) (err error) {
+		s.AutoIter, err = strconv.ParseBool(value)
+		return err
+	}},
+	{"render", func(s *Scene, value string) error {
+		return s.Render.Set(value)
+	}},
+	{"quality", func(s *Scene, value string) (err error) {
+		if s.Quality, err = strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		if s.Quality < 1.0 {
+			return fmt.Errorf("quality must be at least 1")
+		}
+		return nil
+	}},
+	{"blur", func(s *Scene, value string) error {
+		return s.Blur.Set(value)
+	}},
+	{"blursamples", func(s *Scene, value string) (err error) {
+		s.BlurSamples, err = parsePositive(value)
+		return err
+	}},
+	{"shutter", func(s *Scene, value string) (err error) {
+		if s.Shutter, err = strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		if s.Shutter <= 0.0 || s.Shutter > 360.0 {
+			return fmt.Errorf("shutter angle must be in (0,360]")
+		}
+		return nil
+	}},
+	{"duration", func(s *Scene, value string) (err error) {
+		if s.Duration, err = strconv.ParseFloat(value, 64); err != nil {
+			return err
+		}
+		if s.Duration <= 0.0 {
+			return fmt.Errorf("duration must be positive")
+		}
+		return nil
+	}},
+	{"tension", sceneSplineOption(func(s *Scene) *float64 { return &s.Spline.Tension })},
+	{"bias", sceneSplineOption(func(s *Scene) *float64 { return &s.Spline.Bias })},
+	{"continuity", sceneSplineOption(func(s *Scene) *float64 { return &s.Spline.Continuity })},
+	{"pal", scenePaletteOption("pal")},
+	{"off", scenePaletteOption("off")},
+	{"len", scenePaletteOption("len")},
+	{"cps", scenePaletteOption("cps")},
+	{"tf", scenePaletteOption("tf")},
+}
+
+// scenePaletteOption liefert die Funktion fuer die Option key, welche an
+// [PaletteKey.SetOption] weitergereicht wird.
+func scenePaletteOption(key string) func(s *Scene, value string) error {
+	return func(s *Scene, value string) error {
+		return s.Palette.SetOption(key, value)
+	}
+}
+
+// sceneSplineOption liefert die Funktion fuer einen Parameter des Splines,
+// wobei field das entsprechende Feld der Szene liefert.
+func sceneSplineOption(field func(s *Scene) *float64) func(s *Scene, value string) error {
+	return func(s *Scene, value string) error {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		if v < -1.0 || v > 1.0 {
+			return fmt.Errorf("spline parameter must be in [-1,1]: %v", v)
+		}
+		*field(s) = v
+		return nil
+	}
+}
+
+// parsePositive liest eine positive, ganze Zahl.
+func parsePositive(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("value must be positive: %d", n)
+	}
+	return n, nil
+}
+
+// Erstellt eine neue, leere Szene mit dem Namen name.
+func NewScene(name string) *Scene {
+	return &Scene{
+		Name:     name,
+		Formula:  FormulaMandelbrot,
+		Palettes: NewPaletteRegistry(),
+		opts:     make(map[string]string),
+	}
+}
+
+// Setzt die Option key auf den Wert value. Unbekannte Schluessel werden mit
+// [ErrUnknownOption] quittiert.
+func (s *Scene) SetOption(key, value string) error {
+	for _, opt := range sceneSchema {
+		if opt.key != key {
+			continue
+		}
+		if err := opt.set(s, value); err != nil {
+			return err
+		}
+		s.opts[key] = value
+		return nil
+	}
+	return fmt.Errorf("%w '%s'", ErrUnknownOption, key)
+}
+
+// IsSet prueft, ob die Option key in der Szene angegeben wurde.
+func (s *Scene) IsSet(key string) bool {
+	_, ok := s.opts[key]
+	return ok
+}
+
+// NewPalette erstellt die Palette name, wobei die Paletten der Szene
+// Vorrang vor denjenigen aus palette.ini haben (siehe [NewPalette]).
+func (s *Scene) NewPalette(name string) (Palette, error) {
+	if s.Palettes.Has(name) {
+		return s.Palettes.Palette(name)
+	}
+	return NewPalette(name)
+}
+
+// SceneKey ist eine Stuetzstelle einer Szene. Die Koordinaten werden als
+// Text mit der vollen Genauigkeit aus der Datei abgelegt, damit auch
+// Implementationen mit beliebiger Genauigkeit keine Stellen verlieren. Dur
+// ist die Dauer (in Sekunden) des Segmentes bis zur naechsten Stuetzstelle
+// (Schluessel 'dur'); 0 bedeutet, dass die Dauer berechnet wird (siehe
+// [Scene.Durations]). Drehung und Verzerrung der Ansicht werden mit den
+// Schluesseln 'rot', 'skew' und 'stretch' angegeben (siehe [Transform]);
+// fehlen sie, so ist die Ansicht achsenparallel. Alle anderen Angaben
+// hinter der Anzahl Iterationen betreffen die Palette.
+type SceneKey struct {
+	X, Y, W   string
+	MaxIter   int
+	Dur       float64
+	Transform Transform
+	Palette   PaletteKey
+}
+
+// Values liefert die Koordinaten der Stuetzstelle als float64.
+func (k SceneKey) Values() (x, y, w float64, maxIt int) {
+	x, _ = strconv.ParseFloat(k.X, 64)
+	y, _ = strconv.ParseFloat(k.Y, 64)
+	w, _ = strconv.ParseFloat(k.W, 64)
+	return x, y, w, k.MaxIter
+}
+
+// String erstellt die Darstellung der Stuetzstelle, wie sie in path.ini
+// verwendet wird. Die Koordinaten werden unveraendert uebernommen.
+func (k SceneKey) String() string {
+	line := fmt.Sprintf("%s %s %s %d", k.X, k.Y, k.W, k.MaxIter)
+	if opts := k.options(); opts != "" {
+		line += " " + opts
+	}
+	return line
+}
+
+// options liefert die Angaben hinter der Anzahl Iterationen.
+func (k SceneKey) options() string {
+	var opts []string
+
+	if k.Dur > 0.0 {
+		opts = append(opts, "dur="+strconv.FormatFloat(k.Dur, 'f', -1, 64))
+	}
+	if tr := k.Transform.String(); tr != "" {
+		opts = append(opts, tr)
+	}
+	if pal := k.Palette.String(); pal != "" {
+		opts = append(opts, pal)
+	}
+	return strings.Join(opts, " ")
+}
+
+// parseSceneKey wertet eine Zeile mit einer Stuetzstelle aus.
+func parseSceneKey(line string) (SceneKey, error) {
+	var k SceneKey
+
+	m := regxSceneKey.FindStringSubmatch(line)
+	if m == nil {
+		return k, fmt.Errorf("invalid line")
+	}
+	k.X, k.Y, k.W = m[1], m[2], m[3]
+	_, _, w, _ := k.Values()
+	if w <= 0.0 {
+		return k, fmt.Errorf("width must be positive")
+	}
+	it, err := strconv.Atoi(m[4])
+	if err != nil {
+		return k, err
+	}
+	if it < 1 {
+		return k, fmt.Errorf("max iterations must be positive")
+	}
+	k.MaxIter = it
+	for _, opt := range strings.Fields(m[5]) {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "dur":
+			if k.Dur, err = strconv.ParseFloat(value, 64); err != nil {
+				return k, err
+			}
+			if k.Dur <= 0.0 {
+				return k, fmt.Errorf("duration must be positive")
+			}
+		case "rot", "skew", "stretch":
+			err = k.Transform.SetOption(key, value)
+		default:
+			err = k.Palette.SetOption(key, value)
+		}
+		if err != nil {
+			return k, err
+		}
+	}
+	return k, nil
+}
+
+// WriteSection schreibt die Szene im Format von path.ini nach w: die
+// Optionen in der Reihenfolge von sceneSchema und mit den Werten, wie sie
+// mit [Scene.SetOption] gesetzt wurden, danach die Stuetzstellen (mit
+// buendig ausgerichteten Koordinaten) und zuletzt die Paletten der Szene
+// als Unterabschnitte. Die Koordinaten werden als Text geschrieben, so wie
+// sie in Keys abgelegt sind; es gehen also keine Stellen verloren.
+func (s *Scene) WriteSection(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", s.Name)
+	for _, opt := range sceneSchema {
+		if value, ok := s.opts[opt.key]; ok {
+			fmt.Fprintf(bw, "%-8s = %s\n", opt.key, value)
+		}
+	}
+	if len(s.opts) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	var widths [3]int
+	for _, k := range s.Keys {
+		for i, c := range []string{k.X, k.Y, k.W} {
+			widths[i] = max(widths[i], len(c))
+		}
+	}
+	for _, k := range s.Keys {
+		fmt.Fprintf(bw, "%-*s  %-*s  %-*s  %4d", widths[0], k.X, widths[1], k.Y,
+			widths[2], k.W, k.MaxIter)
+		if opts := k.options(); opts != "" {
+			fmt.Fprintf(bw, "  %s", opts)
+		}
+		fmt.Fprintln(bw)
+	}
+
+	for _, name := range s.Palettes.Names() {
+		// Die erste Zeile enthaelt den Namen ohne die Szene; leere Zeilen
+		// stehen fuer Kommentare in der urspruenglichen Datei.
+		fmt.Fprintf(bw, "\n[%s.%s]\n", s.Name, name)
+		lines := strings.Split(string(s.Palettes.data(name)), "\n")
+		for _, line := range lines[1:] {
+			if line != "" {
+				fmt.Fprintln(bw, line)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Liest die Szene name im Format von path.ini aus r.
+func ReadScene(r io.Reader, name string) (*Scene, error) {
+	return readScene(r, name, sceneFileName)
+}
+
+// LoadScene liest die Szene name aus der Datei path.ini mit der hoechsten
+// Prioritaet, welche diese Szene enthaelt (siehe [OpenConfSection]).
+func LoadScene(name string) (*Scene, error) {
+	fd, err := OpenConfSection(sceneFileName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	s, err := readScene(fd, name, fd.Name())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fd.Name(), err)
+	}
+	return s, nil
+}
+
+// readScene liest die Szene name aus r; source wird in Fehlermeldungen der
+// Paletten der Szene verwendet.
+func readScene(r io.Reader, name, source string) (*Scene, error) {
+	var pal *paletteSection
+	var palName string
+	var palLine int
+
+	s := NewScene(name)
+	cs := newConfScanner(r, 0)
+	if !cs.Find(name) {
+		if err := cs.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no scene '%s' found!", name)
+	}
+	flush := func() {
+		if pal != nil {
+			s.Palettes.add(palName, *pal)
+		}
+	}
+loop:
+	for cs.Scan() {
+		line := cs.Line()
+		switch {
+		case line.kind == confSection:
+			sub, ok := strings.CutPrefix(line.key, name+".")
+			if !ok || strings.Contains(sub, ".") {
+				break loop
+			}
+			flush()
+			palName, palLine = sub, line.no
+			pal = &paletteSection{source: source, line: line.no,
+				data: []byte("[" + sub + "]\n")}
+		case pal != nil:
+			// Die Zeilennummern in den Fehlermeldungen der Palette sollen
+			// mit der Datei uebereinstimmen.
+			for ; palLine < line.no-1; palLine++ {
+				pal.data = append(pal.data, '\n')
+			}
+			pal.data = append(pal.data, line.text...)
+			pal.data = append(pal.data, '\n')
+			palLine = line.no
+		case line.kind == confOption:
+			if err := s.SetOption(line.key, line.value); err != nil {
+				return nil, fmt.Errorf("line %d: '%s': %v", line.no, line.text, err)
+			}
+		default:
+			k, err := parseSceneKey(line.text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: '%s': %v", line.no, line.text, err)
+			}
+			s.Keys = append(s.Keys, k)
+		}
+	}
+	if err := cs.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	if len(s.Keys) == 0 {
+		return nil, fmt.Errorf("scene '%s' has no views", name)
+	}
+	return s, nil
+}
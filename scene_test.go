@@ -0,0 +1,232 @@
+package mandel
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+const sceneData = `
+[Other]
+-1.0  0.0  3.5  80
+
+# Eine vollstaendige Szene
+[Sunset]
+size     = 640x480
+sampling = 2x2
+images   = 64
+pal      = Glow
+len      = 256
+
+-1.0             0.0            3.5              80
+-0.745428000525  0.11300999994  5e-11          1200  cps=0.5
+
+[Sunset.Glow]
+# Eine Palette, welche nur in dieser Szene verfuegbar ist.
+0.0: #000000
+1.0: #ff8000
+
+[Sunset.Broken]
+0.0: #000000
+
+0.5: #zz8000
+
+[Next]
+-1.0  0.0  3.5  80
+`
+
+func TestScene(t *testing.T) {
+	s, err := ReadScene(strings.NewReader(sceneData), "Sunset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Cols != 640 || s.Rows != 480 || s.Sampling != Samp2x2 || s.Images != 64 {
+		t.Errorf("wrong options: %+v", s)
+	}
+	if !s.IsSet("size") || s.IsSet("fps") {
+		t.Errorf("IsSet: size=%v fps=%v", s.IsSet("size"), s.IsSet("fps"))
+	}
+	if s.Formula != FormulaMandelbrot {
+		t.Errorf("Formula = '%s'", s.Formula)
+	}
+	if s.Palette.String() != "pal=Glow len=256" {
+		t.Errorf("Palette = '%s'", s.Palette.String())
+	}
+	if len(s.Keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(s.Keys))
+	}
+	k := s.Keys[1]
+	if k.X != "-0.745428000525" || k.W != "5e-11" || k.MaxIter != 1200 || k.Palette.String() != "cps=0.5" {
+		t.Errorf("wrong key: %+v", k)
+	}
+	if x, _, w, _ := k.Values(); x != -0.745428000525 || w != 5e-11 {
+		t.Errorf("Values() = %v, %v", x, w)
+	}
+
+	if names := s.Palettes.Names(); len(names) != 2 || names[0] != "Glow" {
+		t.Errorf("palettes: %v", names)
+	}
+	if _, err := s.NewPalette("Glow"); err != nil {
+		t.Error(err)
+	}
+	_, err = s.NewPalette("Broken")
+	if err == nil || !strings.Contains(err.Error(), "line 24") {
+		t.Errorf("expected an error on line 24, got %v", err)
+	}
+
+	var pt PaletteTrack
+	pt.SetDefaultKey(s.Palette)
+	for _, k := range s.Keys {
+		pt.AddPaletteKey(k.Palette)
+	}
+	if f := pt.GetPalette(0.5); f.Name0 != "Glow" || f.Length != 256 || f.Fields&KeyCPS == 0 {
+		t.Errorf("scene defaults not used: %+v", f)
+	}
+}
+
+func TestSceneWriteSection(t *testing.T) {
+	s, err := ReadScene(strings.NewReader(sceneData), "Sunset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Keys[0].Dur = 4.0
+	s.Keys[0].Transform.Rot = 90.0
+	var buf strings.Builder
+	if err := s.WriteSection(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `[Sunset]
+size     = 640x480
+sampling = 2x2
+images   = 64
+pal      = Glow
+len      = 256
+
+-1.0             0.0            3.5      80  dur=4 rot=90
+-0.745428000525  0.11300999994  5e-11  1200  cps=0.5
+
+[Sunset.Glow]
+0.0: #000000
+1.0: #ff8000
+
+[Sunset.Broken]
+0.0: #000000
+0.5: #zz8000
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	s1, err := ReadScene(strings.NewReader(buf.String()), "Sunset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s1.Keys) != len(s.Keys) {
+		t.Fatalf("got %d keys, want %d", len(s1.Keys), len(s.Keys))
+	}
+	for i := range s.Keys {
+		if s1.Keys[i] != s.Keys[i] {
+			t.Errorf("key %d: got %+v, want %+v", i, s1.Keys[i], s.Keys[i])
+		}
+	}
+	if s1.Cols != 640 || s1.Images != 64 || s1.Palette != s.Palette {
+		t.Errorf("wrong options: %+v", s1)
+	}
+}
+
+func TestSceneErrors(t *testing.T) {
+	for _, data := range []string{
+		"[S]\nspeed = 1\n-1.0 0.0 3.5 80\n",
+		"[S]\nformula = julia\n-1.0 0.0 3.5 80\n",
+		"[S]\nsize = 640\n-1.0 0.0 3.5 80\n",
+		"[S]\n-1.0 0.0 -3.5 80\n",
+		"[S]\n-1.0 0.0 3.5 0\n",
+		"[S]\n-1.0 0.0 3.5\n",
+		"[S]\nbits = 8\n",
+		"[T]\n-1.0 0.0 3.5 80\n",
+	} {
+		if _, err := ReadScene(strings.NewReader(data), "S"); err == nil {
+			t.Errorf("no error for %q", data)
+		}
+	}
+
+	s := NewScene("S")
+	if err := s.SetOption("speed", "1"); !errors.Is(err, ErrUnknownOption) {
+		t.Errorf("unknown option: got %v", err)
+	}
+}
+
+func TestSceneDurations(t *testing.T) {
+	data := `[S]
+-1.0   0.0  3.5    80
+-1.0   0.0  0.035  80  dur=2
+-1.0   0.0  0.035  90
+-0.99  0.0  3.5e-5 80
+`
+	s, err := ReadScene(strings.NewReader(data), "S")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Keys[1].Dur != 2.0 || s.Keys[1].Palette.Fields != 0 {
+		t.Errorf("dur not parsed: %+v", s.Keys[1])
+	}
+
+	durs, err := s.Durations(TimingUniform, 0.0, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if durs[0] != 1.5 || durs[1] != 2.0 || durs[2] != 1.5 {
+		t.Errorf("uniform: %v", durs)
+	}
+
+	// Zoom um den Faktor 100 resp. 1000: die Dauer ist proportional zu
+	// log(w), die Verschiebung um 0.01 faellt kaum ins Gewicht.
+	durs, err = s.Durations(TimingZoom, 12.0, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(durs[0]+durs[2]-10.0) > 1e-9 || math.Abs(durs[2]/durs[0]-1.5) > 1e-2 {
+		t.Errorf("zoom: %v", durs)
+	}
+	if _, err := s.Durations(TimingZoom, 2.0, 1.0); err == nil {
+		t.Errorf("total duration shorter than fixed durations accepted")
+	}
+}
+
+func TestSceneTransform(t *testing.T) {
+	data := "[S]\n-1.0 0.0 3.5 80 rot=90 stretch=2\n-1.0 0.0 3.5 80 rot=450 skew=0.5 pal=Fire\n"
+	s, err := ReadScene(strings.NewReader(data), "S")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr0, tr1 := s.Keys[0].Transform, s.Keys[1].Transform
+	if tr0.String() != "rot=90 stretch=2" || tr1.String() != "rot=450 skew=0.5" {
+		t.Errorf("got '%v' and '%v'", tr0, tr1)
+	}
+	if s.Keys[1].Palette.String() != "pal=Fire" {
+		t.Errorf("palette: '%v'", s.Keys[1].Palette)
+	}
+
+	// Um 90 Grad gedreht und doppelt so hohe Pixel: (1, 0) liegt oben,
+	// (0, 1) links.
+	a, b, c, d := tr0.Matrix()
+	want := []float64{0.0, -2.0, 1.0, 0.0}
+	for i, v := range []float64{a, b, c, d} {
+		if math.Abs(v-want[i]) > 1e-12 {
+			t.Errorf("Matrix() = %v, %v, %v, %v; want %v", a, b, c, d, want)
+			break
+		}
+	}
+	if !(Transform{}).IsIdentity() || tr0.IsIdentity() {
+		t.Errorf("IsIdentity")
+	}
+
+	tr := tr0.Interp(tr1, 0.5)
+	if tr.Rot != 270.0 || tr.Skew != 0.25 || math.Abs(tr.Stretch-math.Sqrt2) > 1e-12 {
+		t.Errorf("Interp() = %+v", tr)
+	}
+	if err := tr.SetOption("stretch", "-1"); err == nil {
+		t.Errorf("negative stretch accepted")
+	}
+}
@@ -0,0 +1,109 @@
+package mandel
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Transform beschreibt die Drehung und Verzerrung einer Ansicht. Der
+// Nullwert entspricht einer achsenparallelen Ansicht mit quadratischen
+// Pixeln.
+//
+//	Rot      Drehung der Ansicht in Grad (im Gegenuhrzeigersinn, d.h. das
+//	         Bild dreht sich im Uhrzeigersinn). Winkel ausserhalb von
+//	         [0,360) sind erlaubt und werden beim Interpolieren nicht
+//	         reduziert, womit sich mehrere Umdrehungen angeben lassen.
+//	Skew     Scherung: Punkte weiter oben im Bild werden um Skew mal ihre
+//	         Hoehe nach rechts verschoben.
+//	Stretch  Verhaeltnis von Hoehe zu Breite eines Pixels; 0 steht fuer 1
+//	         (quadratische Pixel).
+//
+// In path.ini werden diese Angaben mit den Schluesseln 'rot', 'skew' und
+// 'stretch' bei den Stuetzstellen gemacht.
+type Transform struct {
+	Rot, Skew, Stretch float64
+}
+
+// aspect liefert das Verhaeltnis von Hoehe zu Breite eines Pixels.
+func (tr Transform) aspect() float64 {
+	if tr.Stretch == 0.0 {
+		return 1.0
+	}
+	return tr.Stretch
+}
+
+// IsIdentity prueft, ob die Ansicht weder gedreht noch verzerrt ist.
+func (tr Transform) IsIdentity() bool {
+	return tr.Rot == 0.0 && tr.Skew == 0.0 && tr.aspect() == 1.0
+}
+
+// Matrix liefert die Matrix, mit welcher ein Punkt (u, v) relativ zum
+// Mittelpunkt der Ansicht (in Einheiten der komplexen Ebene, v nach oben)
+// auf die komplexe Ebene abgebildet wird:
+//
+//	x = a*u + b*v
+//	y = c*u + d*v
+func (tr Transform) Matrix() (a, b, c, d float64) {
+	sin, cos := math.Sincos(tr.Rot * math.Pi / 180.0)
+	st := tr.aspect()
+	return cos, cos*tr.Skew - sin*st, sin, sin*tr.Skew + cos*st
+}
+
+// Interp interpoliert zwischen tr (t=0) und u (t=1). Drehung und Scherung
+// werden linear, die Streckung geometrisch interpoliert.
+func (tr Transform) Interp(u Transform, t float64) Transform {
+	return Transform{
+		Rot:     (1.0-t)*tr.Rot + t*u.Rot,
+		Skew:    (1.0-t)*tr.Skew + t*u.Skew,
+		Stretch: tr.aspect() * math.Pow(u.aspect()/tr.aspect(), t),
+	}
+}
+
+// SetOption setzt die Angabe key ('rot', 'skew' oder 'stretch') auf den
+// Wert value. Unbekannte Schluessel werden mit [ErrUnknownOption] quittiert.
+func (tr *Transform) SetOption(key, value string) error {
+	var field *float64
+
+	switch key {
+	case "rot":
+		field = &tr.Rot
+	case "skew":
+		field = &tr.Skew
+	case "stretch":
+		field = &tr.Stretch
+	default:
+		return fmt.Errorf("%w '%s'", ErrUnknownOption, key)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	if key == "stretch" && v <= 0.0 {
+		return fmt.Errorf("stretch must be positive")
+	}
+	*field = v
+	return nil
+}
+
+// String erstellt die Darstellung aller Angaben, welche vom Nullwert
+// abweichen, wie sie in path.ini verwendet wird.
+func (tr Transform) String() string {
+	s := ""
+	add := func(key string, v float64) {
+		if s != "" {
+			s += " "
+		}
+		s += key + "=" + strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if tr.Rot != 0.0 {
+		add("rot", tr.Rot)
+	}
+	if tr.Skew != 0.0 {
+		add("skew", tr.Skew)
+	}
+	if tr.aspect() != 1.0 {
+		add("stretch", tr.Stretch)
+	}
+	return s
+}